@@ -0,0 +1,95 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fAuditPolicy string
+	fAuditHTML   string
+
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Compares a policy's nonces and hashes against an HTML document.",
+		Long: clihelpers.LongHelpText(`
+		Compares a policy's nonces and hashes against an HTML document.
+
+		Reports nonce-sources and hash-sources that no element in the document uses
+		(e.g., stale hashes left behind after a script changed), and inline <script>
+		and <style> elements that the policy would block, along with the hash that
+		would allow them.
+
+		Documents in <iframe srcdoc> elements inherit the policy, so they are audited
+		too. The csp attributes of <iframe> elements (Embedded Enforcement) and the
+		<meta> policies of srcdoc documents are validated, compared with the policy
+		to find where they are less strict, and listed in the JSON output.
+
+		Pass the policy with --policy, and the path to the HTML document with --html.`),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			policies, err := csp.Parse(fCurrentURL, "", []string{fAuditPolicy})
+			logErrors(err)
+
+			if len(policies) == 0 {
+				logger.Fatalf("no policy was found in --policy")
+			}
+
+			f, err := os.Open(fAuditHTML)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			defer f.Close()
+
+			audit, err := csp.AuditHTML(policies[0], f)
+			if audit == nil {
+				logger.Fatalf("%v", err)
+			}
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(audit, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			logErrors(err)
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	auditCmd.Flags().
+		StringVarP(&fAuditPolicy, "policy", "p", "", "A Content-Security-Policy header value.")
+	auditCmd.Flags().
+		StringVarP(&fAuditHTML, "html", "H", "", "The path to the HTML document to audit.")
+
+	_ = auditCmd.MarkFlagRequired("policy")
+	_ = auditCmd.MarkFlagRequired("html")
+
+	rootCmd.AddCommand(auditCmd)
+}
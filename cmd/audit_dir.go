@@ -0,0 +1,100 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fAuditDirPolicy string
+
+	auditDirCmd = &cobra.Command{
+		Use:   "audit-dir DIRECTORY",
+		Short: "Reports everything in a built static site that a policy would block.",
+		Long: clihelpers.LongHelpText(`
+		Reports everything in a built static site that a policy would block.
+
+		Walks the DIRECTORY (e.g., ./public) and checks every HTML and CSS file for
+		resources (scripts, stylesheets, images, fonts, frames, and so on) and inline
+		content (<script> and <style> elements, event handler attributes, and style
+		attributes) that the policy would block, before the site is ever deployed.
+
+		Pass the policy with --policy; it may be read from a file with
+		--policy @policy.txt. Pass the URL that the site will be served from with
+		--current-url, which is used to resolve relative URLs and 'self'. It defaults
+		to https://localhost/.`),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			values, _, err := expandPolicyArgs([]string{fAuditDirPolicy})
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			if len(values) == 0 {
+				logger.Fatalf("no policy was passed with --policy")
+			}
+
+			siteURL := fCurrentURL
+			if siteURL == "" {
+				siteURL = "https://localhost/"
+			}
+
+			policies, err := csp.Parse(siteURL, "", values[:1], csp.MinSeverity(csp.SeverityError))
+			logErrors(err)
+
+			if len(policies) == 0 {
+				logger.Fatalf("no policy was found in --policy")
+			}
+
+			audit, err := csp.AuditFS(policies[0], os.DirFS(args[0]), siteURL)
+			if audit == nil {
+				logger.Fatalf("%v", err)
+			}
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(audit, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			logErrors(err)
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	auditDirCmd.Flags().
+		StringVarP(&fAuditDirPolicy, "policy", "p", "", "A Content-Security-Policy header value, or @path to a "+
+			"policy file.")
+	auditDirCmd.Flags().
+		StringVarP(&fCurrentURL, "current-url", "u", "", "The URL that the site will be served from. Used to "+
+			"resolve relative URLs and 'self'. Defaults to https://localhost/.")
+
+	_ = auditDirCmd.MarkFlagRequired("policy")
+
+	rootCmd.AddCommand(auditDirCmd)
+}
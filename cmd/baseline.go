@@ -0,0 +1,127 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fBaselineAgainst string
+
+	baselineCmd = &cobra.Command{
+		Use:   "baseline-compare [POLICY]",
+		Short: "Compares a policy against an industry baseline policy.",
+		Long: clihelpers.LongHelpText(`
+		Compares a policy against an industry baseline policy.
+
+		Reports the protections where the POLICY is weaker than the baseline chosen
+		with --against (e.g., it allows inline scripts, but the baseline does not), and
+		the ones where it is stronger. Without an ARGUMENT, lists the available
+		baselines.
+
+		The POLICY may also be @path/to/file, as with the root command. Nonces in the
+		baselines are placeholders; any nonce in the POLICY counts.`),
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				for _, baseline := range csp.Baselines() {
+					fmt.Printf("%-20s %s\n", baseline.Name, baseline.Description)
+				}
+
+				return
+			}
+
+			policies, _, err := expandPolicyArgs(args)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			parsed, err := csp.Parse(fCurrentURL, fReportingEndpoints, policies, parseOptions()...)
+			logErrors(err)
+
+			comparisons := make([]*csp.BaselineComparison, 0, len(parsed))
+
+			for i := range parsed {
+				comparison, err := csp.CompareBaseline(parsed[i], fBaselineAgainst)
+				if err != nil {
+					handleErrorMsg(err)
+					os.Exit(1)
+				}
+
+				comparisons = append(comparisons, comparison)
+			}
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(comparisons, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			for i, comparison := range comparisons {
+				if len(comparisons) > 1 {
+					fmt.Printf("policy %d:\n", i)
+				}
+
+				printBaselineChecks("weaker than "+comparison.Baseline, comparison.Weaker)
+				printBaselineChecks("stronger than "+comparison.Baseline, comparison.Stronger)
+				printBaselineChecks("same as "+comparison.Baseline, comparison.Matched)
+			}
+		},
+	}
+)
+
+/*
+printBaselineChecks prints a heading and one line per check. Nothing is printed
+when there are no checks.
+
+----
+
+  - heading (string): The heading to print above the checks.
+
+  - checks ([]csp.BaselineCheck): The checks to print.
+*/
+func printBaselineChecks(heading string, checks []csp.BaselineCheck) {
+	if len(checks) == 0 {
+		return
+	}
+
+	fmt.Printf("%s:\n", heading)
+
+	for _, check := range checks {
+		fmt.Printf("  %-16s %s\n", check.Name, check.Description)
+	}
+}
+
+func init() { // lint:allow_init
+	baselineCmd.Flags().
+		StringVarP(&fBaselineAgainst, "against", "a", "owasp", "The baseline to compare against. Run without a "+
+			"POLICY to list them.")
+	baselineCmd.Flags().
+		StringVarP(&fCurrentURL, "current-url", "u", "", "The current URL being evaluated. May be an empty string.")
+
+	rootCmd.AddCommand(baselineCmd)
+}
@@ -0,0 +1,296 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+type (
+	// benchInput is a line of the --input file, when it is an object rather than
+	// a string.
+	benchInput struct {
+		Policy             string `json:"policy"`
+		CurrentURL         string `json:"currentURL"`
+		ReportingEndpoints string `json:"reportingEndpoints"`
+		Target             string `json:"target"`
+	}
+
+	// benchResult is the outcome of a benchmark run.
+	benchResult struct {
+		Policies      int        `json:"policies"`
+		Parse         benchPhase `json:"parse"`
+		Evaluate      benchPhase `json:"evaluate"`
+		GoVersion     string     `json:"goVersion"`
+		GOMAXPROCS    int        `json:"gomaxprocs"`
+		InputFilename string     `json:"input"`
+	}
+
+	// benchPhase is the outcome of one phase (parsing or evaluation) of a
+	// benchmark run.
+	benchPhase struct {
+		Operations  int           `json:"operations"`
+		Total       time.Duration `json:"total"`
+		PerSecond   float64       `json:"perSecond"`
+		P50         time.Duration `json:"p50"`
+		P95         time.Duration `json:"p95"`
+		Max         time.Duration `json:"max"`
+		AllocsPerOp uint64        `json:"allocsPerOp"`
+		BytesPerOp  uint64        `json:"bytesPerOp"`
+	}
+)
+
+// defaultBenchTarget is the URL that policies are evaluated against when an
+// input does not have a "target" key.
+const defaultBenchTarget = "https://cdn.example.com/app.js"
+
+var (
+	fBenchInput      string
+	fBenchIterations int
+
+	benchCmd = &cobra.Command{
+		Use:   "bench",
+		Short: "Measures how fast policies from a corpus are parsed and evaluated.",
+		Long: clihelpers.LongHelpText(`
+		Measures how fast policies from a corpus are parsed and evaluated.
+
+		The --input file is newline-delimited JSON (NDJSON). Each line is either a
+		policy as a JSON string, or an object with a "policy" key and optional
+		"currentURL", "reportingEndpoints", and "target" keys. Blank lines are
+		skipped.
+
+		Each policy is parsed --iterations times. Then, --iterations times, each
+		parsed policy is evaluated: it is asked whether every directive with a source
+		list allows the target URL (https://cdn.example.com/app.js by default), as
+		csp-parser why does. For each phase, the latencies of single operations (p50,
+		p95, and max), the throughput, and the allocations per operation are printed
+		(or, with --json, returned as JSON). Everything runs locally; nothing about
+		the corpus or the results is sent anywhere.`),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			inputs, err := readBenchInputs(fBenchInput)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			if fBenchIterations < 1 {
+				logger.Fatalf("--iterations must be at least 1")
+			}
+
+			result := runBench(inputs, fBenchIterations)
+			result.InputFilename = fBenchInput
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			fmt.Printf("input:       %s (%d policies)\n", result.InputFilename, result.Policies)
+
+			for _, phase := range []struct {
+				name   string
+				result *benchPhase
+			}{
+				{"parse", &result.Parse},
+				{"evaluate", &result.Evaluate},
+			} {
+				p := phase.result
+				fmt.Printf("%s\n", phase.name)
+				fmt.Printf("  operations:  %d in %s (%.0f/s)\n", p.Operations, p.Total, p.PerSecond)
+				fmt.Printf("  latency:     p50=%s p95=%s max=%s\n", p.P50, p.P95, p.Max)
+				fmt.Printf("  allocations: %d allocs/op, %d B/op\n", p.AllocsPerOp, p.BytesPerOp)
+			}
+
+			fmt.Printf("runtime:     %s, GOMAXPROCS=%d\n", result.GoVersion, result.GOMAXPROCS)
+		},
+	}
+)
+
+/*
+readBenchInputs reads the policies from an NDJSON file. See the help text of
+the bench command for the format.
+
+----
+
+  - filename (string): The path of the NDJSON file.
+*/
+func readBenchInputs(filename string) ([]benchInput, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := []benchInput{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var input benchInput
+
+		if text[0] == '"' {
+			err = json.Unmarshal(text, &input.Policy)
+		} else {
+			err = json.Unmarshal(text, &input)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s: no policies found", filename)
+	}
+
+	return inputs, nil
+}
+
+/*
+runBench parses each input the given number of times, then evaluates each
+parsed policy the given number of times, and measures each phase separately.
+
+----
+
+  - inputs ([]benchInput): The policies to parse.
+
+  - iterations (int): The number of times to parse and evaluate each policy.
+*/
+func runBench(inputs []benchInput, iterations int) benchResult {
+	parsed := make([][]*csp.Policy, len(inputs))
+
+	parse := measureBench(len(inputs), iterations, func(i int) {
+		parsed[i], _ = csp.Parse(inputs[i].CurrentURL, inputs[i].ReportingEndpoints, []string{inputs[i].Policy})
+	})
+
+	directives := csp.SourceListDirectives()
+
+	evaluate := measureBench(len(inputs), iterations, func(i int) {
+		target := inputs[i].Target
+		if target == "" {
+			target = defaultBenchTarget
+		}
+
+		for _, policy := range parsed[i] {
+			for _, directive := range directives {
+				_, _ = policy.Explain(inputs[i].CurrentURL, directive, target)
+			}
+		}
+	})
+
+	return benchResult{
+		Policies:   len(inputs),
+		Parse:      parse,
+		Evaluate:   evaluate,
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+}
+
+/*
+measureBench runs an operation on each input the given number of times, and
+measures the latency of each operation and the allocations of the whole run.
+
+----
+
+  - n (int): The number of inputs.
+
+  - iterations (int): The number of times to run the operation on each input.
+
+  - op (func(i int)): The operation, which is passed the index of the input.
+*/
+func measureBench(n, iterations int, op func(i int)) benchPhase {
+	latencies := make([]time.Duration, 0, n*iterations)
+
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	started := time.Now()
+
+	for range iterations {
+		for i := range n {
+			opStarted := time.Now()
+
+			op(i)
+			latencies = append(latencies, time.Since(opStarted))
+		}
+	}
+
+	total := time.Since(started)
+
+	runtime.ReadMemStats(&after)
+	slices.Sort(latencies)
+
+	operations := uint64(len(latencies))
+
+	return benchPhase{
+		Operations:  len(latencies),
+		Total:       total,
+		PerSecond:   float64(len(latencies)) / total.Seconds(),
+		P50:         percentile(latencies, 50),
+		P95:         percentile(latencies, 95),
+		Max:         latencies[len(latencies)-1],
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / operations,
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / operations,
+	}
+}
+
+// percentile returns the p-th percentile (nearest rank) of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (len(sorted)*p + 99) / 100
+
+	return sorted[max(rank-1, 0)]
+}
+
+func init() { // lint:allow_init
+	benchCmd.Flags().
+		StringVarP(&fBenchInput, "input", "i", "", "An NDJSON file with one policy per line.")
+	benchCmd.Flags().
+		IntVarP(&fBenchIterations, "iterations", "n", 10, "The number of times to parse and evaluate each "+
+			"policy.")
+
+	_ = benchCmd.MarkFlagRequired("input")
+
+	rootCmd.AddCommand(benchCmd)
+}
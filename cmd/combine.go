@@ -0,0 +1,110 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fCombinePolicies []string
+	fCombineReport   []string
+
+	combineCmd = &cobra.Command{
+		Use:   "combine",
+		Short: "Shows how several policies for the same response restrict loads together.",
+		Long: clihelpers.LongHelpText(`
+		Shows how several policies for the same response restrict loads together.
+
+		A resource is only loaded when every enforced policy allows it, so one
+		policy's 'none' or narrow list can make another policy's broad allowances
+		irrelevant. For each directive that an enforced policy restricts, prints the
+		restriction of each policy (after fallback), the effective combined source
+		list, and the source expressions that the other policies shadow.
+
+		Pass each enforced policy with --policy, and each report-only policy with
+		--report-only. Report-only policies never block anything.`),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			header := http.Header{
+				csp.HeaderCSP:           fCombinePolicies,
+				csp.HeaderCSPReportOnly: fCombineReport,
+			}
+
+			policies, err := csp.ParseHeader(fCurrentURL, header)
+			logErrors(err)
+
+			combination := csp.Combine(policies)
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(combination, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			for _, directive := range combination.Directives {
+				fmt.Printf("%s:\n", directive.Name)
+				fmt.Printf("  effective: %s\n", strings.Join(directive.Effective, " "))
+
+				for _, restriction := range directive.Restrictions {
+					fmt.Printf(
+						"  policy %d (%s): %s\n",
+						restriction.PolicyIndex, restriction.EffectiveDirective, strings.Join(restriction.Sources, " "),
+					)
+				}
+
+				for _, shadowed := range directive.Shadowed {
+					effect := "has no effect"
+					if shadowed.Partial {
+						effect = "is narrowed"
+					}
+
+					fmt.Printf(
+						"  shadowed: %s in policy %d %s (blocked by policies %v)\n",
+						shadowed.Source, shadowed.PolicyIndex, effect, shadowed.BlockedBy,
+					)
+				}
+			}
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	combineCmd.Flags().
+		StringArrayVarP(&fCombinePolicies, "policy", "p", []string{}, "A Content-Security-Policy header value. May "+
+			"be passed more than once.")
+	combineCmd.Flags().
+		StringArrayVarP(&fCombineReport, "report-only", "r", []string{}, "A Content-Security-Policy-Report-Only "+
+			"header value. May be passed more than once.")
+	combineCmd.Flags().
+		StringVarP(&fCurrentURL, "current-url", "u", "", "The current URL being evaluated. May be an empty string, "+
+			"but then 'self' sources cannot be validated.")
+
+	_ = combineCmd.MarkFlagRequired("policy")
+
+	rootCmd.AddCommand(combineCmd)
+}
@@ -0,0 +1,83 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+/*
+expandPolicyArgs replaces each `@path/to/policy.txt` argument with the policy
+stored in that file. The path may be a glob pattern (e.g., `@policies/*.txt`),
+in which case each matching file becomes its own policy. Other arguments are
+returned unchanged. The files that were read are also returned, by the policy
+they hold, so that their comments can be attached to the parsed policies (see
+attachPolicyFiles).
+
+----
+
+  - args ([]string): The command-line arguments.
+*/
+func expandPolicyArgs(args []string) ([]string, map[string]*csp.PolicyFile, error) {
+	policies := make([]string, 0, len(args))
+	files := map[string]*csp.PolicyFile{}
+
+	for i := range args {
+		if !strings.HasPrefix(args[i], "@") {
+			policies = append(policies, args[i])
+
+			continue
+		}
+
+		pattern := strings.TrimPrefix(args[i], "@")
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern `%s`: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, nil, fmt.Errorf("no policy files match `%s`", pattern)
+		}
+
+		for _, match := range matches {
+			b, err := os.ReadFile(match)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			file := csp.ReadPolicyFile(string(b))
+			files[file.Policy] = file
+			policies = append(policies, file.Policy)
+		}
+	}
+
+	return policies, files, nil
+}
+
+// attachPolicyFiles attaches the comments of each policy file to the policies
+// that were parsed from it.
+func attachPolicyFiles(policies []*csp.Policy, files map[string]*csp.PolicyFile) {
+	for i := range policies {
+		if file, ok := files[policies[i].Raw]; ok {
+			file.Attach(policies[i])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/lsp"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Runs a Language Server Protocol server for policy files over stdio.",
+	Long: clihelpers.LongHelpText(`
+	Runs a Language Server Protocol server for policy files over stdio.
+
+	Provides diagnostics, hovers (directive documentation), and completions
+	(directive names and keyword-sources) for policy files in editors such as VS
+	Code and Neovim. Configure your editor to start "csp-parser lsp" for .csp
+	files.
+
+	Evaluation rules from --rules apply to the diagnostics, unless the editor sends
+	its own rules file as the initialization options.`),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server := lsp.NewServer(os.Stdin, os.Stdout)
+		server.SetRules(readRules())
+
+		if err := server.Run(); err != nil {
+			logger.Fatalf("%v", err)
+		}
+	},
+}
+
+func init() { // lint:allow_init
+	rootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/northwood-labs/csp-parser/csp/evaluate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fMatrixReportOnly []string
+	fMatrixMeta       []string
+
+	matrixCmd = &cobra.Command{
+		Use:   "matrix",
+		Short: "Shows which policies allow each origin, in which directives.",
+		Long: clihelpers.LongHelpText(`
+		Shows which policies allow each origin, in which directives.
+
+		Useful for responses with several policies (e.g., an enforced header, a
+		report-only header, and a <meta> element). For each origin named anywhere in
+		the policies, lists the directives that allow it in each policy, and flags
+		directives where one policy allows the origin but another blocks it.
+
+		Enforced policies are passed as ARGUMENTS. Pass report-only policies with
+		--report-only and <meta> policies with --meta. Pass the URL of the document
+		with --current-url, so that 'self' can be resolved.`),
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			policies := []*csp.Policy{}
+
+			for _, group := range []struct {
+				values []string
+				opts   []csp.Option
+			}{
+				{args, nil},
+				{fMatrixReportOnly, []csp.Option{csp.WithDisposition(csp.DispositionReport)}},
+				{fMatrixMeta, []csp.Option{csp.WithDelivery(csp.DeliveryMeta)}},
+			} {
+				if len(group.values) == 0 {
+					continue
+				}
+
+				opts := append(group.opts, csp.MinSeverity(csp.SeverityError))
+
+				parsed, err := csp.Parse(fCurrentURL, "", group.values, opts...)
+				logErrors(err)
+
+				policies = append(policies, parsed...)
+			}
+
+			matrix := evaluate.NewOriginMatrix(policies)
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(matrix, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			for i := range matrix.Origins {
+				row := &matrix.Origins[i]
+				fmt.Println(row.Origin)
+
+				for j := range row.Allowed {
+					p := matrix.Policies[j]
+					fmt.Printf(
+						"  policy %d (%s, %s): %s\n",
+						p.Index, p.Disposition, p.Delivery, strings.Join(row.Allowed[j], ", "),
+					)
+				}
+
+				if len(row.Conflicts) > 0 {
+					fmt.Printf("  conflicts: %s\n", strings.Join(row.Conflicts, ", "))
+				}
+			}
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	matrixCmd.Flags().
+		StringArrayVarP(&fMatrixReportOnly, "report-only", "r", []string{}, "A Content-Security-Policy-Report-Only "+
+			"header value. May be passed more than once.")
+	matrixCmd.Flags().
+		StringArrayVarP(&fMatrixMeta, "meta", "m", []string{}, "The content of a <meta http-equiv=\"Content-Security-"+
+			"Policy\"> element. May be passed more than once.")
+	matrixCmd.Flags().
+		StringVarP(&fCurrentURL, "current-url", "u", "", "The current URL being evaluated. May be an empty string, "+
+			"but then 'self' sources are not listed as allowing any origin.")
+
+	rootCmd.AddCommand(matrixCmd)
+}
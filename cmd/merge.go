@@ -0,0 +1,88 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge FILE...",
+	Short: "Merges policy fragments into a single policy.",
+	Long: clihelpers.LongHelpText(`
+	Merges policy fragments into a single policy.
+
+	Each FILE holds a policy fragment (e.g., the sources needed by one feature of a
+	site), in the same format as @path/to/policy.txt arguments. A FILE may be a
+	glob pattern. Source lists are combined directive by directive, dropping
+	duplicate and redundant sources. A fragment without a directive contributes
+	the list it falls back to (e.g., default-src). A warning is logged when the
+	fragments conflict (e.g., one fragment has 'none' and another allows sources).
+
+	The merged policy is validated, and printed as a single header value (or, with
+	--json, as the parsed policy).`),
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		patterns := make([]string, len(args))
+		for i := range args {
+			patterns[i] = "@" + args[i]
+		}
+
+		raw, _, err := expandPolicyArgs(patterns)
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+
+		// Fragments are parsed together, so that the policy index of each
+		// diagnostic is the index of the fragment. Fragments are incomplete by
+		// design, so only errors are reported.
+		fragments, err := csp.Parse("", "", raw, csp.WithoutSelfValidation(), csp.WithoutReportingValidation(),
+			csp.MinSeverity(csp.SeverityError))
+		logErrors(err)
+
+		if len(fragments) != len(raw) {
+			logger.Fatalf("each fragment must hold exactly one policy, without commas")
+		}
+
+		header, err := csp.Merge(fragments)
+		logErrors(err)
+
+		merged, err := csp.Parse("", "", []string{header}, csp.WithoutSelfValidation(),
+			csp.WithoutReportingValidation())
+		logErrors(err)
+
+		if !fJSON {
+			fmt.Println(header)
+
+			return
+		}
+
+		jsonb, err := json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+
+		fmt.Println(string(jsonb))
+	},
+}
+
+func init() { // lint:allow_init
+	rootCmd.AddCommand(mergeCmd)
+}
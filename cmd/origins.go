@@ -0,0 +1,71 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/northwood-labs/csp-parser/csp/evaluate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fOrigins []string
+
+	originsCmd = &cobra.Command{
+		Use:   "origins",
+		Short: "Compares what 'self' covers when a policy is served from several origins.",
+		Long: clihelpers.LongHelpText(`
+		Compares what 'self' covers when a policy is served from several origins.
+
+		Useful for policies that are shared across many virtual hosts (e.g.,
+		example.com, www.example.com, app.example.com). For each directive whose
+		effective source list contains 'self', reports which of the candidate origins
+		would be allowed when the policy is served from each candidate origin.
+
+		Pass each candidate origin with --origin. The CSP policy is passed as the
+		single ARGUMENT.`),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			policies, err := csp.Parse("", "", args)
+			logErrors(err)
+
+			if len(policies) == 0 {
+				logger.Fatalf("no policy was found in the argument")
+			}
+
+			comparison, err := evaluate.CompareSelfOrigins(policies[0], fOrigins)
+			logErrors(err)
+
+			jsonb, err := json.MarshalIndent(comparison, "", "  ")
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			fmt.Println(string(jsonb))
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	originsCmd.Flags().
+		StringSliceVarP(&fOrigins, "origin", "o", []string{}, "A candidate origin that the policy is served from "+
+			"(e.g., https://www.example.com). May be passed more than once.")
+
+	rootCmd.AddCommand(originsCmd)
+}
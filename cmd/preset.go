@@ -0,0 +1,68 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var presetCmd = &cobra.Command{
+	Use:   "preset [NAME]",
+	Short: "Prints a named preset policy to start from.",
+	Long: clihelpers.LongHelpText(`
+	Prints a named preset policy to start from.
+
+	Without an ARGUMENT, lists the available presets. With the name of a preset as
+	the ARGUMENT, prints its policy (or, with --json, the parsed policy).`),
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			for _, preset := range csp.Presets() {
+				fmt.Printf("%-12s %s\n", preset.Name, preset.Description)
+			}
+
+			return
+		}
+
+		policy, err := csp.Preset(args[0])
+		if err != nil {
+			handleErrorMsg(err)
+			os.Exit(1)
+		}
+
+		if !fJSON {
+			fmt.Println(policy.Raw)
+
+			return
+		}
+
+		jsonb, err := json.MarshalIndent(policy, "", "  ")
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+
+		fmt.Println(string(jsonb))
+	},
+}
+
+func init() { // lint:allow_init
+	rootCmd.AddCommand(presetCmd)
+}
@@ -0,0 +1,164 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/northwood-labs/csp-parser/csp/format"
+	"github.com/northwood-labs/csp-parser/csp/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fReportInput []string
+	fReportTop   int
+
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Summarizes many earlier scans into a fleet-level report.",
+		Long: clihelpers.LongHelpText(`
+		Summarizes many earlier scans into a fleet-level report.
+
+		Reads the JSON output of earlier runs of csp-parser (one file per scan, e.g.,
+		one per property), and reports the grade distribution, the most common
+		findings, and the hosts that are most frequently allowed. The grade of a scan
+		is the severity of its worst diagnostic: clean, info, warn, or error.
+
+		Findings are recomputed from each policy's raw text, so scans taken with older
+		versions benefit from newer checks. Scans do not record the URL or the
+		Reporting-Endpoints header, so 'self' and report-to are not validated.
+
+		Pass the scans with --input, which may be a glob pattern (e.g., scans/*.json)
+		and may be passed more than once. Paths may also be passed as ARGUMENTS. If a
+		scan can't be read, the report covers the others, and the exit status is 1.`),
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			paths, err := expandReportInputs(append(slices.Clone(fReportInput), args...))
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			fleet := report.NewFleet()
+			failed := 0
+
+			for _, path := range paths {
+				if err := addScanToReport(fleet, path); err != nil {
+					logger.Error("could not read the scan", "file", path, "err", err)
+					failed++
+				}
+			}
+
+			// The report is still printed for the scans that could be read.
+			defer func() {
+				if failed > 0 {
+					os.Exit(1)
+				}
+			}()
+
+			fleet.Sort(fReportTop)
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(fleet, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			if err := fleet.WriteText(os.Stdout); err != nil {
+				logger.Fatalf("%v", err)
+			}
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	reportCmd.Flags().
+		StringArrayVarP(&fReportInput, "input", "i", []string{}, "The path to an earlier scan, or a glob "+
+			"pattern matching several scans. May be passed more than once.")
+	reportCmd.Flags().
+		IntVarP(&fReportTop, "top", "n", 10, "The number of findings and hosts to show. 0 shows all of them.")
+
+	rootCmd.AddCommand(reportCmd)
+}
+
+// expandReportInputs resolves the glob patterns into a sorted list of distinct
+// paths.
+func expandReportInputs(patterns []string) ([]string, error) {
+	paths := []string{}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern `%s`: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no scans match `%s`", pattern)
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no scans were passed; use --input")
+	}
+
+	slices.Sort(paths)
+
+	return slices.Compact(paths), nil
+}
+
+// addScanToReport reads one scan, and adds it to the report. A scan is the JSON
+// document printed by the root command with --json, or the array of policies
+// that older versions printed.
+func addScanToReport(fleet *report.Fleet, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	policies := []csp.Policy{}
+
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		doc := format.Document{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return fmt.Errorf("not the JSON output of csp-parser: %w", err)
+		}
+
+		for _, policy := range doc.Policies {
+			if policy != nil {
+				policies = append(policies, *policy)
+			}
+		}
+	} else if err := json.Unmarshal(b, &policies); err != nil {
+		return fmt.Errorf("not the JSON output of csp-parser: %w", err)
+	}
+
+	fleet.AddScan(policies)
+
+	return nil
+}
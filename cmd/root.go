@@ -16,8 +16,10 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -25,14 +27,34 @@ import (
 	"github.com/hashicorp/go-multierror"
 	clihelpers "github.com/northwood-labs/cli-helpers"
 	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/northwood-labs/csp-parser/csp/format"
 	"github.com/spf13/cobra"
 )
 
 var (
 	fCurrentURL         string
 	fReportingEndpoints string
+	fMinSeverity        string
+	fReportingSeverity  string
+	fInsecureSeverity   string
+	fStrict             bool
+	fMode               string
+	fHostStrictness     string
+	fReportOnly         bool
+	fContext            string
 	fJSON               bool
+	fFormat             string
 	fVerbose            bool
+	fRules              string
+	fMetricsFile        string
+
+	parseStats csp.Stats
+
+	// runSummary counts the diagnostics logged during this run, and summarize is
+	// true once anything was validated. The summary is then printed on stderr
+	// when the run ends.
+	runSummary format.Summary
+	summarize  bool
 
 	logger = log.NewWithOptions(os.Stderr, log.Options{
 		ReportTimestamp: true,
@@ -55,21 +77,79 @@ var (
 
 		CSP policies are passed as ARGUMENTS. There is commonly only one, but multiple
 		are supported. From the command line, we recommend wrapping the entire policy in
-		double-quotes since CSP policies often contain single-quoted values.`),
+		double-quotes since CSP policies often contain single-quoted values.
+
+		An argument of the form @path/to/policy.txt reads the policy from a file. The
+		path may be a glob pattern (e.g., @policies/*.txt) to read several policies.
+		Policy files may span multiple lines, and may contain comments starting with #.
+		Comments annotate the directive they are next to, and are kept in the JSON
+		output, and in the markdown format.
+
+		By default, the parsed policies are printed as JSON, and diagnostics are logged.
+		With --json, a single JSON document is printed instead, with the policies, the
+		diagnostics found while parsing, the findings of the checks across each policy,
+		and the summary. With --format, both are rendered together as json, text,
+		markdown, sarif, or a risk register (risk-register, with --current-url as the
+		affected asset) instead.
+
+		Each run that validates policies ends with a summary of the diagnostics on
+		stderr (e.g., errors=1 warnings=2 info=0 grade=error). The grade is the
+		severity of the worst diagnostic, or clean. With --verbose, the parse
+		statistics (durations, directive and token counts) are logged too; with
+		--metrics-file, they are written as Prometheus metrics.`),
 		Args: cobra.MinimumNArgs(1),
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if summarize {
+				fmt.Fprintln(os.Stderr, runSummary)
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			out, err := csp.Parse(fCurrentURL, fReportingEndpoints, args)
+			policies, files, err := expandPolicyArgs(args)
 			if err != nil {
-				if merr, ok := err.(*multierror.Error); ok {
-					for _, e := range merr.Errors {
-						handleErrorMsg(e)
-					}
-				} else {
-					handleErrorMsg(err)
+				logger.Fatalf("%v", err)
+			}
+
+			out, err := csp.Parse(fCurrentURL, fReportingEndpoints, policies, parseOptions()...)
+			attachPolicyFiles(out, files)
+
+			if fFormat != "" {
+				renderer, rerr := format.ByName(fFormat)
+				if rerr != nil {
+					logger.Fatalf("%v", rerr)
+				}
+
+				if register, ok := renderer.(*format.RiskRegister); ok {
+					register.Asset = fCurrentURL
 				}
+
+				result := format.NewResult(out, err)
+				runSummary, summarize = result.Summary, true
+
+				writeMetrics()
+
+				if rerr := renderer.Render(os.Stdout, result); rerr != nil {
+					logger.Fatalf("%v", rerr)
+				}
+
+				return
+			}
+
+			var v any = out
+
+			// With --json, the diagnostics are part of the document on stdout
+			// rather than log lines on stderr.
+			if fJSON {
+				result := format.NewResult(out, err)
+				runSummary, summarize = result.Summary, true
+				v = format.NewDocument(result)
+			} else {
+				logErrors(err)
 			}
 
-			jsonb, err := json.MarshalIndent(out, "", "  ")
+			logStats()
+			writeMetrics()
+
+			jsonb, err := json.MarshalIndent(v, "", "  ")
 			if err != nil {
 				logger.Fatalf("%v", err)
 			}
@@ -94,20 +174,237 @@ func init() {
 		StringVarP(&fReportingEndpoints, "reporting-endpoints", "e", "", "The value of the Reporting-Endpoints "+
 			"header, used to validate the 'report-to' directive. If there is no 'report-to' directive, "+
 			"this value may be empty.")
+	rootCmd.Flags().
+		StringVarP(&fMinSeverity, "min-severity", "s", "info", "Only report diagnostics at or above this "+
+			"severity. One of: info, warn, error.")
+	rootCmd.Flags().
+		StringVar(&fReportingSeverity, "missing-reporting-severity", "info", "The severity of the diagnostics "+
+			"for a policy that cannot report its violations. One of: info, warn, error.")
+	rootCmd.Flags().
+		StringVar(&fInsecureSeverity, "insecure-reporting-severity", "warn", "The severity of the diagnostics "+
+			"for reporting URLs that are not HTTPS or localhost, which browsers will not send reports to. One of: "+
+			"info, warn, error.")
+	rootCmd.Flags().
+		BoolVarP(&fStrict, "strict", "S", false, "Follow the CSP grammar exactly, where browsers are more "+
+			"forgiving. Shorthand for --mode=strict.")
+	rootCmd.Flags().
+		StringVar(&fMode, "mode", string(csp.ModeDefault), "How closely to follow the CSP grammar. One of: "+
+			"lenient (recover from mistakes as browsers do), default, strict (follow the grammar exactly).")
+	rootCmd.Flags().
+		StringVar(&fHostStrictness, "host-strictness", "", "How strictly to validate the host-part of "+
+			"host-sources. One of: default, spec, lenient. Defaults to the one set by --mode.")
+	rootCmd.Flags().
+		StringVarP(&fFormat, "format", "f", "", "Render the policies and diagnostics together in this format. "+
+			"One of: "+strings.Join(format.Names(), ", ")+".")
+	rootCmd.Flags().
+		BoolVarP(&fReportOnly, "report-only", "r", false, "Treat the policies as values of the "+
+			"Content-Security-Policy-Report-Only header.")
+	rootCmd.Flags().
+		StringVar(&fContext, "context", string(csp.ContextDocument), "The kind of global object that the "+
+			"policies apply to, which reports the directives that have no effect in a worker. One of: document, "+
+			"dedicated-worker, shared-worker, service-worker.")
+	rootCmd.Flags().
+		StringVar(&fMetricsFile, "metrics-file", "", "Write the parse statistics to this file in the Prometheus "+
+			"text format (e.g., for the node_exporter textfile collector).")
 
+	rootCmd.PersistentFlags().StringVar(&fRules, "rules", "", "Read evaluation rules from this JSON file, "+
+		`e.g., {"rules": {"wildcard": {"exclude-directives": ["img-src"]}}, "schemes": [{"scheme": "x-app:", `+
+		`"allow": true}]}. Used by the default command and lsp.`)
 	rootCmd.PersistentFlags().BoolVarP(&fJSON, "json", "j", false, "Return results in JSON format.")
-	rootCmd.PersistentFlags().BoolVarP(&fVerbose, "verbose", "v", false, "Print verbose output.")
+	rootCmd.PersistentFlags().BoolVarP(&fVerbose, "verbose", "v", false, "Print verbose output. "+
+		"In JSON, each token of each directive is listed as it was written, and marked when it was normalized.")
+}
+
+// parseOptions converts the root command's flags into options for csp.Parse.
+func parseOptions() []csp.Option {
+	opts := []csp.Option{csp.MinSeverity(minSeverity()), csp.WithStats(&parseStats)}
+
+	reportingSeverity, ok := parseSeverity(fReportingSeverity)
+	if !ok {
+		logger.Fatalf("invalid --missing-reporting-severity `%s`; expected one of: info, warn, error",
+			fReportingSeverity)
+	}
+
+	insecureSeverity, ok := parseSeverity(fInsecureSeverity)
+	if !ok {
+		logger.Fatalf("invalid --insecure-reporting-severity `%s`; expected one of: info, warn, error",
+			fInsecureSeverity)
+	}
+
+	opts = append(opts,
+		csp.WithMissingReportingSeverity(reportingSeverity),
+		csp.WithInsecureReportingSeverity(insecureSeverity),
+	)
+
+	switch mode := csp.Mode(strings.ToLower(fMode)); {
+	case fStrict:
+		opts = append(opts, csp.Strict())
+	case slices.Contains(csp.Modes, mode):
+		opts = append(opts, csp.WithMode(mode))
+	default:
+		logger.Fatalf("invalid --mode `%s`; expected one of: lenient, default, strict", fMode)
+	}
+
+	if fReportOnly {
+		opts = append(opts, csp.WithDisposition(csp.DispositionReport))
+	}
+
+	if ec := csp.ExecutionContext(strings.ToLower(fContext)); slices.Contains(csp.ExecutionContexts, ec) {
+		opts = append(opts, csp.WithExecutionContext(ec))
+	} else {
+		logger.Fatalf("invalid --context `%s`; expected one of: document, dedicated-worker, shared-worker, "+
+			"service-worker", fContext)
+	}
+
+	if fVerbose {
+		opts = append(opts, csp.WithRawTokens())
+	}
+
+	opts = append(opts, readRules().Options()...)
+
+	// --mode sets the host strictness, unless --host-strictness overrides it.
+	if fHostStrictness != "" {
+		switch strictness := csp.HostStrictness(strings.ToLower(fHostStrictness)); strictness {
+		case csp.HostStrictnessDefault, csp.HostStrictnessSpec, csp.HostStrictnessLenient:
+			opts = append(opts, csp.WithHostStrictness(strictness))
+		default:
+			logger.Fatalf("invalid --host-strictness `%s`; expected one of: default, spec, lenient", fHostStrictness)
+		}
+	}
+
+	return opts
+}
+
+// readRules reads the file named by --rules, or returns nil when it is not set.
+func readRules() *csp.RuleFile {
+	if fRules == "" {
+		return nil
+	}
+
+	f, err := os.Open(fRules)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	defer f.Close()
+
+	rules, err := csp.ReadRules(f)
+	if err != nil {
+		logger.Fatalf("%s: %v", fRules, err)
+	}
+
+	return rules
+}
+
+// minSeverity converts the --min-severity flag into a csp.Severity.
+func minSeverity() csp.Severity {
+	severity, ok := parseSeverity(fMinSeverity)
+	if !ok {
+		logger.Fatalf("invalid --min-severity `%s`; expected one of: info, warn, error", fMinSeverity)
+	}
+
+	return severity
+}
+
+// parseSeverity converts the name of a severity (e.g., `warn`) into a
+// csp.Severity, accepting the same names as csp.Severity.UnmarshalText.
+func parseSeverity(s string) (csp.Severity, bool) {
+	var severity csp.Severity
+
+	if err := severity.UnmarshalText([]byte(s)); err != nil {
+		return csp.SeverityInfo, false
+	}
+
+	return severity, true
+}
+
+// logStats logs the statistics from the last call to csp.Parse, when --verbose
+// is set.
+func logStats() {
+	if !fVerbose {
+		return
+	}
+
+	for i := range parseStats.Policies {
+		p := &parseStats.Policies[i]
+		logger.Info("policy stats", "policy", p.Index, "length", p.Length, "directives", p.Directives,
+			"tokens", p.Tokens, "duration", p.Duration)
+	}
+
+	logger.Info("parse stats", "policies", len(parseStats.Policies), "directives", parseStats.Directives,
+		"tokens", parseStats.Tokens, "diagnostics", parseStats.Diagnostics, "duration", parseStats.Duration)
+}
+
+// writeMetrics writes the statistics from the last call to csp.Parse to the
+// file named by --metrics-file, when it is set.
+func writeMetrics() {
+	if fMetricsFile == "" {
+		return
+	}
+
+	f, err := os.Create(fMetricsFile)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	defer f.Close()
+
+	if err := parseStats.WritePrometheus(f); err != nil {
+		logger.Fatalf("%s: %v", fMetricsFile, err)
+	}
+}
+
+// logErrors logs each error contained in err, which may be a multierror, and
+// counts them for the summary of the run.
+func logErrors(err error) {
+	summarize = true
+
+	if err == nil {
+		return
+	}
+
+	if merr, ok := err.(*multierror.Error); ok {
+		// Group the diagnostics by the policy they belong to.
+		errs := slices.Clone(merr.Errors)
+		slices.SortStableFunc(errs, func(a, b error) int {
+			return csp.PolicyIndexOf(a) - csp.PolicyIndexOf(b)
+		})
+
+		for _, e := range errs {
+			handleErrorMsg(e)
+		}
+	} else {
+		handleErrorMsg(err)
+	}
 }
 
 func handleErrorMsg(e error) {
+	l := logger
+
+	// Label diagnostics with the policy they belong to.
+	var pe *csp.PolicyError
+	if errors.As(e, &pe) {
+		l = logger.With("policy", pe.PolicyIndex, "disposition", pe.Disposition, "phase", pe.Phase)
+
+		if pe.Span != nil {
+			l = l.With("offset", fmt.Sprintf("%d-%d", pe.Span.Start, pe.Span.End))
+		}
+
+		if pe.Pointer != "" {
+			l = l.With("pointer", pe.Pointer)
+		}
+	}
+
+	runSummary.Add(csp.SeverityOf(e).String())
+
 	switch {
 	case strings.HasPrefix(e.Error(), "[ERROR]"):
-		logger.Errorf("%v", e.Error()[8:])
+		l.Errorf("%v", e.Error()[8:])
 	case strings.HasPrefix(e.Error(), "[WARN]"):
-		logger.Warnf("%v", e.Error()[7:])
+		l.Warnf("%v", e.Error()[7:])
 	case strings.HasPrefix(e.Error(), "[INFO]"):
-		logger.Infof("%v", e.Error()[7:])
+		l.Infof("%v", e.Error()[7:])
 	default:
-		logger.Errorf("%v", e.Error())
+		l.Errorf("%v", e.Error())
 	}
 }
@@ -0,0 +1,354 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+type (
+	// watchConfig is the configuration file for the watch command.
+	watchConfig struct {
+		// Interval is how often every target is checked (e.g., `5m`).
+		Interval string        `json:"interval"`
+		Targets  []watchTarget `json:"targets"`
+	}
+
+	// watchTarget is a single URL to monitor.
+	watchTarget struct {
+		URL string `json:"url"`
+
+		// Policy is the path to the file with the expected enforced policy,
+		// relative to the configuration file. If empty, the policy is not
+		// compared.
+		Policy string `json:"policy,omitempty"`
+
+		Alert watchAlert `json:"alert"`
+	}
+
+	// watchAlert controls when and where a target raises alerts.
+	watchAlert struct {
+		// MinSeverity is the lowest severity of diagnostic that raises an alert
+		// (`info`, `warn`, or `error`). Defaults to `error`.
+		MinSeverity string `json:"minSeverity,omitempty"`
+
+		// Webhook is a URL that receives each alert as a JSON POST request.
+		Webhook string `json:"webhook,omitempty"`
+	}
+
+	// watchState is what was last reported for a target, so that an unchanged
+	// problem is only alerted once.
+	watchState struct {
+		alerts []string
+	}
+)
+
+var (
+	fWatchConfig string
+	fWatchOnce   bool
+
+	watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Monitors the policies served by many URLs.",
+		Long: clihelpers.LongHelpText(`
+		Monitors the policies served by many URLs.
+
+		Reads a JSON configuration file that lists the URLs to monitor. Each target
+		may have the path to a file with its expected policy, and its own alert
+		settings:
+
+		  {
+		    "interval": "5m",
+		    "targets": [
+		      {
+		        "url": "https://example.com/",
+		        "policy": "policies/example.txt",
+		        "alert": {"minSeverity": "warn", "webhook": "https://hooks.example.com/csp"}
+		      }
+		    ]
+		  }
+
+		A target raises an alert when it cannot be fetched, when its enforced policy
+		differs from the expected policy, or when its policies have diagnostics at or
+		above minSeverity. An alert is only raised again after the problem changes.
+
+		Responses are cached according to their Cache-Control headers (for at most
+		one interval), and revalidated with conditional requests. Requests to the
+		targets and the webhooks time out after 30 seconds, and a webhook that does
+		not respond with a 2xx status is logged as an error.`),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := readWatchConfig(fWatchConfig)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			interval, err := time.ParseDuration(config.Interval)
+			if err != nil {
+				logger.Fatalf("invalid interval `%s`: %v", config.Interval, err)
+			}
+
+			// The same client sends the requests to the targets and to the
+			// webhooks, so that neither can stall the loop.
+			client := &http.Client{Timeout: watchTimeout}
+			fetcher := &csp.CachingFetcher{Client: client, MaxAge: interval}
+			states := make([]watchState, len(config.Targets))
+
+			for {
+				for i := range config.Targets {
+					alerts := checkWatchTarget(cmd.Context(), fetcher, &config.Targets[i])
+
+					if !slices.Equal(alerts, states[i].alerts) {
+						raiseWatchAlerts(cmd.Context(), client, &config.Targets[i], alerts)
+					}
+
+					states[i].alerts = alerts
+				}
+
+				if fWatchOnce {
+					return
+				}
+
+				time.Sleep(interval)
+			}
+		},
+	}
+)
+
+const (
+	// watchTimeout is how long a request to a target or a webhook may take,
+	// including reading the response.
+	watchTimeout = 30 * time.Second
+
+	// maxWebhookResponse is how much of a webhook's response is read.
+	maxWebhookResponse = 64 * 1024
+)
+
+func init() { // lint:allow_init
+	watchCmd.Flags().
+		StringVarP(&fWatchConfig, "config", "c", "", "The path to the JSON configuration file.")
+	watchCmd.Flags().
+		BoolVar(&fWatchOnce, "once", false, "Check every target once, then exit.")
+
+	_ = watchCmd.MarkFlagRequired("config")
+
+	rootCmd.AddCommand(watchCmd)
+}
+
+// readWatchConfig reads and validates the configuration file. The expected
+// policy files are resolved relative to it.
+func readWatchConfig(path string) (*watchConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &watchConfig{Interval: "5m"}
+
+	if err := json.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("invalid configuration file `%s`: %w", path, err)
+	}
+
+	if len(config.Targets) == 0 {
+		return nil, fmt.Errorf("configuration file `%s` has no targets", path)
+	}
+
+	for i := range config.Targets {
+		target := &config.Targets[i]
+
+		if target.URL == "" {
+			return nil, fmt.Errorf("target %d has no url", i)
+		}
+
+		if target.Alert.MinSeverity == "" {
+			target.Alert.MinSeverity = "error"
+		}
+
+		if _, ok := parseSeverity(target.Alert.MinSeverity); !ok {
+			return nil, fmt.Errorf(
+				"target `%s` has an invalid minSeverity `%s`; expected one of: info, warn, error",
+				target.URL,
+				target.Alert.MinSeverity,
+			)
+		}
+
+		if target.Policy != "" && !filepath.IsAbs(target.Policy) {
+			target.Policy = filepath.Join(filepath.Dir(path), target.Policy)
+		}
+	}
+
+	return config, nil
+}
+
+// checkWatchTarget fetches the target, and returns a message for each problem.
+func checkWatchTarget(ctx context.Context, fetcher csp.Fetcher, target *watchTarget) []string {
+	alerts := []string{}
+	severity, _ := parseSeverity(target.Alert.MinSeverity)
+
+	policies, err := csp.FetchPolicies(ctx, fetcher, target.URL, csp.MinSeverity(severity))
+
+	if merr, ok := err.(*multierror.Error); ok {
+		for _, e := range merr.Errors {
+			alerts = append(alerts, e.Error())
+		}
+	} else if err != nil {
+		return append(alerts, fmt.Sprintf("[ERROR] could not fetch `%s`: %v", target.URL, err))
+	}
+
+	if target.Policy == "" {
+		return alerts
+	}
+
+	b, err := os.ReadFile(target.Policy)
+	if err != nil {
+		return append(alerts, fmt.Sprintf("[ERROR] could not read the expected policy: %v", err))
+	}
+
+	served := []string{}
+
+	for i := range policies {
+		if policies[i].Disposition == csp.DispositionEnforce && policies[i].Delivery == csp.DeliveryHeader {
+			served = append(served, normalizePolicy(policies[i].Raw))
+		}
+	}
+
+	if expected := normalizePolicy(csp.ReadPolicyFile(string(b)).Policy); !slices.Contains(served, expected) {
+		alerts = append(alerts, fmt.Sprintf(
+			"[ERROR] the enforced policy does not match `%s`; served: %s",
+			target.Policy,
+			strings.Join(served, " | "),
+		))
+	}
+
+	return alerts
+}
+
+/*
+raiseWatchAlerts logs the alerts for a target, and sends them to its webhook.
+An empty list means that earlier problems were resolved. Failures to send the
+alerts are logged.
+
+----
+
+  - ctx (context.Context): Controls cancellation of the webhook request.
+
+  - client (*http.Client): The client that sends the webhook request.
+
+  - target (*watchTarget): The target that the alerts are about.
+
+  - alerts ([]string): The alerts, as diagnostic messages.
+*/
+func raiseWatchAlerts(ctx context.Context, client *http.Client, target *watchTarget, alerts []string) {
+	l := logger.With("url", target.URL)
+
+	if len(alerts) == 0 {
+		l.Info("no problems found")
+	}
+
+	for i := range alerts {
+		// Drop the severity prefix, which is shown by the logger.
+		msg := alerts[i]
+		if _, rest, ok := strings.Cut(msg, "] "); ok && strings.HasPrefix(msg, "[") {
+			msg = rest
+		}
+
+		switch csp.SeverityOf(fmt.Errorf("%s", alerts[i])) {
+		case csp.SeverityInfo:
+			l.Info(msg)
+		case csp.SeverityWarning:
+			l.Warn(msg)
+		default:
+			l.Error(msg)
+		}
+	}
+
+	if target.Alert.Webhook == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]any{"url": target.URL, "alerts": alerts})
+	if err != nil {
+		l.Error(err)
+
+		return
+	}
+
+	if err := sendWebhook(ctx, client, target.Alert.Webhook, body); err != nil {
+		l.Error("could not send the alert to the webhook", "err", err)
+	}
+}
+
+// sendWebhook posts a JSON body to the webhook, and returns an error unless it
+// responds with a 2xx status.
+func sendWebhook(ctx context.Context, client *http.Client, webhook string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Read the rest of the body, so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponse))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook `%s` responded with %s", webhook, resp.Status)
+	}
+
+	return nil
+}
+
+// normalizePolicy returns the policy with its directives trimmed, lowercased by
+// name, and sorted, so that policies that differ only in formatting compare
+// equal.
+func normalizePolicy(policy string) string {
+	directives := []string{}
+
+	for _, d := range strings.Split(policy, ";") {
+		fields := strings.Fields(d)
+		if len(fields) == 0 {
+			continue
+		}
+
+		fields[0] = strings.ToLower(fields[0])
+		directives = append(directives, strings.Join(fields, " "))
+	}
+
+	slices.Sort(directives)
+
+	return strings.Join(directives, "; ")
+}
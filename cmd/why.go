@@ -0,0 +1,121 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	clihelpers "github.com/northwood-labs/cli-helpers"
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fWhyPolicies  []string
+	fWhyReport    []string
+	fWhyLoad      string
+	fWhyDirective string
+
+	whyCmd = &cobra.Command{
+		Use:   "why",
+		Short: "Explains why a resource would be allowed or blocked.",
+		Long: clihelpers.LongHelpText(`
+		Explains why a resource would be allowed or blocked.
+
+		For each policy, reports which directive was used (after fallback), and which
+		source expression matched or why none did. A resource is only loaded when every
+		enforced policy allows it; report-only policies only report it.
+
+		Pass each enforced policy with --policy, each report-only policy with
+		--report-only, and the URL of the resource with --load. Use
+		--directive to choose the kind of load (e.g., script-src-elem for a <script
+		src> element, img-src for an image).`),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Parse as one response, so that report-only policies are numbered
+			// after the enforced ones.
+			header := http.Header{
+				csp.HeaderCSP:           fWhyPolicies,
+				csp.HeaderCSPReportOnly: fWhyReport,
+			}
+
+			policies, err := csp.ParseHeader(fCurrentURL, header)
+			logErrors(err)
+
+			result, err := csp.Combine(policies).Explain(fCurrentURL, fWhyDirective, fWhyLoad)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			explanations := result.Explanations
+
+			if fJSON {
+				jsonb, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+
+				fmt.Println(string(jsonb))
+
+				return
+			}
+
+			for i := range explanations {
+				decision := "blocked"
+				if explanations[i].Allowed {
+					decision = "allowed"
+				}
+
+				fmt.Printf(
+					"policy %d (%s): %s: %s\n",
+					explanations[i].PolicyIndex, policies[i].Disposition, decision, explanations[i].Reason,
+				)
+			}
+
+			if result.Allowed {
+				fmt.Printf("result: `%s` is allowed by `%s`\n", fWhyLoad, fWhyDirective)
+			} else {
+				fmt.Printf("result: `%s` is blocked by `%s` in policies %v\n", fWhyLoad, fWhyDirective, result.BlockedBy)
+			}
+
+			if len(result.ReportedBy) > 0 {
+				fmt.Printf("reported by policies %v\n", result.ReportedBy)
+			}
+		},
+	}
+)
+
+func init() { // lint:allow_init
+	whyCmd.Flags().
+		StringArrayVarP(&fWhyPolicies, "policy", "p", []string{}, "A Content-Security-Policy header value. May be "+
+			"passed more than once.")
+	whyCmd.Flags().
+		StringArrayVarP(&fWhyReport, "report-only", "r", []string{}, "A Content-Security-Policy-Report-Only header "+
+			"value. May be passed more than once.")
+	whyCmd.Flags().
+		StringVarP(&fWhyLoad, "load", "l", "", "The absolute URL of the resource being loaded.")
+	whyCmd.Flags().
+		StringVarP(&fWhyDirective, "directive", "d", "script-src-elem", "The directive that governs the load.")
+	whyCmd.Flags().
+		StringVarP(&fCurrentURL, "current-url", "u", "", "The current URL being evaluated. May be an empty string, "+
+			"but then 'self' sources will never match.")
+
+	_ = whyCmd.MarkFlagRequired("policy")
+	_ = whyCmd.MarkFlagRequired("load")
+
+	rootCmd.AddCommand(whyCmd)
+}
@@ -0,0 +1,591 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/net/html"
+)
+
+type (
+	// HTMLAudit compares the nonce-sources and hash-sources in a policy against
+	// the inline content of an HTML document.
+	HTMLAudit struct {
+		// Unused lists the nonce-sources and hash-sources that the policy declares,
+		// but that no element in the document uses (e.g., stale hashes).
+		Unused []DeclaredSource `json:"unused,omitempty"`
+
+		// Undeclared lists the inline `<script>` and `<style>` elements that the
+		// policy would block.
+		Undeclared []InlineContent `json:"undeclared,omitempty"`
+
+		// Frames lists the policies found on `<iframe>` elements.
+		Frames []FramePolicy `json:"frames,omitempty"`
+	}
+
+	// FramePolicy is a policy found on an `<iframe>` element: either its `csp`
+	// attribute (Embedded Enforcement), which the framed document must accept,
+	// or a `<meta>` element inside its `srcdoc` document.
+	//
+	// https://w3c.github.io/webappsec-cspee/
+	FramePolicy struct {
+		// Frame identifies the `<iframe>` element (e.g., `iframe[0]`, or
+		// `iframe[0]/iframe[1]` for an element inside a `srcdoc` document).
+		Frame string `json:"frame"`
+
+		// Source is either `csp-attribute` or `srcdoc-meta`.
+		Source string `json:"source"`
+
+		Policy *Policy `json:"policy"`
+
+		// Looser lists the sources that the policy allows, but that the
+		// embedding policy blocks.
+		Looser []DeclaredSource `json:"looser,omitempty"`
+
+		// Unrestricted lists the directives that the embedding policy restricts,
+		// but that a `csp` attribute does not. It is always empty for a `srcdoc`
+		// policy, which is enforced along with the embedding policy.
+		Unrestricted []string `json:"unrestricted,omitempty"`
+	}
+
+	// DeclaredSource is a nonce-source or hash-source from a policy.
+	DeclaredSource struct {
+		Directive string `json:"directive"`
+		Source    string `json:"source"`
+	}
+
+	// InlineContent is an inline `<script>` or `<style>` element from an HTML
+	// document.
+	InlineContent struct {
+		// Element is either `script` or `style`.
+		Element string `json:"element"`
+
+		// Directive is the directive (after fallback) that governs the element.
+		Directive string `json:"directive"`
+
+		// Hash is the `'sha256-...'` hash-source that would allow the element.
+		Hash string `json:"hash"`
+
+		// Nonce is the value of the element's `nonce` attribute, if any.
+		Nonce string `json:"nonce,omitempty"`
+
+		// Frame identifies the `<iframe srcdoc>` element that contains the
+		// element, or is empty for the audited document itself.
+		Frame string `json:"frame,omitempty"`
+	}
+
+	// auditElement is a `<script>` or `<style>` element, or a `<link>` element
+	// that loads a stylesheet, found while walking the document.
+	auditElement struct {
+		directive string
+		inline    bool
+		content   string
+		nonce     string
+		frame     string
+	}
+
+	// auditFrame is an `<iframe>` element found while walking the document.
+	auditFrame struct {
+		path string
+		node *html.Node
+
+		// srcdoc is the parsed `srcdoc` document, or nil.
+		srcdoc *html.Node
+	}
+)
+
+// Sources of a FramePolicy.
+const (
+	FrameSourceCSPAttribute = "csp-attribute"
+	FrameSourceSrcdocMeta   = "srcdoc-meta"
+)
+
+/*
+AuditHTML compares the nonce-sources and hash-sources in the policy against the
+elements in an HTML document. It reports sources that nothing in the document
+uses, and inline `<script>` and `<style>` elements that the policy would block.
+
+Documents in `<iframe srcdoc>` elements inherit the policy, so their elements
+are audited along with the rest of the document. The `csp` attributes of
+`<iframe>` elements, and the `<meta>` policies of `srcdoc` documents, are parsed
+and returned in HTMLAudit.Frames, with their diagnostics. Each of them is
+compared with the policy: a `csp` attribute that is less strict holds the framed
+document to a weaker policy, and sources in a `srcdoc` policy that the inherited
+policy blocks have no effect.
+
+The returned error contains a diagnostic for each finding. Errors reading or
+parsing the document are returned with a nil HTMLAudit.
+
+----
+
+  - policy (*Policy): The parsed policy to evaluate.
+
+  - r (io.Reader): The HTML document.
+*/
+func AuditHTML(policy *Policy, r io.Reader) (*HTMLAudit, error) {
+	var errs *multierror.Error
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := collectAuditElements(doc, nil)
+	audit := &HTMLAudit{}
+
+	frames := collectFrames(doc, "")
+
+	for i := range frames {
+		errs = multierror.Append(errs, auditFramePolicies(policy, audit, &frames[i]))
+
+		if frames[i].srcdoc == nil {
+			continue
+		}
+
+		for _, element := range collectAuditElements(frames[i].srcdoc, nil) {
+			element.frame = frames[i].path
+			elements = append(elements, element)
+		}
+	}
+
+	for _, directive := range []string{"script-src-elem", "style-src-elem"} {
+		effective, list, ok := policy.effectiveSourceList(directive)
+		if !ok {
+			continue
+		}
+
+		used := map[string]bool{}
+
+		for i := range elements {
+			if elements[i].directive != directive {
+				continue
+			}
+
+			allowed := false
+
+			for j := range list.SourceExprs {
+				if auditSourceMatches(&list.SourceExprs[j], &elements[i]) {
+					used[list.SourceExprs[j].String()] = true
+					allowed = true
+				}
+			}
+
+			if !elements[i].inline || allowed || allowsUnsafeInline(list) {
+				continue
+			}
+
+			content := InlineContent{
+				Element:   strings.TrimSuffix(strings.TrimSuffix(directive, "-src-elem"), "-src"),
+				Directive: effective,
+				Hash:      hashSource("sha256", elements[i].content),
+				Nonce:     elements[i].nonce,
+				Frame:     elements[i].frame,
+			}
+
+			audit.Undeclared = append(audit.Undeclared, content)
+			err := fmt.Errorf(errCSP1202, content.Element, effective, content.Hash)
+
+			if content.Frame != "" {
+				errs = multierror.Append(errs, frameErrors(content.Frame, "`srcdoc` document", err)...)
+			} else {
+				errs = multierror.Append(errs, err)
+			}
+		}
+
+		for i := range list.SourceExprs {
+			expr := &list.SourceExprs[i]
+
+			if expr.NonceSource == "" && expr.HashSource == "" {
+				continue
+			}
+
+			if used[expr.String()] || declaredIn(audit.Unused, effective, expr.String()) {
+				continue
+			}
+
+			audit.Unused = append(audit.Unused, DeclaredSource{Directive: effective, Source: expr.String()})
+			errs = multierror.Append(errs, fmt.Errorf(errCSP1201, effective, expr.String()))
+		}
+	}
+
+	return audit, errs.ErrorOrNil()
+}
+
+/*
+collectFrames walks the document and returns its `<iframe>` elements in
+document order, followed in each case by the `<iframe>` elements of its `srcdoc`
+document.
+
+----
+
+  - doc (*html.Node): The document to walk.
+
+  - prefix (string): The path of the `<iframe>` element that contains doc,
+    followed by `/`, or empty for the audited document.
+*/
+func collectFrames(doc *html.Node, prefix string) []auditFrame {
+	var (
+		frames []auditFrame
+		walk   func(n *html.Node)
+	)
+
+	count := 0
+
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "iframe" {
+			frame := auditFrame{path: fmt.Sprintf("%siframe[%d]", prefix, count), node: n}
+			count++
+
+			if hasAttr(n, "srcdoc") {
+				// html.Parse only fails when reading fails, which a string cannot.
+				frame.srcdoc, _ = html.Parse(strings.NewReader(attr(n, "srcdoc")))
+			}
+
+			frames = append(frames, frame)
+
+			if frame.srcdoc != nil {
+				frames = append(frames, collectFrames(frame.srcdoc, frame.path+"/")...)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(doc)
+
+	return frames
+}
+
+/*
+auditFramePolicies parses the `csp` attribute of an `<iframe>` element and the
+`<meta>` policies of its `srcdoc` document, compares them with the embedding
+policy, and adds them to the audit. Their diagnostics are returned with the
+frame and the source of the policy added to the message.
+
+----
+
+  - embedder (*Policy): The policy of the audited document.
+
+  - audit (*HTMLAudit): The audit to add the policies to.
+
+  - frame (*auditFrame): The `<iframe>` element.
+*/
+func auditFramePolicies(embedder *Policy, audit *HTMLAudit, frame *auditFrame) error {
+	var errs *multierror.Error
+
+	if hasAttr(frame.node, "csp") {
+		policies, err := Parse("", "", []string{attr(frame.node, "csp")}, WithoutSelfValidation(),
+			WithoutReportingValidation(), MinSeverity(SeverityWarning))
+		errs = multierror.Append(errs, frameErrors(frame.path, "`csp` attribute", err)...)
+
+		// Embedded Enforcement only accepts a single serialized policy.
+		if len(policies) > 1 {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP1206, frame.path, len(policies)))
+		}
+
+		for i := range policies {
+			framePolicy := FramePolicy{Frame: frame.path, Source: FrameSourceCSPAttribute, Policy: policies[i]}
+			errs = multierror.Append(errs, compareFramePolicy(embedder, &framePolicy)...)
+			audit.Frames = append(audit.Frames, framePolicy)
+		}
+	}
+
+	if frame.srcdoc == nil {
+		return errs.ErrorOrNil()
+	}
+
+	metas, _ := collectMetaPolicies(frame.srcdoc, false, nil, 0)
+	if len(metas) == 0 {
+		return errs.ErrorOrNil()
+	}
+
+	policies, err := Parse("", "", metas, WithoutSelfValidation(), WithoutReportingValidation(),
+		WithDelivery(DeliveryMeta), MinSeverity(SeverityWarning))
+	errs = multierror.Append(errs, frameErrors(frame.path, "`srcdoc` `<meta>` policy", err)...)
+
+	for i := range policies {
+		framePolicy := FramePolicy{Frame: frame.path, Source: FrameSourceSrcdocMeta, Policy: policies[i]}
+		errs = multierror.Append(errs, compareFramePolicy(embedder, &framePolicy)...)
+		audit.Frames = append(audit.Frames, framePolicy)
+	}
+
+	return errs.ErrorOrNil()
+}
+
+/*
+compareFramePolicy compares the policy of a frame with the embedding policy,
+directive by directive (with fallback applied to both), and records where it is
+less strict. Each source is reported once, for the first directive that allows
+it.
+
+----
+
+  - embedder (*Policy): The policy of the audited document.
+
+  - frame (*FramePolicy): The policy of the frame. Its Looser and Unrestricted
+    fields are set.
+*/
+func compareFramePolicy(embedder *Policy, frame *FramePolicy) []error {
+	errs := []error{}
+	unrestricted := map[string][]string{}
+	order := []string{}
+
+	for _, name := range sourceListDirectives {
+		outerEffective, outer, ok := embedder.effectiveSourceList(name)
+		if !ok {
+			continue
+		}
+
+		effective, inner, ok := frame.Policy.effectiveSourceList(name)
+		if !ok {
+			if frame.Source == FrameSourceCSPAttribute {
+				if _, seen := unrestricted[outerEffective]; !seen {
+					order = append(order, outerEffective)
+				}
+
+				unrestricted[outerEffective] = append(unrestricted[outerEffective], name)
+				frame.Unrestricted = append(frame.Unrestricted, name)
+			}
+
+			continue
+		}
+
+		for _, expr := range inner.expressions() {
+			if coveredBy(expr, outer) || declaredIn(frame.Looser, effective, expr.String()) {
+				continue
+			}
+
+			frame.Looser = append(frame.Looser, DeclaredSource{Directive: effective, Source: expr.String()})
+
+			msg := errCSP1207
+			if frame.Source == FrameSourceSrcdocMeta {
+				msg = errCSP1209
+			}
+
+			errs = append(errs, fmt.Errorf(msg, frame.Frame, effective, expr.String(), outerEffective))
+		}
+	}
+
+	for _, outerEffective := range order {
+		errs = append(errs, fmt.Errorf(
+			errCSP1208, frame.Frame, strings.Join(unrestricted[outerEffective], "`, `"), outerEffective,
+		))
+	}
+
+	return errs
+}
+
+// frameErrors adds the frame and the source of a policy to each of its
+// diagnostics, after the severity prefix.
+func frameErrors(path, source string, err error) []error {
+	var errs []error
+
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	} else if err != nil {
+		errs = []error{err}
+	}
+
+	framed := make([]error, 0, len(errs))
+
+	for i := range errs {
+		severity, msg, _ := strings.Cut(errs[i].Error(), "] ")
+		framed = append(framed, fmt.Errorf("%s] `%s` %s: %s", severity, path, source, msg))
+	}
+
+	return framed
+}
+
+// collectAuditElements walks the document and returns the elements that are
+// governed by `script-src-elem` or `style-src-elem`, in document order. Data
+// blocks (e.g., `<script type="application/ld+json">`) are skipped.
+func collectAuditElements(n *html.Node, elements []auditElement) []auditElement {
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "script":
+			if !isScriptBlock(n) {
+				break
+			}
+
+			elements = append(elements, auditElement{
+				directive: "script-src-elem",
+				inline:    !hasAttr(n, "src"),
+				content:   textContent(n),
+				nonce:     attr(n, "nonce"),
+			})
+		case "style":
+			elements = append(elements, auditElement{
+				directive: "style-src-elem",
+				inline:    true,
+				content:   textContent(n),
+				nonce:     attr(n, "nonce"),
+			})
+		case "link":
+			if strings.EqualFold(attr(n, "rel"), "stylesheet") {
+				elements = append(elements, auditElement{
+					directive: "style-src-elem",
+					nonce:     attr(n, "nonce"),
+				})
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		elements = collectAuditElements(c, elements)
+	}
+
+	return elements
+}
+
+/*
+isScriptBlock reports whether a `<script>` element is processed by the browser,
+and so governed by the policy. Scripts whose `type` is not a JavaScript MIME
+type, `module`, `importmap`, or `speculationrules` are data blocks, which are
+never executed.
+
+https://html.spec.whatwg.org/multipage/scripting.html#prepare-the-script-element
+
+----
+
+  - n (*html.Node): The `<script>` element.
+*/
+func isScriptBlock(n *html.Node) bool {
+	if !hasAttr(n, "type") {
+		return true
+	}
+
+	scriptType := strings.ToLower(strings.TrimSpace(attr(n, "type")))
+	essence, _, _ := strings.Cut(scriptType, ";")
+
+	switch strings.TrimSpace(essence) {
+	case "", "module", "importmap", "speculationrules",
+		"application/ecmascript", "application/javascript", "application/x-ecmascript",
+		"application/x-javascript", "text/ecmascript", "text/javascript", "text/javascript1.0",
+		"text/javascript1.1", "text/javascript1.2", "text/javascript1.3", "text/javascript1.4",
+		"text/javascript1.5", "text/jscript", "text/livescript", "text/x-ecmascript", "text/x-javascript":
+		return true
+	}
+
+	return false
+}
+
+// auditSourceMatches reports whether a nonce-source or hash-source applies to
+// the element. Hash-sources only apply to inline content.
+func auditSourceMatches(expr *SourceExpr, element *auditElement) bool {
+	switch {
+	case expr.NonceSource != "":
+		return element.nonce != "" && element.nonce == nonceValue(expr.NonceSource)
+	case expr.HashSource != "" && element.inline:
+		algorithm, _, _ := strings.Cut(strings.Trim(expr.HashSource, `'`), "-")
+
+		return strings.EqualFold(expr.HashSource, hashSource(strings.ToLower(algorithm), element.content))
+	}
+
+	return false
+}
+
+// allowsUnsafeInline reports whether the list allows all inline content. Per
+// CSP3, `'unsafe-inline'` is ignored when a nonce-source or hash-source is
+// present.
+func allowsUnsafeInline(list *SourceListItem) bool {
+	unsafeInline := false
+
+	for i := range list.SourceExprs {
+		switch {
+		case list.SourceExprs[i].NonceSource != "", list.SourceExprs[i].HashSource != "":
+			return false
+		case strings.EqualFold(list.SourceExprs[i].KeywordSource, `'unsafe-inline'`):
+			unsafeInline = true
+		}
+	}
+
+	return unsafeInline
+}
+
+// hashSource returns the hash-source (e.g., `'sha256-...'`) for the content.
+func hashSource(algorithm, content string) string {
+	var h hash.Hash
+
+	switch algorithm {
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	default:
+		algorithm = "sha256"
+		h = sha256.New()
+	}
+
+	h.Write([]byte(content))
+
+	return "'" + algorithm + "-" + base64.StdEncoding.EncodeToString(h.Sum(nil)) + "'"
+}
+
+// declaredIn reports whether the source has already been reported for the
+// directive.
+func declaredIn(sources []DeclaredSource, directive, source string) bool {
+	for i := range sources {
+		if sources[i].Directive == directive && sources[i].Source == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attr returns the value of the attribute, or an empty string.
+func attr(n *html.Node, key string) string {
+	for i := range n.Attr {
+		if strings.EqualFold(n.Attr[i].Key, key) {
+			return n.Attr[i].Val
+		}
+	}
+
+	return ""
+}
+
+// hasAttr reports whether the element has the attribute.
+func hasAttr(n *html.Node, key string) bool {
+	for i := range n.Attr {
+		if strings.EqualFold(n.Attr[i].Key, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// textContent returns the concatenated text of the element's children.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,173 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestAuditHTML(t *testing.T) {
+	// sha256 of `alert(1)`
+	const alertHash = "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='"
+
+	// sha256 of `{}`
+	const emptyObjectHash = "'sha256-RBNvo1WzZ4oRRq0W9+hknpT7T8If536DEMBg9hyq/4o='"
+
+	for name, tc := range map[string]struct {
+		CSP        string
+		HTML       string
+		Unused     []DeclaredSource
+		Undeclared []InlineContent
+	}{
+		"hash and nonce in use": {
+			CSP:  "script-src " + alertHash + " 'nonce-abc123'",
+			HTML: `<script>alert(1)</script><script nonce="abc123" src="/app.js"></script>`,
+		},
+		"stale hash": {
+			CSP:    "script-src 'sha256-AAAA' 'nonce-abc123'",
+			HTML:   `<script nonce="abc123">alert(1)</script>`,
+			Unused: []DeclaredSource{{Directive: "script-src", Source: "'sha256-AAAA'"}},
+		},
+		"undeclared inline script": {
+			CSP:    "default-src 'self' 'nonce-abc123'",
+			HTML:   `<script>alert(1)</script>`,
+			Unused: []DeclaredSource{{Directive: "default-src", Source: "'nonce-abc123'"}},
+			Undeclared: []InlineContent{
+				{Element: "script", Directive: "default-src", Hash: alertHash},
+			},
+		},
+		"unsafe-inline": {
+			CSP:  "style-src 'unsafe-inline'",
+			HTML: `<style>body { color: red; }</style>`,
+		},
+		"data blocks": {
+			CSP: "script-src 'self'",
+			HTML: `<script type="application/ld+json">{"@type": "Organization"}</script>` +
+				`<script type="text/plain">alert(1)</script>`,
+		},
+		"module and import map": {
+			CSP:  "script-src 'self'",
+			HTML: `<script type="importmap">{}</script><script type=" Module ">alert(1)</script>`,
+			Undeclared: []InlineContent{
+				{Element: "script", Directive: "script-src", Hash: emptyObjectHash},
+				{Element: "script", Directive: "script-src", Hash: alertHash},
+			},
+		},
+		"unrestricted": {
+			CSP:  "img-src 'self'",
+			HTML: `<script>alert(1)</script>`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, _ := Parse("", "", []string{tc.CSP})
+			audit, err := AuditHTML(policies[0], strings.NewReader(tc.HTML))
+
+			assert.Equal(tc.Unused, audit.Unused)
+			assert.Equal(tc.Undeclared, audit.Undeclared)
+
+			if len(tc.Unused) == 0 && len(tc.Undeclared) == 0 {
+				assert.NoError(err)
+			} else {
+				assert.Error(err)
+			}
+		})
+	}
+}
+
+func TestAuditHTMLFrames(t *testing.T) {
+	assert := assert.New(t)
+
+	// sha256 of `alert(1)`
+	const alertHash = "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='"
+
+	policies, _ := Parse("", "", []string{"script-src " + alertHash + " 'nonce-abc123'"})
+	audit, err := AuditHTML(policies[0], strings.NewReader(`
+		<iframe csp="script-src 'self'; img-src *"></iframe>
+		<iframe srcdoc="<meta http-equiv='Content-Security-Policy' content='style-src https://cdn.example.com:0'>
+			<script>alert(1)</script>
+			<iframe srcdoc='<script>alert(2)</script>'></iframe>"></iframe>
+		<iframe csp="script-src 'self', img-src 'none'"></iframe>`))
+
+	// The hash is used inside the `srcdoc` document, and the nonce nowhere.
+	assert.Equal([]DeclaredSource{{Directive: "script-src", Source: "'nonce-abc123'"}}, audit.Unused)
+	assert.Len(audit.Undeclared, 1)
+	assert.Equal("iframe[1]/iframe[0]", audit.Undeclared[0].Frame)
+
+	assert.Len(audit.Frames, 4)
+	assert.Equal(FramePolicy{
+		Frame:  "iframe[0]",
+		Source: FrameSourceCSPAttribute,
+		Policy: audit.Frames[0].Policy,
+		Looser: []DeclaredSource{{Directive: "script-src", Source: "'self'"}},
+	}, audit.Frames[0])
+	assert.Equal("iframe[1]", audit.Frames[1].Frame)
+	assert.Equal(FrameSourceSrcdocMeta, audit.Frames[1].Source)
+	assert.Equal(DeliveryMeta, audit.Frames[1].Policy.Delivery)
+	assert.Equal("iframe[2]", audit.Frames[3].Frame)
+
+	assert.ErrorContains(err, "[WARN] `iframe[0]` `csp` attribute: directive `img-src`: host-source `*` is a wildcard")
+	assert.ErrorContains(err, "[ERROR] `iframe[1]` `srcdoc` `<meta>` policy: directive `style-src`: host-source "+
+		"`https://cdn.example.com:0` has an invalid port")
+	assert.ErrorContains(err, "`iframe[2]`: the `csp` attribute holds 2 policies")
+	assert.ErrorContains(err, "[WARN] `iframe[1]/iframe[0]` `srcdoc` document: an inline `<script>` element is "+
+		"blocked by `script-src`")
+	assert.ErrorContains(err, "[WARN] `iframe[0]` `csp` attribute: directive `script-src` allows `'self'`, "+
+		"which the embedding policy blocks in `script-src`, so the framed document is held to a weaker policy "+
+		"[CSP-1207]")
+}
+
+func TestAuditHTMLFramesEmbeddingPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := Parse("", "", []string{"default-src 'self'; script-src 'self'; img-src 'self' https:"})
+	audit, err := AuditHTML(policies[0], strings.NewReader(`
+		<iframe csp="script-src 'self' https://cdn.example.com; img-src https://images.example.com"></iframe>
+		<iframe csp="default-src 'self'; img-src https:"></iframe>
+		<iframe srcdoc="<meta http-equiv='Content-Security-Policy' content='img-src data: https:; font-src *'>">
+		</iframe>`))
+
+	assert.Len(audit.Frames, 3)
+
+	assert.Equal([]DeclaredSource{{Directive: "script-src", Source: "https://cdn.example.com"}},
+		audit.Frames[0].Looser)
+	assert.Equal([]string{"default-src", "child-src", "connect-src", "fenced-frame-src", "font-src", "frame-src",
+		"manifest-src", "media-src", "object-src", "style-src", "style-src-attr", "style-src-elem"},
+		audit.Frames[0].Unrestricted)
+
+	// At least as strict as the embedding policy.
+	assert.Empty(audit.Frames[1].Looser)
+	assert.Empty(audit.Frames[1].Unrestricted)
+
+	// A `srcdoc` document is also held to the embedding policy, so directives
+	// it does not restrict are not reported.
+	assert.Equal([]DeclaredSource{
+		{Directive: "font-src", Source: "*"},
+		{Directive: "img-src", Source: "data:"},
+	}, audit.Frames[2].Looser)
+	assert.Empty(audit.Frames[2].Unrestricted)
+
+	assert.ErrorContains(err, "[WARN] `iframe[0]` `csp` attribute: the policy does not restrict `default-src`, "+
+		"`child-src`, `connect-src`")
+	assert.ErrorContains(err, "which the embedding policy restricts with `default-src` [CSP-1208]")
+	assert.ErrorContains(err, "[INFO] `iframe[2]` `srcdoc` `<meta>` policy: directive `img-src` allows `data:`, "+
+		"but the `srcdoc` document inherits the embedding policy, which blocks it in `img-src` [CSP-1209]")
+	assert.NotContains(err.Error(), "`iframe[1]`")
+}
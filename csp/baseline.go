@@ -0,0 +1,304 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+type (
+	// BaselineInfo describes an industry reference policy that policies can be
+	// compared against.
+	BaselineInfo struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Reference   string `json:"reference"`
+		Policy      string `json:"policy"`
+	}
+
+	// BaselineComparison lists the protections where a policy is weaker or
+	// stronger than a baseline, and the ones where they agree.
+	BaselineComparison struct {
+		Baseline string `json:"baseline"`
+
+		// Weaker has the protections that the baseline has, but the policy does
+		// not. Stronger has the protections that the policy has, but the
+		// baseline does not.
+		Weaker   []BaselineCheck `json:"weaker"`
+		Stronger []BaselineCheck `json:"stronger"`
+
+		// Matched has the protections that both have, and Missing has the ones
+		// that neither has.
+		Matched []BaselineCheck `json:"matched"`
+		Missing []BaselineCheck `json:"missing"`
+	}
+
+	// BaselineCheck is a single protection that a policy may or may not have.
+	BaselineCheck struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	// baselineCheck is a BaselineCheck, and how to tell whether a policy has
+	// the protection.
+	baselineCheck struct {
+		BaselineCheck
+
+		protects func(p *Policy) bool
+	}
+)
+
+// baselines are the reference policies returned by Baselines, in the order they
+// are listed. Nonces are placeholders: any nonce in a compared policy counts.
+var baselines = []BaselineInfo{
+	{
+		Name:        "owasp",
+		Description: "The restrictive starting policy from the OWASP Content Security Policy Cheat Sheet.",
+		Reference:   "https://cheatsheetseries.owasp.org/cheatsheets/Content_Security_Policy_Cheat_Sheet.html",
+		Policy: "default-src 'none'; script-src 'self'; connect-src 'self'; img-src 'self'; style-src 'self'; " +
+			"frame-ancestors 'self'; form-action 'self'",
+	},
+	{
+		Name:        "google-strict",
+		Description: "Google's nonce-based strict CSP, with fallbacks for older browsers.",
+		Reference:   "https://csp.withgoogle.com/docs/strict-csp.html",
+		Policy: "script-src 'nonce-cmFuZG9tLW5vbmNlLXZhbHVl' 'strict-dynamic' 'unsafe-inline' https:; " +
+			"object-src 'none'; base-uri 'none'",
+	},
+	{
+		Name:        "mozilla-observatory",
+		Description: "A policy that passes the CSP test of the Mozilla HTTP Observatory without a penalty.",
+		Reference:   "https://developer.mozilla.org/en-US/observatory/docs/tests_and_scoring",
+		Policy: "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; " +
+			"frame-ancestors 'none'",
+	},
+}
+
+// baselineChecks are the protections that CompareBaseline looks for, in the
+// order they are reported.
+var baselineChecks = []baselineCheck{
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "inline-scripts",
+			Description: "Inline scripts are blocked, or only allowed by a nonce or hash.",
+		},
+		protects: func(p *Policy) bool { return blocksInline(p, "script-src-elem") },
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "eval",
+			Description: "`eval()` and similar string-to-code functions are blocked.",
+		},
+		protects: func(p *Policy) bool {
+			_, list, ok := p.effectiveSourceList("script-src")
+
+			return ok && !containsKeyword(list, `'unsafe-eval'`)
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "script-sources",
+			Description: "Scripts only load from specific sources, rather than from `*` or a whole scheme.",
+		},
+		protects: func(p *Policy) bool {
+			_, list, ok := p.effectiveSourceList("script-src-elem")
+
+			// 'strict-dynamic' makes browsers ignore host-sources and scheme-sources.
+			return ok && (containsKeyword(list, `'strict-dynamic'`) || !hasBroadSource(list))
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "inline-styles",
+			Description: "Inline styles are blocked, or only allowed by a nonce or hash.",
+		},
+		protects: func(p *Policy) bool { return blocksInline(p, "style-src-elem") },
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "plugins",
+			Description: "Plugins are blocked (`object-src 'none'`).",
+		},
+		protects: func(p *Policy) bool {
+			_, list, ok := p.effectiveSourceList("object-src")
+
+			return ok && list.None
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "default-src",
+			Description: "`default-src` restricts every fetch directive that does not have its own list.",
+		},
+		protects: func(p *Policy) bool {
+			list, ok := p.sourceList("default-src")
+
+			return ok && !hasBroadSource(list)
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "base-uri",
+			Description: "`<base>` cannot point relative URLs at another origin.",
+		},
+		protects: func(p *Policy) bool {
+			list, ok := p.sourceList("base-uri")
+
+			return ok && (list.None || (len(list.SourceExprs) == 1 && containsKeyword(list, `'self'`)))
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "form-action",
+			Description: "Forms can only be submitted to specific sources.",
+		},
+		protects: func(p *Policy) bool {
+			list, ok := p.sourceList("form-action")
+
+			return ok && !hasBroadSource(list)
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "framing",
+			Description: "Only specific origins may frame the document (`frame-ancestors`).",
+		},
+		protects: func(p *Policy) bool {
+			list, ok := mergeSourceList(p, "frame-ancestors")
+
+			return ok && !hasBroadSource(&list)
+		},
+	},
+	{
+		BaselineCheck: BaselineCheck{
+			Name:        "mixed-content",
+			Description: "Insecure requests are upgraded to HTTPS (`upgrade-insecure-requests`).",
+		},
+		protects: func(p *Policy) bool { return p.UpgradeInsecureReq },
+	},
+}
+
+// Baselines returns the reference policies, in a stable order.
+func Baselines() []BaselineInfo {
+	list := make([]BaselineInfo, len(baselines))
+	copy(list, baselines)
+
+	return list
+}
+
+/*
+CompareBaseline compares a policy against a named reference policy, and reports
+the protections where the policy is weaker or stronger than it. The name is
+case-insensitive.
+
+----
+
+  - policy (*Policy): The parsed policy to compare.
+
+  - name (string): The name of the baseline (e.g., `owasp`). See Baselines for
+    the full list.
+*/
+func CompareBaseline(policy *Policy, name string) (*BaselineComparison, error) {
+	names := make([]string, 0, len(baselines))
+
+	for i := range baselines {
+		if !strings.EqualFold(baselines[i].Name, name) {
+			names = append(names, baselines[i].Name)
+
+			continue
+		}
+
+		// The baselines are known to be valid, so only the informational
+		// diagnostics about the missing URLs and reporting remain.
+		parsed, _ := Parse("", "", []string{baselines[i].Policy})
+		baseline := parsed[0]
+
+		comparison := &BaselineComparison{
+			Baseline: baselines[i].Name,
+			Weaker:   []BaselineCheck{},
+			Stronger: []BaselineCheck{},
+			Matched:  []BaselineCheck{},
+			Missing:  []BaselineCheck{},
+		}
+
+		for _, check := range baselineChecks {
+			switch ours, theirs := check.protects(policy), check.protects(baseline); {
+			case ours && theirs:
+				comparison.Matched = append(comparison.Matched, check.BaselineCheck)
+			case theirs:
+				comparison.Weaker = append(comparison.Weaker, check.BaselineCheck)
+			case ours:
+				comparison.Stronger = append(comparison.Stronger, check.BaselineCheck)
+			default:
+				comparison.Missing = append(comparison.Missing, check.BaselineCheck)
+			}
+		}
+
+		return comparison, nil
+	}
+
+	return nil, fmt.Errorf(errCSP0024, name, strings.Join(names, ", "))
+}
+
+/*
+blocksInline reports whether the effective source list of the directive blocks
+inline content: it has no `'unsafe-inline'`, or it also has a nonce-source, a
+hash-source, or `'strict-dynamic'`, which make browsers ignore
+`'unsafe-inline'`.
+
+----
+
+  - p (*Policy): The parsed policy.
+
+  - directive (string): The directive that governs the inline content (e.g.,
+    `script-src-elem`).
+*/
+func blocksInline(p *Policy, directive string) bool {
+	_, list, ok := p.effectiveSourceList(directive)
+	if !ok {
+		return false
+	}
+
+	if !containsKeyword(list, `'unsafe-inline'`) {
+		return true
+	}
+
+	for _, expr := range list.SourceExprs {
+		if expr.NonceSource != "" || expr.HashSource != "" || strings.EqualFold(expr.KeywordSource, `'strict-dynamic'`) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasBroadSource reports whether the source list allows a whole scheme (e.g.,
+// `https:`) or any host (`*`).
+func hasBroadSource(list *SourceListItem) bool {
+	for _, expr := range list.SourceExprs {
+		if expr.SchemeSource != "" {
+			return true
+		}
+
+		if _, host, _, _ := hostsource.Split(expr.HostSource); expr.HostSource != "" && host == "*" {
+			return true
+		}
+	}
+
+	return false
+}
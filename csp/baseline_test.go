@@ -0,0 +1,100 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkNames(checks []BaselineCheck) []string {
+	names := make([]string, len(checks))
+
+	for i := range checks {
+		names[i] = checks[i].Name
+	}
+
+	return names
+}
+
+func TestBaselines(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, baseline := range Baselines() {
+		// Every baseline must be free of warnings and errors.
+		_, err := Parse("https://example.com", "", []string{baseline.Policy}, MinSeverity(SeverityWarning))
+		assert.NoError(err, baseline.Name)
+
+		// A baseline is never weaker or stronger than itself.
+		policies, _ := Parse("", "", []string{baseline.Policy})
+		comparison, err := CompareBaseline(policies[0], baseline.Name)
+		assert.NoError(err)
+		assert.Empty(comparison.Weaker, baseline.Name)
+		assert.Empty(comparison.Stronger, baseline.Name)
+		assert.Len(comparison.Matched, len(baselineChecks)-len(comparison.Missing))
+	}
+
+	_, err := CompareBaseline(&Policy{}, "bogus")
+	assert.ErrorContains(err, "unknown baseline `bogus`; expected one of: owasp, google-strict, mozilla-observatory")
+}
+
+func TestCompareBaseline(t *testing.T) {
+	for name, tc := range map[string]struct {
+		policy   string
+		baseline string
+		weaker   []string
+		stronger []string
+	}{
+		"no fetch restrictions": {
+			policy:   "upgrade-insecure-requests",
+			baseline: "owasp",
+			weaker: []string{
+				"inline-scripts", "eval", "script-sources", "inline-styles", "plugins", "default-src",
+				"form-action", "framing",
+			},
+			stronger: []string{"mixed-content"},
+		},
+		"unsafe inline and broad sources": {
+			policy:   "default-src 'self'; script-src 'self' 'unsafe-inline' 'unsafe-eval' https:; object-src 'none'",
+			baseline: "mozilla-observatory",
+			weaker:   []string{"inline-scripts", "eval", "script-sources", "base-uri", "form-action", "framing"},
+			stronger: []string{},
+		},
+		"strict dynamic ignores unsafe-inline and schemes": {
+			policy: "script-src 'nonce-YWJjZGVmZ2hpamtsbW5vcA==' 'strict-dynamic' 'unsafe-inline' https:; " +
+				"object-src 'none'; base-uri 'self'; frame-ancestors 'self'",
+			baseline: "google-strict",
+			weaker:   []string{},
+			stronger: []string{"framing"},
+		},
+		"stronger than google": {
+			policy:   "default-src 'none'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'",
+			baseline: "Google-Strict",
+			weaker:   []string{},
+			stronger: []string{"inline-styles", "default-src", "form-action", "framing"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, _ := Parse("", "", []string{tc.policy})
+			comparison, err := CompareBaseline(policies[0], tc.baseline)
+			assert.NoError(err)
+			assert.Equal(tc.weaker, checkNames(comparison.Weaker))
+			assert.Equal(tc.stronger, checkNames(comparison.Stronger))
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// CachingFetcher is a Fetcher for polling the same URLs repeatedly (e.g., to
+	// detect policy changes). It honors `Cache-Control` from the target, and
+	// revalidates stale responses with conditional requests (`If-None-Match`,
+	// `If-Modified-Since`), so that unchanged responses cost a `304 Not Modified`
+	// instead of a full response.
+	CachingFetcher struct {
+		// Client is the client used for requests. If nil, http.DefaultClient is
+		// used.
+		Client *http.Client
+
+		// MaxAge caps how long a response is reused without revalidation, so that
+		// changes are detected promptly even when the target allows long caching.
+		// If zero, the target's `max-age` is used as-is.
+		MaxAge time.Duration
+
+		// Now returns the current time. If nil, time.Now is used.
+		Now func() time.Time
+
+		mu      sync.Mutex
+		entries map[string]*cacheEntry
+	}
+
+	// cacheEntry is a cached response.
+	cacheEntry struct {
+		status  int
+		header  http.Header
+		body    []byte
+		expires time.Time
+	}
+)
+
+/*
+Fetch implements Fetcher. A fresh cached response is returned without a
+request. A stale one is revalidated; if the target answers `304 Not Modified`,
+the cached response is returned with its freshness renewed.
+
+----
+
+  - ctx (context.Context): Controls cancellation and deadlines.
+
+  - rawURL (string): The absolute URL to retrieve.
+*/
+func (f *CachingFetcher) Fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	entry := f.entries[rawURL]
+	f.mu.Unlock()
+
+	if entry != nil {
+		if f.now().Before(entry.expires) {
+			return entry.response(req), nil
+		}
+
+		if etag := entry.header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if modified := entry.header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+
+		f.mu.Lock()
+		entry.expires = f.expires(resp.Header)
+		f.mu.Unlock()
+
+		return entry.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") {
+		f.mu.Lock()
+		delete(f.entries, rawURL)
+		f.mu.Unlock()
+
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchedBody))
+	if err != nil {
+		return nil, err
+	}
+
+	entry = &cacheEntry{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: f.expires(resp.Header),
+	}
+
+	f.mu.Lock()
+	if f.entries == nil {
+		f.entries = map[string]*cacheEntry{}
+	}
+
+	f.entries[rawURL] = entry
+	f.mu.Unlock()
+
+	return entry.response(resp.Request), nil
+}
+
+// now returns the current time.
+func (f *CachingFetcher) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+
+	return time.Now()
+}
+
+// expires returns the time until which a response with these headers may be
+// reused without revalidation.
+func (f *CachingFetcher) expires(header http.Header) time.Time {
+	var maxAge time.Duration
+
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+
+			switch strings.ToLower(name) {
+			case "no-cache", "no-store":
+				return time.Time{}
+			case "max-age":
+				if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+					maxAge = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+	}
+
+	if f.MaxAge > 0 && maxAge > f.MaxAge {
+		maxAge = f.MaxAge
+	}
+
+	return f.now().Add(maxAge)
+}
+
+// response returns a new response with the cached status, headers, and body.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// hasDirective reports whether the `Cache-Control` header contains the
+// directive.
+func hasDirective(header http.Header, directive string) bool {
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(d), directive) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
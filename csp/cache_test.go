@@ -0,0 +1,85 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingFetcher(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests, revalidations int
+
+	policy := "default-src 'self'"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := `"` + policy + `"`
+
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("Content-Security-Policy", policy)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fetcher := &CachingFetcher{
+		MaxAge: time.Minute,
+		Now:    func() time.Time { return now },
+	}
+
+	fetch := func() string {
+		policies, _ := FetchPolicies(context.Background(), fetcher, server.URL)
+		assert.Len(policies, 1)
+
+		return policies[0].Raw
+	}
+
+	assert.Equal("default-src 'self'", fetch())
+	assert.Equal(1, requests)
+
+	// Fresh: served from the cache without a request.
+	now = now.Add(30 * time.Second)
+	assert.Equal("default-src 'self'", fetch())
+	assert.Equal(1, requests)
+
+	// Stale after MaxAge (not the target's max-age): revalidated, not modified.
+	now = now.Add(time.Minute)
+	assert.Equal("default-src 'self'", fetch())
+	assert.Equal(2, requests)
+	assert.Equal(1, revalidations)
+
+	// Stale again, and the policy changed.
+	now = now.Add(2 * time.Minute)
+	policy = "default-src 'none'"
+	assert.Equal("default-src 'none'", fetch())
+	assert.Equal(3, requests)
+	assert.Equal(1, revalidations)
+}
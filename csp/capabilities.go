@@ -0,0 +1,83 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"regexp"
+	"slices"
+)
+
+type (
+	// CapabilityList describes what the running version of this package
+	// supports, so that integrating tools (web UIs, editors, CI plugins) can
+	// adapt without hard-coding lists of their own.
+	CapabilityList struct {
+		SpecLevels    []string    `json:"specLevels"`
+		Directives    []string    `json:"directives"`
+		Keywords      []string    `json:"keywords"`
+		SandboxTokens []string    `json:"sandboxTokens"`
+		ErrorCodes    []ErrorCode `json:"errorCodes"`
+	}
+
+	// ErrorCode describes a single diagnostic that this package can return.
+	ErrorCode struct {
+		Code     string `json:"code"`
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+
+		// Retired is true for a diagnostic that is no longer returned. Its code is
+		// never reused.
+		Retired bool `json:"retired,omitempty"`
+	}
+)
+
+// Capabilities returns the supported specification levels, directives,
+// keyword-sources (including those added with RegisterKeyword), sandbox tokens,
+// and the catalog of error codes.
+func Capabilities() CapabilityList {
+	return CapabilityList{
+		SpecLevels: []string{
+			"https://www.w3.org/TR/CSP2/",
+			"https://www.w3.org/TR/2024/WD-CSP3-20240613/",
+		},
+		Directives:    slices.Clone(knownDirectives),
+		Keywords:      allKeywords(),
+		SandboxTokens: slices.Clone(sandboxTokens),
+		ErrorCodes:    errorCodes(),
+	}
+}
+
+// errorCodes splits each entry in the error catalog (and the retired catalog)
+// into its severity, message template, and code.
+func errorCodes() []ErrorCode {
+	reMessage := regexp.MustCompile(`^\[([A-Z]+)\] (.*) \[(CSP-[0-9]+)\]$`)
+	codes := make([]ErrorCode, 0, len(errorCatalog)+len(retiredCatalog))
+
+	for i, msg := range append(slices.Clone(errorCatalog), retiredCatalog...) {
+		m := reMessage.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+
+		codes = append(codes, ErrorCode{
+			Code:     m[3],
+			Severity: m[1],
+			Message:  m[2],
+			Retired:  i >= len(errorCatalog),
+		})
+	}
+
+	return codes
+}
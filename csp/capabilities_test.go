@@ -0,0 +1,55 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	assert := assert.New(t)
+	capabilities := Capabilities()
+
+	// Every entry in the catalog must be well-formed.
+	assert.Len(capabilities.ErrorCodes, len(errorCatalog)+len(retiredCatalog))
+
+	seen := map[string]bool{}
+
+	for _, code := range capabilities.ErrorCodes {
+		assert.Falsef(seen[code.Code], "Duplicate error code `%s`.", code.Code)
+		assert.Contains([]string{"ERROR", "WARN", "INFO"}, code.Severity)
+
+		seen[code.Code] = true
+	}
+
+	// Retired codes are listed, but marked, so that they are never reused.
+	assert.Contains(capabilities.ErrorCodes, ErrorCode{
+		Code:     "CSP-0501",
+		Severity: "ERROR",
+		Message:  "directive `%s` may only have a single value",
+		Retired:  true,
+	})
+	assert.False(capabilities.ErrorCodes[0].Retired)
+
+	assert.Contains(capabilities.Directives, "script-src")
+	assert.Contains(capabilities.Keywords, "'strict-dynamic'")
+	assert.Contains(capabilities.SandboxTokens, "allow-scripts")
+
+	// Callers must not be able to modify the package's lists.
+	capabilities.Keywords[0] = "'modified'"
+	assert.True(isKeywordSource("'self'"))
+}
@@ -0,0 +1,321 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+/*
+checkPolicy runs the checks that need to look across directives, after a policy
+has been fully parsed.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkPolicy(cfg *config, policy *Policy) error {
+	var errs *multierror.Error
+
+	errs = multierror.Append(errs, checkInertSelf(cfg, policy))
+	errs = multierror.Append(errs, checkReportOnly(cfg, policy))
+	errs = multierror.Append(errs, checkReporting(cfg, policy))
+	errs = multierror.Append(errs, checkMetaDelivery(cfg, policy))
+	errs = multierror.Append(errs, checkWorkerContext(cfg, policy))
+	errs = multierror.Append(errs, checkPlacement(cfg, policy))
+	errs = multierror.Append(errs, checkReportSample(policy))
+	errs = multierror.Append(errs, checkDuplicateNonces(policy))
+	errs = multierror.Append(errs, checkSandboxEscape(cfg, policy))
+
+	return errs.ErrorOrNil()
+}
+
+/*
+checkInertSelf reports directives containing `'self'` when `'self'` cannot match
+anything: either the protected document has an opaque origin (e.g., a `file:`
+or `data:` URL), or the policy sandboxes the document without
+`allow-same-origin`, which also gives it an opaque origin.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkInertSelf(cfg *config, policy *Policy) error {
+	var errs *multierror.Error
+
+	opaqueURL := false
+
+	if cfg.currentURL != "" {
+		o, err := ParseOrigin(cfg.currentURL)
+		opaqueURL = err == nil && o == nil
+	}
+
+	// Report-only policies and workers ignore `sandbox`, so it never makes their
+	// origin opaque.
+	sandboxed := cfg.disposition != DispositionReport && !cfg.executionContext.isWorker() &&
+		len(policy.Sandbox) > 0 && !sandboxAllows(&policy.Sandbox[0], "allow-same-origin")
+
+	if !opaqueURL && !sandboxed {
+		return nil
+	}
+
+	for _, directive := range sourceListDirectives {
+		list, ok := policy.sourceList(directive)
+		if !ok || !containsKeyword(list, `'self'`) {
+			continue
+		}
+
+		if opaqueURL {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0005, directive, cfg.currentURL))
+		} else {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0006, directive))
+		}
+	}
+
+	// `frame-ancestors` compares against the origin of the response URL, which
+	// `sandbox` does not change.
+	if opaqueURL && len(policy.FrameAncestors) > 0 && slices.ContainsFunc(policy.FrameAncestors[0].AncestorExprs,
+		func(e AncestorExpr) bool { return e.KeywordSource != "" }) {
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0005, "frame-ancestors", cfg.currentURL))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+/*
+checkSandboxEscape reports a `sandbox` directive that allows both `allow-scripts`
+and `allow-same-origin`. Scripts running with the document's real origin can
+reach anything the sandbox was meant to protect, including removing the sandbox
+itself (e.g., through a same-origin parent or a new window), so the sandbox no
+longer contains them.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkSandboxEscape(cfg *config, policy *Policy) error {
+	// Report-only policies ignore `sandbox`, which CSP-0701 already reports, and
+	// workers ignore it, which CSP-0029 already reports.
+	if cfg.disposition == DispositionReport || cfg.executionContext.isWorker() || len(policy.Sandbox) == 0 {
+		return nil
+	}
+
+	if sandboxAllows(&policy.Sandbox[0], "allow-scripts") && sandboxAllows(&policy.Sandbox[0], "allow-same-origin") {
+		return fmt.Errorf(errCSP0704, "sandbox")
+	}
+
+	return nil
+}
+
+/*
+checkReporting reports policies whose violations cannot be reported: policies
+without a `report-to` or `report-uri` directive (report-only policies get
+CSP-0007 instead), and policies whose reporting destinations all failed
+validation.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkReporting(cfg *config, policy *Policy) error {
+	switch {
+	case len(policy.ReportTo) == 0 && len(policy.ReportURI) == 0:
+		if cfg.disposition == DispositionReport {
+			return nil
+		}
+
+		return errors.New(withSeverity(errCSP0021, cfg.missingReportingSeverity))
+	case hasReportingDestination(cfg, policy):
+		return nil
+	}
+
+	return errors.New(withSeverity(errCSP0022, cfg.missingReportingSeverity))
+}
+
+// hasReportingDestination reports whether the policy has a `report-uri` URL or
+// a `report-to` endpoint that passed validation. When `report-to` could not be
+// validated because the Reporting-Endpoints header is empty, it is assumed to be
+// valid.
+func hasReportingDestination(cfg *config, policy *Policy) bool {
+	for i := range policy.ReportURI {
+		if len(policy.ReportURI[i].URLs) > 0 {
+			return true
+		}
+	}
+
+	for i := range policy.ReportTo {
+		if policy.ReportTo[i].URL != "" || (policy.ReportTo[i].Endpoint != "" && cfg.withoutReporting) ||
+			(cfg.reportingEndpoints == "" && !cfg.withoutReporting) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+checkPlacement makes advisory recommendations about the order and placement of
+directives, which make a policy easier to read and review: `default-src` first,
+and reporting directives last.
+
+----
+
+  - cfg (*config): The options for this call to Parse. Provides the order of
+    the directives.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkPlacement(cfg *config, policy *Policy) error {
+	var errs *multierror.Error
+
+	order := cfg.directiveOrder
+
+	if i := slices.Index(order, "default-src"); i > 0 {
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0904))
+	}
+
+	// Reporting directives should be at the end, in any order.
+	last := len(order)
+	for last > 0 && isReportingDirective(order[last-1]) {
+		last--
+	}
+
+	for i := 0; i < last; i++ {
+		if isReportingDirective(order[i]) {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0905, order[i]))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+/*
+checkReportSample reports source lists with `'report-sample'` in a policy that has
+neither `report-to` nor `report-uri`, since the samples are never delivered
+anywhere. This needs every directive of the policy, so it runs after parsing.
+
+----
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkReportSample(policy *Policy) error {
+	var errs *multierror.Error
+
+	if len(policy.ReportTo) > 0 || len(policy.ReportURI) > 0 {
+		return nil
+	}
+
+	for _, directive := range sourceListDirectives {
+		list, ok := policy.sourceList(directive)
+		if ok && containsKeyword(list, `'report-sample'`) {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0906, directive))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+/*
+checkDuplicateNonces reports nonce-sources whose value appears in more than one
+directive. A nonce that authorizes several kinds of content (e.g., both scripts
+and styles) widens what an attacker who learns it can inject.
+
+----
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkDuplicateNonces(policy *Policy) error {
+	var errs *multierror.Error
+
+	first := map[string]string{}
+
+	for _, use := range policyNonces(policy) {
+		directive, ok := first[use.nonce]
+		if !ok {
+			first[use.nonce] = use.directive
+
+			continue
+		}
+
+		if directive != use.directive {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0908, use.nonce, directive, use.directive))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// nonceUse is the value of a nonce-source, and the directive it appears in.
+type nonceUse struct {
+	directive string
+	nonce     string
+}
+
+// policyNonces returns the value of every nonce-source in the policy, in the
+// order of sourceListDirectives.
+func policyNonces(policy *Policy) []nonceUse {
+	uses := []nonceUse{}
+
+	for _, directive := range sourceListDirectives {
+		list, ok := policy.sourceList(directive)
+		if !ok {
+			continue
+		}
+
+		for i := range list.SourceExprs {
+			if list.SourceExprs[i].NonceSource != "" {
+				uses = append(uses, nonceUse{directive, nonceValue(list.SourceExprs[i].NonceSource)})
+			}
+		}
+	}
+
+	return uses
+}
+
+// nonceValue returns the base64 value of a nonce-source (e.g., `abc123` for
+// `'nonce-abc123'`).
+func nonceValue(nonceSource string) string {
+	return strings.TrimSuffix(nonceSource[len(`'nonce-`):], `'`)
+}
+
+// isReportingDirective reports whether the directive tells the browser where to
+// send violation reports.
+func isReportingDirective(name string) bool {
+	return name == "report-to" || name == "report-uri"
+}
+
+// sandboxAllows reports whether the sandbox directive includes the token.
+func sandboxAllows(sandbox *SandboxToken, token string) bool {
+	for i := range sandbox.Allow {
+		if strings.EqualFold(sandbox.Allow[i], token) {
+			return true
+		}
+	}
+
+	return false
+}
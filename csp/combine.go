@@ -0,0 +1,374 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Combination describes how several policies for the same response (e.g.,
+	// repeated `Content-Security-Policy` headers) restrict loads together. A
+	// resource is only loaded when every enforced policy allows it, so the
+	// effective restriction is the intersection of the enforced policies.
+	// Report-only policies never block anything, and policies in trailers are
+	// ignored.
+	Combination struct {
+		// Enforced and ReportOnly are the indexes of the policies with each
+		// disposition, in the slice passed to Combine.
+		Enforced   []int `json:"enforced"`
+		ReportOnly []int `json:"reportOnly,omitempty"`
+
+		// Directives lists, for each fetch directive that at least one enforced
+		// policy restricts, the source list that each of those policies applies.
+		Directives []CombinedDirective `json:"directives"`
+
+		policies []*Policy
+	}
+
+	// CombinedDirective is a directive, and every enforced restriction on it. A
+	// load must match a source expression in each restriction.
+	CombinedDirective struct {
+		Name         string        `json:"name"`
+		Restrictions []Restriction `json:"restrictions"`
+
+		// Effective is the combined behavior: the source expressions that every
+		// restriction allows. It is computed at the level of source expressions,
+		// so expressions that only partially overlap are left out. `'none'`
+		// means that nothing is loaded.
+		Effective []string `json:"effective"`
+
+		// Shadowed lists the source expressions that one enforced policy allows,
+		// but another one does not, so they allow less than they appear to.
+		Shadowed []ShadowedSource `json:"shadowed,omitempty"`
+	}
+
+	// ShadowedSource is a source expression in one enforced policy that other
+	// enforced policies narrow (e.g., `https:` next to `https://cdn.example.com`)
+	// or make irrelevant (e.g., anything next to `'none'`).
+	ShadowedSource struct {
+		PolicyIndex int    `json:"policyIndex"`
+		Source      string `json:"source"`
+
+		// BlockedBy lists the enforced policies that do not allow everything
+		// that the source expression allows.
+		BlockedBy []int `json:"blockedBy"`
+
+		// Partial is true when each of the policies in BlockedBy still allows
+		// some of what the source expression allows. Otherwise, the source
+		// expression has no effect.
+		Partial bool `json:"partial,omitempty"`
+	}
+
+	// Restriction is the source list that one policy applies to a directive,
+	// after fallback.
+	Restriction struct {
+		PolicyIndex        int      `json:"policyIndex"`
+		EffectiveDirective string   `json:"effectiveDirective"`
+		Sources            []string `json:"sources"`
+	}
+
+	// CombinedExplanation is the combined decision of every policy about a single
+	// load.
+	CombinedExplanation struct {
+		// Allowed is true when every enforced policy allows the load.
+		Allowed bool `json:"allowed"`
+
+		// BlockedBy lists the enforced policies that block the load.
+		BlockedBy []int `json:"blockedBy,omitempty"`
+
+		// ReportedBy lists the report-only policies that would report the load.
+		ReportedBy []int `json:"reportedBy,omitempty"`
+
+		// Explanations has the decision of each policy, in order.
+		Explanations []Explanation `json:"explanations"`
+	}
+)
+
+/*
+Combine describes how the policies restrict loads together, instead of as
+parallel, independent policies.
+
+----
+
+  - policies ([]*Policy): The parsed policies for a single response (e.g., as
+    returned by ParseHeader).
+*/
+func Combine(policies []*Policy) *Combination {
+	c := &Combination{
+		Enforced:   []int{},
+		Directives: []CombinedDirective{},
+		policies:   policies,
+	}
+
+	for i := range policies {
+		switch {
+		case policies[i].Delivery == DeliveryTrailer:
+			// Browsers ignore policies in trailers.
+		case policies[i].Disposition == DispositionReport:
+			c.ReportOnly = append(c.ReportOnly, i)
+		default:
+			c.Enforced = append(c.Enforced, i)
+		}
+	}
+
+	for _, name := range sourceListDirectives {
+		directive := CombinedDirective{Name: name}
+		lists := []*SourceListItem{}
+
+		for _, i := range c.Enforced {
+			effective, list, ok := policies[i].effectiveSourceList(name)
+			if !ok {
+				continue
+			}
+
+			lists = append(lists, list)
+			directive.Restrictions = append(directive.Restrictions, Restriction{
+				PolicyIndex:        i,
+				EffectiveDirective: effective,
+				Sources:            sourceStrings(list),
+			})
+		}
+
+		if len(directive.Restrictions) > 0 {
+			directive.Effective, directive.Shadowed = combineRestrictions(directive.Restrictions, lists)
+			c.Directives = append(c.Directives, directive)
+		}
+	}
+
+	return c
+}
+
+/*
+Explain determines whether the combined policies allow the resource to be
+loaded, which enforced policies block it, and which report-only policies would
+report it.
+
+----
+
+  - currentURL (string): The URL of the protected document. May be an empty
+    string, but then `'self'` will never match.
+
+  - directive (string): The directive that governs the load (e.g.,
+    `script-src-elem` for a `<script src>` element).
+
+  - target (string): The absolute URL of the resource being loaded.
+*/
+func (c *Combination) Explain(currentURL, directive, target string) (*CombinedExplanation, error) {
+	allowed, explanations, err := Explain(c.policies, currentURL, directive, target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CombinedExplanation{
+		Allowed:      allowed,
+		Explanations: explanations,
+	}
+
+	for i := range explanations {
+		if explanations[i].Allowed {
+			continue
+		}
+
+		switch {
+		case c.policies[i].Delivery == DeliveryTrailer:
+			continue
+		case c.policies[i].Disposition == DispositionReport:
+			result.ReportedBy = append(result.ReportedBy, i)
+		default:
+			result.BlockedBy = append(result.BlockedBy, i)
+		}
+	}
+
+	return result, nil
+}
+
+/*
+combineRestrictions computes the combined behavior of the restrictions on a
+single directive, and the source expressions that the other restrictions
+shadow.
+
+----
+
+  - restrictions ([]Restriction): The restrictions on the directive.
+
+  - lists ([]*SourceListItem): The source list of each restriction, in the same
+    order.
+*/
+func combineRestrictions(restrictions []Restriction, lists []*SourceListItem) ([]string, []ShadowedSource) {
+	var (
+		effective []SourceExpr
+		shadowed  []ShadowedSource
+	)
+
+	for i, list := range lists {
+		for _, expr := range list.SourceExprs {
+			// `'report-sample'` only changes the reports of its own policy.
+			if strings.EqualFold(expr.KeywordSource, `'report-sample'`) {
+				continue
+			}
+
+			source := ShadowedSource{
+				PolicyIndex: restrictions[i].PolicyIndex,
+				Source:      expr.String(),
+				BlockedBy:   []int{},
+				Partial:     true,
+			}
+
+			for j, other := range lists {
+				if i == j || coveredBy(expr, other) {
+					continue
+				}
+
+				source.BlockedBy = append(source.BlockedBy, restrictions[j].PolicyIndex)
+				source.Partial = source.Partial && overlaps(expr, other)
+			}
+
+			if len(source.BlockedBy) == 0 {
+				effective = append(effective, expr)
+
+				continue
+			}
+
+			shadowed = append(shadowed, source)
+		}
+	}
+
+	// Remove the expressions that more than one policy contributed.
+	combined := SourceListItem{SourceExprs: effective}.Union(SourceListItem{})
+
+	return sourceStrings(&combined), shadowed
+}
+
+/*
+coveredBy reports whether the source list allows everything that the source
+expression allows. This is like subsumedByAny, except that `'unsafe-inline'`
+also allows what a nonce-source or hash-source allows, unless the list has a
+nonce-source or hash-source of its own, which makes browsers ignore
+`'unsafe-inline'`.
+
+----
+
+  - expr (SourceExpr): The source expression that may be covered.
+
+  - list (*SourceListItem): The source list that may cover it.
+*/
+func coveredBy(expr SourceExpr, list *SourceListItem) bool {
+	if subsumedByAny(expr, list.SourceExprs) {
+		return true
+	}
+
+	if expr.NonceSource == "" && expr.HashSource == "" {
+		return false
+	}
+
+	for _, other := range list.SourceExprs {
+		if other.NonceSource != "" || other.HashSource != "" {
+			return false
+		}
+	}
+
+	return containsKeyword(list, `'unsafe-inline'`)
+}
+
+// overlaps reports whether the source list allows some of what the source
+// expression allows, because the expression subsumes one of its expressions.
+func overlaps(expr SourceExpr, list *SourceListItem) bool {
+	for _, other := range list.SourceExprs {
+		if expr.Subsumes(other) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sourceStrings returns the source expressions of the list as they would
+// appear in a policy. An empty list is returned as `'none'`.
+func sourceStrings(list *SourceListItem) []string {
+	sources := make([]string, 0, len(list.SourceExprs)+1)
+	if list.None || len(list.SourceExprs) == 0 {
+		sources = append(sources, `'none'`)
+	}
+
+	for i := range list.SourceExprs {
+		sources = append(sources, list.SourceExprs[i].String())
+	}
+
+	return sources
+}
+
+/*
+checkShadowedSources reports the source expressions in enforced policies that
+other enforced policies of the same response narrow or make irrelevant. Each
+source expression is reported once, for the first directive it is shadowed in,
+rather than for every directive that falls back to it.
+
+----
+
+  - policies ([]*Policy): Every policy of the response.
+*/
+func checkShadowedSources(policies []*Policy) []error {
+	errs := []error{}
+	reported := map[string]bool{}
+
+	for _, directive := range Combine(policies).Directives {
+		for _, shadowed := range directive.Shadowed {
+			effective := directive.Name
+
+			for _, restriction := range directive.Restrictions {
+				if restriction.PolicyIndex == shadowed.PolicyIndex {
+					effective = restriction.EffectiveDirective
+				}
+			}
+
+			key := strconv.Itoa(shadowed.PolicyIndex) + " " + effective + " " + shadowed.Source
+			if reported[key] {
+				continue
+			}
+
+			reported[key] = true
+
+			msg := errCSP0025
+			if shadowed.Partial {
+				msg = errCSP0026
+			}
+
+			errs = append(errs, fmt.Errorf(
+				msg, effective, shadowed.Source, shadowed.PolicyIndex, directive.Name, policyList(shadowed.BlockedBy),
+			))
+		}
+	}
+
+	return errs
+}
+
+// policyList describes the policies with the given indexes (e.g., `policy 1`,
+// or `policies 1, 2`).
+func policyList(indexes []int) string {
+	if len(indexes) == 1 {
+		return "policy " + strconv.Itoa(indexes[0])
+	}
+
+	list := make([]string, len(indexes))
+
+	for i, index := range indexes {
+		list[i] = strconv.Itoa(index)
+	}
+
+	return "policies " + strings.Join(list, ", ")
+}
@@ -0,0 +1,160 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombine(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Add("Content-Security-Policy", "default-src 'self' https://cdn.example.com; img-src *")
+	header.Add("Content-Security-Policy", "script-src https://cdn.example.com")
+	header.Add("Content-Security-Policy-Report-Only", "script-src 'self'")
+
+	policies, _ := ParseHeader("https://example.com", header)
+	combination := Combine(policies)
+
+	assert.Equal([]int{0, 1}, combination.Enforced)
+	assert.Equal([]int{2}, combination.ReportOnly)
+
+	for _, d := range combination.Directives {
+		if d.Name != "script-src-elem" {
+			continue
+		}
+
+		assert.Equal([]Restriction{
+			{PolicyIndex: 0, EffectiveDirective: "default-src", Sources: []string{"'self'", "https://cdn.example.com"}},
+			{PolicyIndex: 1, EffectiveDirective: "script-src", Sources: []string{"https://cdn.example.com"}},
+		}, d.Restrictions)
+	}
+
+	// Allowed by both enforced policies; the report-only policy only reports it.
+	explanation, err := combination.Explain("https://example.com", "script-src-elem", "https://cdn.example.com/a.js")
+	assert.NoError(err)
+	assert.True(explanation.Allowed)
+	assert.Empty(explanation.BlockedBy)
+	assert.Equal([]int{2}, explanation.ReportedBy)
+	assert.Len(explanation.Explanations, 3)
+
+	// Allowed by the first policy, but blocked by the second.
+	explanation, err = combination.Explain("https://example.com", "script-src-elem", "https://example.com/a.js")
+	assert.NoError(err)
+	assert.False(explanation.Allowed)
+	assert.Equal([]int{1}, explanation.BlockedBy)
+	assert.Empty(explanation.ReportedBy)
+
+	_, err = combination.Explain("", "img-src", "not a url")
+	assert.Error(err)
+}
+
+func TestCombineShadowed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Policies    []string
+		Directive   string
+		Effective   []string
+		Shadowed    []ShadowedSource
+		ErrorSubstr []string
+	}{
+		"none makes the other policy irrelevant": {
+			Policies:  []string{"default-src 'self' https://cdn.example.com", "script-src 'none'"},
+			Directive: "script-src-elem",
+			Effective: []string{"'none'"},
+			Shadowed: []ShadowedSource{
+				{PolicyIndex: 0, Source: "'self'", BlockedBy: []int{1}},
+				{PolicyIndex: 0, Source: "https://cdn.example.com", BlockedBy: []int{1}},
+			},
+			ErrorSubstr: []string{
+				"[WARN] directive `default-src`: `'self'` in policy 0 has no effect on `script-src`, because it " +
+					"is not allowed by enforced policy 1",
+			},
+		},
+		"narrow list narrows a broad one": {
+			Policies:  []string{"script-src https: 'unsafe-eval'", "script-src https://cdn.example.com"},
+			Directive: "script-src",
+			Effective: []string{"https://cdn.example.com"},
+			Shadowed: []ShadowedSource{
+				{PolicyIndex: 0, Source: "https:", BlockedBy: []int{1}, Partial: true},
+				{PolicyIndex: 0, Source: "'unsafe-eval'", BlockedBy: []int{1}},
+			},
+			ErrorSubstr: []string{
+				"[INFO] directive `script-src`: `https:` in policy 0 is narrowed for `script-src` by enforced " +
+					"policy 1, which only allows part of it [CSP-0026]",
+				"`'unsafe-eval'` in policy 0 has no effect on `script-src`",
+			},
+		},
+		"unsafe-inline allows a nonce in another policy": {
+			Policies: []string{
+				"script-src 'nonce-YWJjZGVmZ2hpamtsbW5vcA==' 'strict-dynamic' 'report-sample'",
+				"script-src 'self' 'unsafe-inline'",
+			},
+			Directive: "script-src",
+			Effective: []string{"'nonce-YWJjZGVmZ2hpamtsbW5vcA=='"},
+			Shadowed: []ShadowedSource{
+				{PolicyIndex: 0, Source: "'strict-dynamic'", BlockedBy: []int{1}},
+				{PolicyIndex: 1, Source: "'self'", BlockedBy: []int{0}},
+				{PolicyIndex: 1, Source: "'unsafe-inline'", BlockedBy: []int{0}},
+			},
+			ErrorSubstr: []string{"`'unsafe-inline'` in policy 1 has no effect on `script-src`"},
+		},
+		"same list in both policies": {
+			Policies:  []string{"img-src 'self' https://img.example.com", "img-src https://img.example.com 'self'"},
+			Directive: "img-src",
+			Effective: []string{"'self'", "https://img.example.com"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			header := http.Header{}
+			for _, policy := range tc.Policies {
+				header.Add("Content-Security-Policy", policy)
+			}
+
+			// A report-only policy never shadows anything.
+			header.Add("Content-Security-Policy-Report-Only", "default-src 'none'")
+
+			policies, err := ParseHeader("https://example.com", header)
+
+			for _, substr := range tc.ErrorSubstr {
+				assert.ErrorContains(err, substr)
+			}
+
+			if len(tc.ErrorSubstr) == 0 {
+				assert.NotContains(fmt.Sprint(err), "[CSP-0025]")
+				assert.NotContains(fmt.Sprint(err), "[CSP-0026]")
+			}
+
+			found := false
+
+			for _, d := range Combine(policies).Directives {
+				if d.Name == tc.Directive {
+					found = true
+
+					assert.Equal(tc.Effective, d.Effective)
+					assert.Equal(tc.Shadowed, d.Shadowed)
+				}
+			}
+
+			assert.True(found, tc.Directive)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Delivery describes how a policy reached the browser.
+//
+// https://www.w3.org/TR/2024/WD-CSP3-20240613/#policy-delivery
+type Delivery string
+
+const (
+	// DeliveryHeader is used for policies delivered by an HTTP response header.
+	DeliveryHeader Delivery = "header"
+
+	// DeliveryMeta is used for policies delivered by a
+	// `<meta http-equiv="Content-Security-Policy">` element.
+	DeliveryMeta Delivery = "meta"
+
+	// DeliveryTrailer is used for policies found in the trailer of an HTTP
+	// response. Browsers ignore them, so they never restrict anything.
+	DeliveryTrailer Delivery = "trailer"
+)
+
+// metaIgnoredDirectives are the directives that browsers ignore when a policy
+// is delivered by a `<meta>` element.
+//
+// https://www.w3.org/TR/2024/WD-CSP3-20240613/#meta-element
+var metaIgnoredDirectives = []string{
+	"frame-ancestors",
+	"report-to",
+	"report-uri",
+	"sandbox",
+}
+
+// WithDelivery sets how the policies passed to Parse were delivered. The
+// default is DeliveryHeader. ParseHTML uses DeliveryMeta.
+func WithDelivery(d Delivery) Option {
+	return func(c *config) {
+		c.delivery = d
+	}
+}
+
+/*
+checkMetaDelivery reports directives that are ignored because the policy was
+delivered by a `<meta>` element.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkMetaDelivery(cfg *config, policy *Policy) error {
+	var errs *multierror.Error
+
+	if cfg.delivery != DeliveryMeta {
+		return nil
+	}
+
+	for _, directive := range metaIgnoredDirectives {
+		present := false
+
+		switch directive {
+		case "frame-ancestors":
+			present = len(policy.FrameAncestors) > 0
+		case "report-to":
+			present = len(policy.ReportTo) > 0
+		case "report-uri":
+			present = len(policy.ReportURI) > 0
+		case "sandbox":
+			present = len(policy.Sandbox) > 0
+		}
+
+		if present {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0012, directive))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
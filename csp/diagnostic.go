@@ -0,0 +1,196 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Phase describes when a diagnostic was found.
+type Phase string
+
+const (
+	// PhaseParse is used for diagnostics about a single directive, found while
+	// the directive was parsed.
+	PhaseParse Phase = "parse"
+
+	// PhasePolicy is used for diagnostics that look across the directives of a
+	// policy, found after the whole policy was parsed.
+	PhasePolicy Phase = "policy"
+)
+
+// PolicyError is a diagnostic that belongs to one of the policies passed to
+// Parse. Its message is the message of the wrapped diagnostic, so SeverityOf
+// works on it unchanged. Diagnostics about the call as a whole (e.g., CSP-0001)
+// are not wrapped.
+type PolicyError struct {
+	// PolicyIndex is the index of the policy in the slice passed to Parse.
+	PolicyIndex int
+
+	// Disposition is the disposition of the policy.
+	Disposition Disposition
+
+	// Phase is when the diagnostic was found.
+	Phase Phase
+
+	// Span is the location of the directive that the diagnostic is about, or of
+	// a single value of it when the diagnostic is about one. It is nil for
+	// diagnostics about the policy as a whole.
+	Span *Span
+
+	// Pointer is a JSON Pointer (RFC 6901) into the JSON encoding of the
+	// policies returned by Parse, to the entry in Policy.Directives that the
+	// diagnostic is about (e.g., `/0/directives/2`), or to a single value of it
+	// when the diagnostic names one (e.g., `/0/directives/2/values/1`). It is
+	// empty for diagnostics about the policy as a whole.
+	Pointer string
+
+	// Err is the diagnostic.
+	Err error
+}
+
+// Error implements error.
+func (e *PolicyError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped diagnostic.
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+/*
+PolicyIndexOf returns the index of the policy that a diagnostic belongs to, or
+-1 if it belongs to the call as a whole.
+
+----
+
+  - err (error): A single diagnostic (not a multierror).
+*/
+func PolicyIndexOf(err error) int {
+	var pe *PolicyError
+
+	if errors.As(err, &pe) {
+		return pe.PolicyIndex
+	}
+
+	return -1
+}
+
+/*
+annotate wraps the diagnostics that were appended to errs since `from` in a
+PolicyError for the current policy and, while a directive is being parsed, its
+location.
+
+----
+
+  - errs (*multierror.Error): The diagnostics collected so far.
+
+  - from (int): The number of diagnostics that were collected before the ones
+    to wrap.
+
+  - phase (Phase): When the diagnostics were found.
+*/
+func (c *config) annotate(errs *multierror.Error, from int, phase Phase) {
+	list := errorsOf(errs)
+
+	for i := from; i < len(list); i++ {
+		err, value, span := list[i], -1, c.span
+
+		if ve, ok := err.(*valueError); ok {
+			err, value = ve.err, ve.index
+
+			if value < len(c.valueSpans) {
+				span = &c.valueSpans[value]
+			}
+		}
+
+		list[i] = &PolicyError{
+			PolicyIndex: c.policyIndex,
+			Disposition: c.disposition,
+			Phase:       phase,
+			Span:        span,
+			Pointer:     c.pointer(value),
+			Err:         err,
+		}
+	}
+}
+
+// pointer returns the JSON Pointer for a diagnostic about the directive being
+// parsed, or about one of its values when value is not -1. It returns an empty
+// string between directives.
+func (c *config) pointer(value int) string {
+	if c.directiveIndex < 0 {
+		return ""
+	}
+
+	pointer := fmt.Sprintf("/%d/directives/%d", c.policyIndex, c.directiveIndex)
+
+	if value >= 0 {
+		pointer += fmt.Sprintf("/values/%d", value)
+	}
+
+	return pointer
+}
+
+// valueError is a diagnostic about a single value of the directive being
+// parsed. It only lives until annotate replaces it with a PolicyError.
+type valueError struct {
+	index int
+	err   error
+}
+
+// Error implements error.
+func (e *valueError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped diagnostic.
+func (e *valueError) Unwrap() error {
+	return e.err
+}
+
+/*
+atValue marks a diagnostic (or each diagnostic in a multierror) as being about
+the value at the index, so that annotate can point to it.
+
+----
+
+  - index (int): The index of the value in the values of the directive.
+
+  - err (error): The diagnostic. May be nil.
+*/
+func atValue(index int, err error) error {
+	if merr, ok := err.(*multierror.Error); ok {
+		if merr == nil {
+			return nil
+		}
+
+		for i := range merr.Errors {
+			merr.Errors[i] = atValue(index, merr.Errors[i])
+		}
+
+		return merr
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	return &valueError{index: index, err: err}
+}
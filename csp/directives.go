@@ -0,0 +1,246 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"slices"
+	"strings"
+)
+
+// knownDirectives is the list of directive names that the parser recognizes,
+// including deprecated and obsolete directives that produce errors.
+var knownDirectives = []string{
+	"base-uri",
+	"block-all-mixed-content",
+	"child-src",
+	"connect-src",
+	"default-src",
+	"fenced-frame-src",
+	"font-src",
+	"form-action",
+	"frame-ancestors",
+	"frame-src",
+	"img-src",
+	"manifest-src",
+	"media-src",
+	"navigate-to",
+	"object-src",
+	"plugin-types",
+	"prefetch-src",
+	"referrer",
+	"report-to",
+	"report-uri",
+	"require-sri-for",
+	"require-trusted-types-for",
+	"sandbox",
+	"script-src",
+	"script-src-attr",
+	"script-src-elem",
+	"style-src",
+	"style-src-attr",
+	"style-src-elem",
+	"upgrade-insecure-requests",
+	"webrtc",
+	"worker-src",
+}
+
+// keywordSources is the list of keyword-sources that the parser recognizes.
+//
+// https://www.w3.org/TR/2024/WD-CSP3-20240613/#grammardef-keyword-source
+var keywordSources = []string{
+	`'self'`,
+	`'report-sample'`,
+	`'strict-dynamic'`,
+	`'unsafe-eval'`,
+	`'unsafe-hashes'`,
+	`'unsafe-inline'`,
+	`'unsafe-allow-redirects'`,
+	`'wasm-unsafe-eval'`,
+	`'inline-speculation-rules'`,
+}
+
+/*
+directiveFallbackList implements the "directive fallback list" from CSP Level 3,
+§ 6.8.3. The first entry is always the directive itself. Directives that are
+not listed here do not fall back to anything.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#directive-fallback-list
+*/
+var directiveFallbackList = map[string][]string{
+	"script-src-elem":  {"script-src-elem", "script-src", "default-src"},
+	"script-src-attr":  {"script-src-attr", "script-src", "default-src"},
+	"style-src-elem":   {"style-src-elem", "style-src", "default-src"},
+	"style-src-attr":   {"style-src-attr", "style-src", "default-src"},
+	"worker-src":       {"worker-src", "child-src", "script-src", "default-src"},
+	"connect-src":      {"connect-src", "default-src"},
+	"manifest-src":     {"manifest-src", "default-src"},
+	"object-src":       {"object-src", "default-src"},
+	"frame-src":        {"frame-src", "child-src", "default-src"},
+	"fenced-frame-src": {"fenced-frame-src", "frame-src", "child-src", "default-src"},
+	"media-src":        {"media-src", "default-src"},
+	"font-src":         {"font-src", "default-src"},
+	"img-src":          {"img-src", "default-src"},
+	"child-src":        {"child-src", "default-src"},
+	"script-src":       {"script-src", "default-src"},
+	"style-src":        {"style-src", "default-src"},
+}
+
+// sourceListDirectives is the list of directives whose values are parsed as a
+// source list, in the order in which they are reported.
+var sourceListDirectives = []string{
+	"default-src",
+	"base-uri",
+	"child-src",
+	"connect-src",
+	"fenced-frame-src",
+	"font-src",
+	"form-action",
+	"frame-src",
+	"img-src",
+	"manifest-src",
+	"media-src",
+	"object-src",
+	"script-src",
+	"script-src-attr",
+	"script-src-elem",
+	"style-src",
+	"style-src-attr",
+	"style-src-elem",
+	"worker-src",
+}
+
+/*
+sourceList returns the source list for the named directive, exactly as it
+appears in the policy (no fallback is applied). When a directive appears more
+than once, only the first occurrence is returned.
+
+----
+
+  - name (string): The name of the directive (e.g., `script-src`).
+*/
+func (p *Policy) sourceList(name string) (*SourceListItem, bool) {
+	var items []SourceListItem
+
+	switch strings.ToLower(name) {
+	case "base-uri":
+		items = p.BaseURI
+	case "child-src":
+		items = p.ChildSource
+	case "connect-src":
+		items = p.ConnectSource
+	case "default-src":
+		items = p.DefaultSource
+	case "fenced-frame-src":
+		items = p.FencedFrameSource
+	case "font-src":
+		items = p.FontSource
+	case "form-action":
+		items = p.FormAction
+	case "frame-src":
+		items = p.FrameSource
+	case "img-src":
+		items = p.ImageSource
+	case "manifest-src":
+		items = p.ManifestSource
+	case "media-src":
+		items = p.MediaSource
+	case "navigate-to":
+		items = p.NavigateTo
+	case "object-src":
+		items = p.ObjectSource
+	case "prefetch-src":
+		items = p.PrefetchSource
+	case "script-src":
+		items = p.ScriptSource
+	case "script-src-attr":
+		items = p.ScriptSourceAttr
+	case "script-src-elem":
+		items = p.ScriptSourceElem
+	case "style-src":
+		items = p.StyleSource
+	case "style-src-attr":
+		items = p.StyleSourceAttr
+	case "style-src-elem":
+		items = p.StyleSourceElem
+	case "worker-src":
+		items = p.WorkerSource
+	}
+
+	if len(items) == 0 {
+		return nil, false
+	}
+
+	return &items[0], true
+}
+
+/*
+effectiveSourceList walks the directive fallback list for the named directive
+and returns the name and source list of the first directive that is present in
+the policy. If no directive in the fallback list is present, ok is false and
+loads governed by this directive are not restricted by the policy.
+
+----
+
+  - name (string): The name of the directive (e.g., `script-src-elem`).
+*/
+func (p *Policy) effectiveSourceList(name string) (effective string, list *SourceListItem, ok bool) {
+	name = strings.ToLower(name)
+
+	fallbacks, found := directiveFallbackList[name]
+	if !found {
+		fallbacks = []string{name}
+	}
+
+	for i := range fallbacks {
+		if list, ok := p.sourceList(fallbacks[i]); ok {
+			return fallbacks[i], list, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// SourceListDirectives returns the names of the directives whose values are
+// parsed as a source list, in the order in which they are reported.
+func SourceListDirectives() []string {
+	return slices.Clone(sourceListDirectives)
+}
+
+/*
+SourceList returns the source list for the named directive, exactly as it
+appears in the policy (no fallback is applied). When a directive appears more
+than once, only the first occurrence is returned.
+
+----
+
+  - name (string): The name of the directive (e.g., `script-src`).
+*/
+func (p *Policy) SourceList(name string) (*SourceListItem, bool) {
+	return p.sourceList(name)
+}
+
+/*
+EffectiveSourceList walks the directive fallback list for the named directive
+and returns the name and source list of the first directive that is present in
+the policy. If no directive in the fallback list is present, ok is false and
+loads governed by this directive are not restricted by the policy.
+
+----
+
+  - name (string): The name of the directive (e.g., `script-src-elem`).
+*/
+func (p *Policy) EffectiveSourceList(name string) (effective string, list *SourceListItem, ok bool) {
+	return p.effectiveSourceList(name)
+}
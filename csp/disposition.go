@@ -0,0 +1,76 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Disposition describes whether a policy is enforced, or only reports
+// violations.
+//
+// https://www.w3.org/TR/2024/WD-CSP3-20240613/#policy-disposition
+type Disposition string
+
+const (
+	// DispositionEnforce is used for policies delivered with the
+	// `Content-Security-Policy` header, or a `<meta>` element.
+	DispositionEnforce Disposition = "enforce"
+
+	// DispositionReport is used for policies delivered with the
+	// `Content-Security-Policy-Report-Only` header.
+	DispositionReport Disposition = "report"
+)
+
+// WithDisposition sets the disposition of every policy passed to Parse. Use
+// DispositionReport for values of the `Content-Security-Policy-Report-Only`
+// header. The default is DispositionEnforce.
+func WithDisposition(d Disposition) Option {
+	return func(c *config) {
+		c.disposition = d
+	}
+}
+
+/*
+checkReportOnly reports problems that only apply to report-only policies:
+directives that are ignored in a report-only policy, and report-only policies
+that have nowhere to send their reports.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkReportOnly(cfg *config, policy *Policy) error {
+	var errs *multierror.Error
+
+	if cfg.disposition != DispositionReport {
+		return nil
+	}
+
+	// https://www.w3.org/TR/2024/WD-CSP3-20240613/#cspro-header
+	if len(policy.Sandbox) > 0 {
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0701, "sandbox"))
+	}
+
+	if len(policy.ReportTo) == 0 && len(policy.ReportURI) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0007))
+	}
+
+	return errs.ErrorOrNil()
+}
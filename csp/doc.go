@@ -14,5 +14,15 @@
 
 /*
 Package csp provides a Content Security Policy parser and evaluator for Go.
+
+It parses policies into a tree, reports diagnostics, and answers questions about
+a single policy (e.g., Policy.Explain). Evaluation across several policies or
+origins lives in csp/evaluate, fleet-level reports in csp/report, and rendering
+in csp/format.
+
+From v1.0.0, exported identifiers, JSON field names, and diagnostic codes are
+stable within a major version. A diagnostic code is never reused for a
+different meaning, and the wording of messages may change, so match on the
+code.
 */
 package csp
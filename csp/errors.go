@@ -18,12 +18,106 @@ const (
 	// Parser and evaluator configuration
 	errCSP0001 = "[INFO] currentURL is empty, so validation of 'self' sources is disabled [CSP-0001]"
 	errCSP0002 = "[INFO] reportingEndpointsHeader is empty, so validation of `report-to` is disabled [CSP-0002]"
+	errCSP0003 = "[ERROR] origin `%s` is not a valid absolute URL [CSP-0003]"
+	errCSP0004 = "[ERROR] URL `%s` is not a valid absolute URL [CSP-0004]"
+	errCSP0005 = "[ERROR] directive `%s`: `'self'` matches nothing, because `%s` has an opaque origin [CSP-0005]"
+	errCSP0006 = "[ERROR] directive `%s`: `'self'` matches nothing, because `sandbox` without " +
+		"`allow-same-origin` gives the document an opaque origin [CSP-0006]"
+	errCSP0007 = "[WARN] report-only policy has no `report-to` or `report-uri` directive, so violations " +
+		"will not be reported anywhere [CSP-0007]"
+	errCSP0008 = "[ERROR] policy is %d bytes long, which exceeds the limit of %d bytes; the policy was not " +
+		"parsed [CSP-0008]"
+	errCSP0009 = "[ERROR] policy has more than %d directives, which exceeds the limit; the remaining " +
+		"directives were not parsed [CSP-0009]"
+	errCSP0010 = "[ERROR] directive `%s` has %d values, which exceeds the limit of %d; the remaining values " +
+		"were not parsed [CSP-0010]"
+	errCSP0011 = "[WARN] found %d `<meta http-equiv=\"Content-Security-Policy\">` element(s) outside of " +
+		"`<head>`, which browsers ignore [CSP-0011]"
+	errCSP0012 = "[WARN] directive `%s` is ignored in a policy delivered by a `<meta>` element [CSP-0012]"
+	errCSP0013 = "[WARN] header `%s` is obsolete and ignored by current browsers; use `Content-Security-Policy` " +
+		"instead [CSP-0013]"
+	errCSP0014 = "[ERROR] unknown preset `%s`; expected one of: %s [CSP-0014]"
+	errCSP0015 = "[INFO] header `%s` appears with several casings (`%s`); they were combined, as header names " +
+		"are case-insensitive [CSP-0015]"
+	errCSP0016 = "[WARN] header `%s` was sent in the trailer of the response, where browsers ignore it [CSP-0016]"
+	errCSP0017 = "[INFO] nonce `%s` appears in policies %s of the same response [CSP-0017]"
+	errCSP0018 = "[ERROR] keyword `%s` is not valid; keywords are a single-quoted name of ASCII letters, digits, " +
+		"and `-`, and may not look like `'none'`, a nonce-source, or a hash-source [CSP-0018]"
+	errCSP0019 = "[ERROR] keyword `%s` is already recognized [CSP-0019]"
+	errCSP0020 = "[ERROR] directive `%s` does not take a source list, so keywords cannot be registered for it " +
+		"[CSP-0020]"
+	errCSP0021 = "[INFO] policy has no `report-to` or `report-uri` directive, so violations will be invisible; " +
+		"send a `Reporting-Endpoints` header and add `report-to` (with `report-uri` for older browsers) [CSP-0021]"
+	errCSP0022 = "[INFO] policy has no valid reporting destination, so violations will be invisible; fix the " +
+		"`report-to` endpoint or the `report-uri` URLs [CSP-0022]"
+	errCSP0023 = "[ERROR] policy appears to be truncated after `%s`, and the rest of it is missing; check for a " +
+		"length limit on the header in the web server, CDN, or proxy that sets it [CSP-0023]"
+	errCSP0024 = "[ERROR] unknown baseline `%s`; expected one of: %s [CSP-0024]"
+	errCSP0025 = "[WARN] directive `%s`: `%s` in policy %d has no effect on `%s`, because it is not allowed by " +
+		"enforced %s; a load must be allowed by every enforced policy [CSP-0025]"
+	errCSP0026 = "[INFO] directive `%s`: `%s` in policy %d is narrowed for `%s` by enforced %s, which only " +
+		"allows part of it [CSP-0026]"
+	errCSP0027 = "[ERROR] currentURL `%s` is not a valid absolute URL, so it was ignored and validation of " +
+		"'self' sources is disabled [CSP-0027]"
+	errCSP0028 = "[WARN] currentURL `%s` uses the `%s` scheme instead of HTTP(S), so the checks that depend on its " +
+		"origin may not match a deployed site [CSP-0028]"
+	errCSP0029 = "[WARN] directive `%s` has no effect in a %s, because %s [CSP-0029]"
+	errCSP0030 = "[ERROR] no policy was found; the header values only contain commas and whitespace [CSP-0030]"
+	errCSP0031 = "[ERROR] `%s` in directive `%s` is empty or contains whitespace, `;`, or `,`, so it cannot be a " +
+		"directive name or value [CSP-0031]"
 
 	// Source expressions
 	errCSP0100 = "[ERROR] directive `%s` has an invalid value `%s` [CSP-0100]"
+	errCSP0101 = "[ERROR] directive `%s`: host-source `%s` contains userinfo (`user@` or `user:password@`), " +
+		"which is not allowed [CSP-0101]"
+	errCSP0102 = "[ERROR] directive `%s`: host-source `%s` contains a fragment (`#...`), which is not allowed [CSP-0102]"
+	errCSP0103 = "[WARN] directive `%s`: keyword `%s` only has meaning in `navigate-to`, and is ignored here [CSP-0103]"
+	errCSP0104 = "[WARN] directive `%s`: keyword `%s` only has meaning in `script-src-elem`, `script-src`, and " +
+		"`default-src`, and is ignored here [CSP-0104]"
+	errCSP0105 = "[ERROR] directive `%s`: host-source `%s` has an invalid port; it must be `*` or a number from 1 to " +
+		"65535 [CSP-0105]"
+	errCSP0106 = "[INFO] directive `%s`: host-source `%s` allows any port [CSP-0106]"
+	errCSP0107 = "[ERROR] directive `%s`: host-source `%s` has an invalid path-part; paths may only contain " +
+		"unreserved characters, sub-delimiters, `:`, `@`, and valid percent-encoding [CSP-0107]"
+	errCSP0108 = "[WARN] directive `%s`: host-source `%s` has a Unicode host, which some browsers convert and " +
+		"others reject; use the ASCII form `%s` [CSP-0108]"
+	errCSP0109 = "[ERROR] directive `%s`: hash-source `%s` has a %d-byte digest, but %s digests are %d bytes, so it " +
+		"will never match [CSP-0109]"
+	errCSP0110 = "[WARN] directive `%s`: nonce-source `%s` has about %d bits of entropy; nonces should have at least " +
+		"%d bits [CSP-0110]"
+	errCSP0111 = "[WARN] directive `%s`: nonce-source `%s` follows an obvious pattern, so it can be guessed; " +
+		"generate nonces with a cryptographically secure random number generator [CSP-0111]"
+	errCSP0112 = "[ERROR] directive `%s`: `'none'` must be the only value, but the list also contains `%s`; " +
+		"browsers ignore `'none'` here, so the list allows those sources [CSP-0112]"
+	errCSP0113 = "[WARN] directive `%s`: keyword `%s` was registered for `%s` only, and is ignored here [CSP-0113]"
+	errCSP0114 = "[WARN] directive `%s` has no values, so it matches nothing, like `'none'`; write `'none'` if " +
+		"this is intended, since an empty directive is more often a serialization bug [CSP-0114]"
+	errCSP0115 = "[INFO] directive `%s`: source `%s` appears more than once; the duplicate was ignored [CSP-0115]"
+	errCSP0116 = "[ERROR] directive `%s`: host-source `%s` has a host-part that does not follow the CSP grammar; " +
+		"labels may only contain letters, digits, and `-`, and `*` may only be the first label [CSP-0116]"
+	errCSP0117 = "[WARN] directive `%s`: host-source `%s` has an underscore in its host-part; browsers accept it, " +
+		"but it is not valid in the CSP grammar [CSP-0117]"
+	errCSP0118 = "[INFO] directive `%s`: host-source `%s` has a trailing dot in its host-part, which names the " +
+		"same host as `%s`; the dot was removed [CSP-0118]"
+	errCSP0119 = "[WARN] directive `%s`: host-source `%s` is a wildcard that allows any host [CSP-0119]"
+	errCSP0120 = "[WARN] directive `%s`: `%s` has no effect here, since browsers only check %s in `%s` " +
+		"[CSP-0120]"
+	errCSP0121 = "[WARN] directive `%s`: scheme-source `%s` %s [CSP-0121]"
+	errCSP0122 = "[ERROR] directive `%s`: `%s` allows `javascript:` URLs, which run script in the page " +
+		"(e.g., as the target of a form or a link), bypassing the rest of the policy; remove it [CSP-0122]"
+	errCSP0123 = "[ERROR] directive `%s` has an invalid value `%s`; did you mean `%s`? [CSP-0123]"
+	errCSP0124 = "[WARN] directive `%s` has `%s`, which browsers read as a host-source for a host of that name; " +
+		"did you mean `%s`? [CSP-0124]"
 
 	// Ancestor expressions
 	errCSP0200 = "[ERROR] directive `%s` has an invalid value `%s` [CSP-0200]"
+	errCSP0201 = "[ERROR] directive `%s`: keyword `%s` is not allowed here; the only keywords are `'self'` and " +
+		"`'none'` [CSP-0201]"
+	errCSP0202 = "[ERROR] directive `%s`: `%s` is not allowed here, since nonces and hashes only apply to scripts " +
+		"and styles, not to the documents that embed this one [CSP-0202]"
+	errCSP0203 = "[ERROR] directive `%s`: `%s` has no meaning here, since this directive controls which documents " +
+		"may embed this one, not which scripts or styles may run; remove it [CSP-0203]"
+	errCSP0204 = "[ERROR] directive `%s` has an invalid value `%s`; did you mean `%s`? [CSP-0204]"
 
 	// Plugin types
 	errCSP0300 = "[ERROR] directive `%s` has an invalid value `%s` [CSP-0300]"
@@ -31,12 +125,17 @@ const (
 	// Reporting URLs
 	errCSP0400 = "[ERROR] directive `%s` has an invalid value `%s` [CSP-0400]"
 	errCSP0401 = "[ERROR] directive `%s`: could not parse as a URL: `%s` [CSP-0401]"
-	errCSP0402 = "[ERROR] directive `%s`: URL `%s` is missing a SCHEME, which is required [CSP-0402]"
+	errCSP0402 = "[ERROR] directive `%s`: URL `%s` is missing a SCHEME, which is required unless a currentURL is " +
+		"given to resolve it against [CSP-0402]"
 	errCSP0403 = "[ERROR] directive `%s`: URL `%s` includes a FRAGMENT, which is disallowed [CSP-0403]"
+	errCSP0404 = "[WARN] directive `%s`: URL `%s` is not potentially trustworthy (HTTPS, or a localhost or " +
+		"loopback address), so browsers will refuse to send reports to it [CSP-0404]"
 
 	// Report-To directive and Reporting Endpoints header
-	errCSP0501 = "[ERROR] directive `%s` may only have a single value [CSP-0501]"
 	errCSP0502 = "[ERROR] directive `%s` refers to undefined reporting endpoint `%s` [CSP-0502]"
+	errCSP0503 = "[ERROR] directive `%s` requires the name of a reporting endpoint [CSP-0503]"
+	errCSP0504 = "[ERROR] directive `%s` may only have a single value; browsers send reports to `%s`, and ignore " +
+		"`%s` [CSP-0504]"
 	errCSP0510 = "[ERROR] token-pair `%s` does not contain an `=` character [CSP-0510]"
 	errCSP0511 = "[ERROR] `%s` appears to be missing a comma between token-pairs [CSP-0511]"
 	errCSP0512 = "[ERROR] token-pair `%s` is missing either a key or value [CSP-0512]"
@@ -45,13 +144,23 @@ const (
 	errCSP0515 = "[ERROR] token-pair `%s` is missing a URL [CSP-0515]"
 	errCSP0516 = "[ERROR] token-pair `%s` URL is not enclosed in double quotes [CSP-0516]"
 	errCSP0517 = "[ERROR] token-pair `%s` URL is not a valid URL [CSP-0517]"
+	errCSP0518 = "[WARN] directive `%s`: reporting endpoint `%s` has URL `%s`, which is not potentially " +
+		"trustworthy (HTTPS, or a localhost or loopback address), so browsers will refuse to send reports to it " +
+		"[CSP-0518]"
 
 	// WebRTC
 	errCSP0600 = "[ERROR] directive `%s` has an invalid value `%s` [CSP-0600]"
 	errCSP0601 = "[ERROR] directive `%s` may only have a single value [CSP-0601]"
+	errCSP0602 = "[ERROR] directive `%s` has value `%s`, but the grammar only allows lowercase `%s` [CSP-0602]"
 
 	// Sandboxing
 	errCSP0700 = "[ERROR] directive `%s` has an invalid value `%s` [CSP-0700]"
+	errCSP0701 = "[WARN] directive `%s` is ignored in a report-only policy [CSP-0701]"
+	errCSP0702 = "[ERROR] directive `%s` has an invalid value `%s`; did you mean `%s`? [CSP-0702]"
+	errCSP0703 = "[INFO] directive `%s`: sandbox token `%s` %s; browsers that do not support it ignore it, and " +
+		"keep the restriction in place [CSP-0703]"
+	errCSP0704 = "[WARN] directive `%s` allows both `allow-scripts` and `allow-same-origin`, so the sandboxed " +
+		"document can remove its own sandboxing [CSP-0704]"
 
 	// Deprecations and obsoletions
 	errCSP0801 = "[ERROR] directive `%s` is obsolete; use `upgrade-insecure-requests` instead [CSP-0801]"
@@ -59,7 +168,213 @@ const (
 	errCSP0803 = "[ERROR] directive `%s` was experimental in CSP3, but should now be removed from CSP policies [CSP-0803]"
 	errCSP0804 = "[ERROR] directive `%s` is obsolete; remove this directive from the policy [CSP-0804]"
 	errCSP0805 = "[WARN] directive `%s` is valid in CSP2, but will be deprecated in CSP3 [CSP-0805]"
+	errCSP0806 = "[WARN] directive `%s` was removed from CSP3 and is ignored by browsers; add `integrity` " +
+		"attributes to `<script>` and `<link>` elements instead [CSP-0806]"
+	errCSP0807 = "[ERROR] directive `%s` has an invalid value `%s`; expected `script` or `style` [CSP-0807]"
+	errCSP0808 = "[INFO] directive `%s`: send a `Referrer-Policy: %s` response header instead [CSP-0808]"
+	errCSP0809 = "[INFO] directive `%s`: browsers ignore this directive, and prefetches are governed by " +
+		"`default-src`; remove it from the policy [CSP-0809]"
+	errCSP0810 = "[ERROR] directive `%s` has an invalid value `%s`; expected a referrer policy such as " +
+		"`no-referrer` or `origin` [CSP-0810]"
+	errCSP0811 = "[ERROR] directive `%s` may only have a single value [CSP-0811]"
 
 	// Miscellaneous
 	errCSP0901 = "[ERROR] unknown directive `%s` [CSP-0901]"
+	errCSP0902 = "[ERROR] directive name `%s` is not valid; directive names may only contain ASCII letters, " +
+		"digits, and `-` [CSP-0902]"
+	errCSP0903 = "[ERROR] unknown directive `%s`; directive names cannot contain spaces, did you mean `%s`? " +
+		"[CSP-0903]"
+	errCSP0904 = "[INFO] directive `default-src` is easier to review as the first directive in the policy [CSP-0904]"
+	errCSP0905 = "[INFO] directive `%s` is easier to find as one of the last directives in the policy [CSP-0905]"
+	errCSP0906 = "[INFO] directive `%s` includes `'report-sample'`, but the policy has no `report-to` or " +
+		"`report-uri` directive to send the sample to [CSP-0906]"
+	errCSP0907 = "[WARN] directive `%s` may only appear once per policy; browsers ignore every occurrence " +
+		"after the first [CSP-0907]"
+	errCSP0908 = "[WARN] nonce `%s` appears in both `%s` and `%s`; anyone who learns it can inject both kinds of " +
+		"content, so use a separate nonce for each [CSP-0908]"
+	errCSP0909 = "[ERROR] directive `%s` does not take a value, but has `%s`; browsers ignore the value [CSP-0909]"
+	errCSP0910 = "[WARN] directive `%s`: fragment %d has `'none'`, but fragment %d allows sources, so the merged " +
+		"directive allows `%s` [CSP-0910]"
+	errCSP0911 = "[WARN] directive `%s` differs between fragments %d and %d; the merged policy keeps `%s` from " +
+		"fragment %d [CSP-0911]"
+	errCSP0912 = "[ERROR] directive `%s`: `%s` contains %s (%U), which the CSP grammar does not allow; browsers " +
+		"may ignore the whole directive, or misread the value [CSP-0912]"
+	errCSP0913 = "[ERROR] directive `%s` is surrounded by non-ASCII whitespace, which the CSP grammar does not " +
+		"allow; browsers only strip ASCII whitespace, so they read it as part of the directive [CSP-0913]"
+	errCSP0914 = "[WARN] `%s` has a `,`, which starts a new policy; use `;` to separate directives, or a space to " +
+		"separate values [CSP-0914]"
+	errCSP0915 = "[ERROR] unknown directive `%s`, which looks like a value of `%s`; the `;` before it ends the " +
+		"directive, so use a space to separate values [CSP-0915]"
+	errCSP0916 = "[ERROR] unknown directive `%s`; did you mean `%s`? [CSP-0916]"
+
+	// Trusted Types
+	errCSP1100 = "[ERROR] directive `%s` has an invalid value `%s`; the only sink group is `'script'` [CSP-1100]"
+	errCSP1101 = "[ERROR] directive `%s` requires at least one sink group (e.g., `'script'`) [CSP-1101]"
+
+	// Host reputation
+	errCSP1001 = "[ERROR] directive `%s` allows `%s`, which is on a blocklist (%s) [CSP-1001]"
+	errCSP1002 = "[WARN] directive `%s` allows `%s`, which was recently registered (%s) [CSP-1002]"
+	errCSP1003 = "[WARN] could not look up the reputation of `%s`: %v [CSP-1003]"
+
+	// Vendors
+	errCSP1301 = "[WARN] %s appears to be partially configured: `%s` allows `%s`, but `%s` does not allow " +
+		"`%s` [CSP-1301]"
+
+	// HTML audit
+	errCSP1201 = "[WARN] directive `%s` declares `%s`, but no element in the document uses it [CSP-1201]"
+	errCSP1202 = "[WARN] an inline `<%s>` element is blocked by `%s`; its hash is `%s` [CSP-1202]"
+	errCSP1203 = "[ERROR] `%s`: `%s` would be blocked by `%s` [CSP-1203]"
+	errCSP1204 = "[ERROR] `%s`: inline `%s` would be blocked by `%s`; its hash is `%s` [CSP-1204]"
+	errCSP1205 = "[WARN] `%s`: nonce `%s` from `%s` appears in a static file; nonces must be generated for each " +
+		"response, so this one is reused [CSP-1205]"
+	errCSP1206 = "[ERROR] `%s`: the `csp` attribute holds %d policies, but Embedded Enforcement only accepts a " +
+		"single policy, so browsers ignore it [CSP-1206]"
+	errCSP1207 = "[WARN] `%s` `csp` attribute: directive `%s` allows `%s`, which the embedding policy blocks in " +
+		"`%s`, so the framed document is held to a weaker policy [CSP-1207]"
+	errCSP1208 = "[WARN] `%s` `csp` attribute: the policy does not restrict `%s`, which the embedding policy " +
+		"restricts with `%s` [CSP-1208]"
+	errCSP1209 = "[INFO] `%s` `srcdoc` `<meta>` policy: directive `%s` allows `%s`, but the `srcdoc` document " +
+		"inherits the embedding policy, which blocks it in `%s` [CSP-1209]"
 )
+
+// retiredCatalog lists the diagnostics that this package no longer returns. They
+// stay in the catalog, so that their codes are never reused for a different
+// meaning.
+var retiredCatalog = []string{
+	// Replaced by CSP-0504, which also names the values that are ignored.
+	"[ERROR] directive `%s` may only have a single value [CSP-0501]",
+}
+
+// errorCatalog lists every diagnostic that this package can return. It is
+// exposed to integrating tools through Capabilities.
+var errorCatalog = []string{
+	errCSP0001,
+	errCSP0002,
+	errCSP0003,
+	errCSP0004,
+	errCSP0005,
+	errCSP0006,
+	errCSP0007,
+	errCSP0008,
+	errCSP0009,
+	errCSP0010,
+	errCSP0011,
+	errCSP0012,
+	errCSP0013,
+	errCSP0014,
+	errCSP0015,
+	errCSP0016,
+	errCSP0017,
+	errCSP0018,
+	errCSP0019,
+	errCSP0020,
+	errCSP0021,
+	errCSP0022,
+	errCSP0023,
+	errCSP0024,
+	errCSP0025,
+	errCSP0026,
+	errCSP0027,
+	errCSP0028,
+	errCSP0029,
+	errCSP0030,
+	errCSP0031,
+	errCSP0100,
+	errCSP0101,
+	errCSP0102,
+	errCSP0103,
+	errCSP0104,
+	errCSP0105,
+	errCSP0106,
+	errCSP0107,
+	errCSP0108,
+	errCSP0109,
+	errCSP0110,
+	errCSP0111,
+	errCSP0112,
+	errCSP0113,
+	errCSP0114,
+	errCSP0115,
+	errCSP0116,
+	errCSP0117,
+	errCSP0118,
+	errCSP0119,
+	errCSP0120,
+	errCSP0121,
+	errCSP0122,
+	errCSP0123,
+	errCSP0124,
+	errCSP0200,
+	errCSP0201,
+	errCSP0202,
+	errCSP0203,
+	errCSP0204,
+	errCSP0300,
+	errCSP0400,
+	errCSP0401,
+	errCSP0402,
+	errCSP0403,
+	errCSP0404,
+	errCSP0502,
+	errCSP0503,
+	errCSP0504,
+	errCSP0510,
+	errCSP0511,
+	errCSP0512,
+	errCSP0513,
+	errCSP0514,
+	errCSP0515,
+	errCSP0516,
+	errCSP0517,
+	errCSP0518,
+	errCSP0600,
+	errCSP0601,
+	errCSP0602,
+	errCSP0700,
+	errCSP0701,
+	errCSP0702,
+	errCSP0703,
+	errCSP0704,
+	errCSP0801,
+	errCSP0802,
+	errCSP0803,
+	errCSP0804,
+	errCSP0805,
+	errCSP0806,
+	errCSP0807,
+	errCSP0808,
+	errCSP0809,
+	errCSP0810,
+	errCSP0811,
+	errCSP0901,
+	errCSP0902,
+	errCSP0903,
+	errCSP0904,
+	errCSP0905,
+	errCSP0906,
+	errCSP0907,
+	errCSP0908,
+	errCSP0909,
+	errCSP0910,
+	errCSP0911,
+	errCSP0912,
+	errCSP0913,
+	errCSP0914,
+	errCSP0915,
+	errCSP0916,
+	errCSP1100,
+	errCSP1101,
+	errCSP1001,
+	errCSP1002,
+	errCSP1003,
+	errCSP1201,
+	errCSP1202,
+	errCSP1203,
+	errCSP1204,
+	errCSP1205,
+	errCSP1206,
+	errCSP1207,
+	errCSP1208,
+	errCSP1209,
+	errCSP1301,
+}
@@ -0,0 +1,24 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package evaluate evaluates parsed policies across several policies or candidate
+origins: which policies allow each origin (NewOriginMatrix), and how the
+coverage of `'self'` changes when a policy is shared by several origins
+(CompareSelfOrigins). Questions about a single policy are answered by the csp
+package itself (e.g., csp.Policy.Explain).
+
+It is covered by the same compatibility guarantees as the csp package.
+*/
+package evaluate
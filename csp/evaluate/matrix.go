@@ -0,0 +1,186 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluate
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+type (
+	// OriginMatrix shows, for each origin referenced by any of the policies,
+	// which policies allow it in which directives.
+	OriginMatrix struct {
+		Policies []MatrixPolicy `json:"policies"`
+		Origins  []MatrixRow    `json:"origins"`
+	}
+
+	// MatrixPolicy identifies a column of the matrix.
+	MatrixPolicy struct {
+		Index       int             `json:"index"`
+		Disposition csp.Disposition `json:"disposition,omitempty"`
+		Delivery    csp.Delivery    `json:"delivery,omitempty"`
+	}
+
+	// MatrixRow is a single origin, and the directives that allow it in each
+	// policy. Allowed has one entry per policy, in the same order as
+	// OriginMatrix.Policies.
+	MatrixRow struct {
+		Origin  string     `json:"origin"`
+		Allowed [][]string `json:"allowed"`
+
+		// Conflicts lists the directives that allow the origin in at least one
+		// policy, but block it in another. Because a resource must be allowed by
+		// every enforced policy, these usually point to a configuration mistake.
+		Conflicts []string `json:"conflicts,omitempty"`
+	}
+)
+
+/*
+NewOriginMatrix builds an OriginMatrix for the policies. Origins are taken from
+the host-sources of every policy; wildcard host-sources (e.g., `*.example.com`)
+are not expanded, since they do not name a single origin. The origin of a policy
+that uses `'self'` (from the currentURL passed to csp.Parse) is included too. A directive only
+counts as allowing an origin when one of its source expressions matches, not
+when the policy leaves the directive unrestricted.
+
+----
+
+  - policies ([]*csp.Policy): The parsed policies (e.g., enforced, report-only,
+    and `<meta>` policies for the same response).
+*/
+func NewOriginMatrix(policies []*csp.Policy) *OriginMatrix {
+	directives := csp.SourceListDirectives()
+	matrix := &OriginMatrix{
+		Policies: make([]MatrixPolicy, 0, len(policies)),
+		Origins:  []MatrixRow{},
+	}
+
+	for i := range policies {
+		matrix.Policies = append(matrix.Policies, MatrixPolicy{
+			Index:       i,
+			Disposition: policies[i].Disposition,
+			Delivery:    policies[i].Delivery,
+		})
+	}
+
+	for _, o := range referencedOrigins(policies) {
+		row := MatrixRow{
+			Origin:  o,
+			Allowed: make([][]string, 0, len(policies)),
+		}
+
+		union := []string{}
+		restricted := make([]map[string]bool, len(policies))
+
+		for i := range policies {
+			allowed := []string{}
+			restricted[i] = map[string]bool{}
+
+			for _, d := range policies[i].WhereAllowed(o + "/") {
+				if d.Matched == nil {
+					continue
+				}
+
+				allowed = append(allowed, d.Name)
+
+				if !slices.Contains(union, d.Name) {
+					union = append(union, d.Name)
+				}
+			}
+
+			for _, name := range directives {
+				if _, _, ok := policies[i].EffectiveSourceList(name); ok {
+					restricted[i][name] = true
+				}
+			}
+
+			restricted[i]["frame-ancestors"] = len(policies[i].FrameAncestors) > 0
+			row.Allowed = append(row.Allowed, allowed)
+		}
+
+		for _, name := range union {
+			for i := range policies {
+				if restricted[i][name] && !slices.Contains(row.Allowed[i], name) {
+					row.Conflicts = append(row.Conflicts, name)
+
+					break
+				}
+			}
+		}
+
+		matrix.Origins = append(matrix.Origins, row)
+	}
+
+	return matrix
+}
+
+// referencedOrigins returns the sorted, de-duplicated origins named by the
+// host-sources of the policies, and by `'self'` when a policy has an origin.
+func referencedOrigins(policies []*csp.Policy) []string {
+	origins := []string{}
+	directives := csp.SourceListDirectives()
+
+	add := func(hostSource string) {
+		scheme, host, port, _ := hostsource.Split(hostSource)
+
+		if host == "" || strings.HasPrefix(host, "*") {
+			return
+		}
+
+		if scheme == "" {
+			scheme = "https"
+		}
+
+		o := strings.ToLower(scheme + "://" + host)
+		if port != "" && port != "*" {
+			o += ":" + port
+		}
+
+		if !slices.Contains(origins, o) {
+			origins = append(origins, o)
+		}
+	}
+
+	for i := range policies {
+		for _, name := range directives {
+			list, ok := policies[i].SourceList(name)
+			if !ok {
+				continue
+			}
+
+			for j := range list.SourceExprs {
+				add(list.SourceExprs[j].HostSource)
+			}
+
+			if policies[i].Origin != nil && list.HasKeyword("'self'") {
+				add(policies[i].Origin.String())
+			}
+		}
+
+		for j := range policies[i].FrameAncestors {
+			for k := range policies[i].FrameAncestors[j].AncestorExprs {
+				add(policies[i].FrameAncestors[j].AncestorExprs[k].HostSource)
+			}
+		}
+	}
+
+	slices.Sort(origins)
+
+	return origins
+}
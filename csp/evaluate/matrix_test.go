@@ -0,0 +1,66 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluate
+
+import (
+	"testing"
+
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOriginMatrix(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := csp.Parse("", "", []string{
+		"script-src https://cdn.example.com https://*.example.net; img-src *",
+		"script-src 'self'; frame-ancestors https://partner.example.org",
+	})
+
+	matrix := NewOriginMatrix(policies)
+	assert.Len(matrix.Policies, 2)
+	assert.Len(matrix.Origins, 2)
+
+	assert.Equal("https://cdn.example.com", matrix.Origins[0].Origin)
+	assert.Contains(matrix.Origins[0].Allowed[0], "script-src-elem")
+	assert.Contains(matrix.Origins[0].Allowed[0], "img-src")
+	assert.NotContains(matrix.Origins[0].Allowed[1], "script-src-elem")
+	assert.Contains(matrix.Origins[0].Conflicts, "script-src-elem")
+	assert.NotContains(matrix.Origins[0].Conflicts, "img-src")
+
+	assert.Equal("https://partner.example.org", matrix.Origins[1].Origin)
+	assert.Equal([]string{"frame-ancestors"}, matrix.Origins[1].Allowed[1])
+}
+
+func TestNewOriginMatrixSelf(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := csp.Parse("https://example.com:8443/page", "", []string{
+		"script-src 'self'",
+		"script-src https://example.com:8443",
+	})
+
+	matrix := NewOriginMatrix(policies)
+	assert.Len(matrix.Origins, 1)
+
+	assert.Equal("https://example.com:8443", matrix.Origins[0].Origin)
+	assert.Contains(matrix.Origins[0].Allowed[0], "script-src-elem")
+	assert.Contains(matrix.Origins[0].Allowed[1], "script-src-elem")
+	assert.Empty(matrix.Origins[0].Conflicts)
+
+	// Without a currentURL, 'self' names no origin.
+	policies, _ = csp.Parse("", "", []string{"script-src 'self'"})
+	assert.Empty(NewOriginMatrix(policies).Origins)
+}
@@ -0,0 +1,147 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluate
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+// errInvalidOrigin is the diagnostic that csp.ParseOrigin returns for an
+// invalid origin, which is also used here for an origin without a host.
+const errInvalidOrigin = "[ERROR] origin `%s` is not a valid absolute URL [CSP-0003]"
+
+type (
+	// SelfOriginComparison describes how the coverage of `'self'` changes when the
+	// same policy is served from several different origins (e.g., a policy that
+	// is shared across many virtual hosts).
+	SelfOriginComparison struct {
+		Origins    []string       `json:"origins"`
+		Directives []SelfCoverage `json:"directives,omitempty"`
+	}
+
+	// SelfCoverage describes, for a single directive, which of the candidate
+	// origins are allowed when the policy is served from each candidate origin.
+	// OriginDependent is true only when those allowed sets are not all the same,
+	// so a directive that lists every candidate origin alongside `'self'` is not
+	// origin-dependent.
+	SelfCoverage struct {
+		Directive          string              `json:"directive"`
+		EffectiveDirective string              `json:"effectiveDirective"`
+		Allowed            map[string][]string `json:"allowed"`
+		OriginDependent    bool                `json:"originDependent"`
+	}
+)
+
+/*
+CompareSelfOrigins evaluates a single policy as though it were served from each
+of the candidate origins, and reports which candidate origins each directive
+allows. Only directives whose effective source list contains `'self'` are
+reported, since those are the only directives whose coverage depends on the
+origin of the protected document.
+
+----
+
+  - policy (*csp.Policy): The parsed policy to evaluate.
+
+  - origins ([]string): A slice of absolute URLs (e.g., `https://example.com`),
+    each representing an origin that the policy is served from.
+*/
+func CompareSelfOrigins(policy *csp.Policy, origins []string) (*SelfOriginComparison, error) {
+	var (
+		errs  *multierror.Error
+		names = make([]string, 0, len(origins))
+	)
+
+	for i := range origins {
+		o, err := csp.ParseOrigin(origins[i])
+		if err != nil || o == nil {
+			errs = multierror.Append(errs, fmt.Errorf(errInvalidOrigin, origins[i]))
+
+			continue
+		}
+
+		names = append(names, o.String())
+	}
+
+	comparison := &SelfOriginComparison{
+		Origins: names,
+	}
+
+	for _, directive := range csp.SourceListDirectives() {
+		effective, list, ok := policy.EffectiveSourceList(directive)
+		if !ok || !list.HasKeyword(`'self'`) {
+			continue
+		}
+
+		coverage := SelfCoverage{
+			Directive:          directive,
+			EffectiveDirective: effective,
+			Allowed:            make(map[string][]string, len(names)),
+		}
+
+		for i := range names {
+			allowed := []string{}
+
+			for j := range names {
+				explanation, err := policy.Explain(names[i], directive, names[j]+"/")
+				if err != nil {
+					errs = multierror.Append(errs, err)
+
+					continue
+				}
+
+				if explanation.Allowed {
+					allowed = append(allowed, names[j])
+				}
+			}
+
+			coverage.Allowed[names[i]] = allowed
+		}
+
+		coverage.OriginDependent = allowedSetsDiffer(coverage.Allowed)
+		comparison.Directives = append(comparison.Directives, coverage)
+	}
+
+	return comparison, errs.ErrorOrNil()
+}
+
+// allowedSetsDiffer reports whether any two origins allow a different set of
+// candidate origins, regardless of the order in which they are listed.
+func allowedSetsDiffer(allowed map[string][]string) bool {
+	var first []string
+
+	seen := false
+
+	for _, origins := range allowed {
+		sorted := slices.Clone(origins)
+		slices.Sort(sorted)
+
+		if !seen {
+			first, seen = sorted, true
+
+			continue
+		}
+
+		if !slices.Equal(first, sorted) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluate
+
+import (
+	"testing"
+
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareSelfOrigins(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := csp.Parse("", "", []string{"default-src 'self'; script-src 'self' https://www.example.com; img-src *"})
+
+	comparison, err := CompareSelfOrigins(policies[0], []string{"https://example.com", "https://www.example.com"})
+	assert.NoError(err)
+
+	for i := range comparison.Directives {
+		coverage := comparison.Directives[i]
+
+		assert.NotEqual("img-src", coverage.Directive)
+
+		if coverage.Directive == "script-src" {
+			assert.Equal("script-src", coverage.EffectiveDirective)
+			assert.Equal(
+				[]string{"https://example.com", "https://www.example.com"},
+				coverage.Allowed["https://example.com"],
+			)
+			assert.Equal([]string{"https://www.example.com"}, coverage.Allowed["https://www.example.com"])
+			assert.True(coverage.OriginDependent)
+		}
+
+		if coverage.Directive == "worker-src" {
+			assert.Equal("script-src", coverage.EffectiveDirective)
+		}
+	}
+
+	_, err = CompareSelfOrigins(policies[0], []string{"example.com"})
+	assert.Error(err)
+
+	// Every candidate origin is listed explicitly, so `'self'` changes nothing.
+	policies, _ = csp.Parse("", "", []string{"script-src 'self' https://example.com https://www.example.com"})
+
+	comparison, err = CompareSelfOrigins(policies[0], []string{"https://example.com", "https://www.example.com"})
+	assert.NoError(err)
+	assert.NotEmpty(comparison.Directives)
+
+	for i := range comparison.Directives {
+		assert.False(comparison.Directives[i].OriginDependent)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation is a trace of how a single policy decided whether or not a
+// resource may be loaded.
+type Explanation struct {
+	PolicyIndex        int         `json:"policyIndex"`
+	Directive          string      `json:"directive"`
+	EffectiveDirective string      `json:"effectiveDirective,omitempty"`
+	URL                string      `json:"url"`
+	Allowed            bool        `json:"allowed"`
+	Matched            *SourceExpr `json:"matched,omitempty"`
+
+	// Ignored is the source expression that matched the URL, but that browsers
+	// ignore because the source list contains `'strict-dynamic'` (e.g., a
+	// host-source in `script-src`).
+	Ignored *SourceExpr `json:"ignored,omitempty"`
+	Reason  string      `json:"reason"`
+}
+
+/*
+Explain determines whether each policy allows the resource to be loaded, and
+returns an Explanation for each policy. A resource is only loaded when every
+enforced policy allows it, so allowed is true only if every Explanation for an
+enforced policy is allowed. Report-only policies and policies in trailers are
+explained, but never block the load.
+
+----
+
+  - policies ([]*Policy): The parsed policies, as returned by Parse.
+
+  - currentURL (string): The URL of the protected document. May be an empty
+    string, but then `'self'` will never match.
+
+  - directive (string): The directive that governs the load (e.g.,
+    `script-src-elem` for a `<script src>` element).
+
+  - target (string): The absolute URL of the resource being loaded.
+*/
+func Explain(policies []*Policy, currentURL, directive, target string) (bool, []Explanation, error) {
+	allowed := true
+	explanations := make([]Explanation, 0, len(policies))
+
+	for i := range policies {
+		explanation, err := policies[i].Explain(currentURL, directive, target)
+		if err != nil {
+			return false, nil, err
+		}
+
+		explanation.PolicyIndex = i
+		allowed = allowed && (explanation.Allowed ||
+			policies[i].Disposition == DispositionReport ||
+			policies[i].Delivery == DeliveryTrailer)
+		explanations = append(explanations, explanation)
+	}
+
+	return allowed, explanations, nil
+}
+
+/*
+Explain determines whether this policy allows the resource to be loaded, and
+explains which directive (after fallback) and which source expression made the
+decision.
+
+----
+
+  - currentURL (string): The URL of the protected document. May be an empty
+    string, but then `'self'` will never match.
+
+  - directive (string): The directive that governs the load (e.g.,
+    `script-src-elem` for a `<script src>` element).
+
+  - target (string): The absolute URL of the resource being loaded.
+*/
+func (p *Policy) Explain(currentURL, directive, target string) (Explanation, error) {
+	var self *Origin
+
+	directive = strings.ToLower(directive)
+	explanation := Explanation{
+		Directive: directive,
+		URL:       target,
+	}
+
+	if currentURL != "" {
+		o, err := ParseOrigin(currentURL)
+		if err != nil {
+			return explanation, err
+		}
+
+		self = o
+	}
+
+	u, err := parseRequestURL(target)
+	if err != nil {
+		return explanation, err
+	}
+
+	effective, list, ok := p.effectiveSourceList(directive)
+	if !ok {
+		explanation.Allowed = true
+		explanation.Reason = fmt.Sprintf(
+			"none of the directives in the fallback list (%s) are present, so the load is not restricted",
+			strings.Join(fallbacksFor(directive), ", "),
+		)
+
+		return explanation, nil
+	}
+
+	explanation.EffectiveDirective = effective
+	strictDynamic := ignoresURLSources(directive, list)
+
+	matched, ok := matchesSourceList(u, list, self)
+
+	switch {
+	case ok && strictDynamic:
+		explanation.Ignored = matched
+		explanation.Reason = fmt.Sprintf(
+			"`%s` matched source expression `%s`, but it is ignored because `%s` contains `'strict-dynamic'`; "+
+				"the script is only allowed with a matching nonce or hash, or when an already-trusted script "+
+				"loads it",
+			effective,
+			matched.String(),
+			effective,
+		)
+
+		return explanation, nil
+	case ok:
+		explanation.Allowed = true
+		explanation.Matched = matched
+		explanation.Reason = fmt.Sprintf("`%s` matched source expression `%s`", effective, matched.String())
+
+		return explanation, nil
+	}
+
+	explanation.Reason = explainMismatch(effective, list, self)
+
+	if strictDynamic {
+		explanation.Reason += "; `'strict-dynamic'` is present, so only a matching nonce or hash, or an " +
+			"already-trusted script loading it, allows the script"
+	}
+
+	return explanation, nil
+}
+
+/*
+ignoresURLSources reports whether browsers ignore the host-sources,
+scheme-sources, and `'self'` of the source list when loading a script, because
+it contains `'strict-dynamic'`.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#strict-dynamic-usage
+
+----
+
+  - directive (string): The lowercase directive that governs the load.
+
+  - list (*SourceListItem): The effective source list for the directive.
+*/
+func ignoresURLSources(directive string, list *SourceListItem) bool {
+	return (directive == "script-src" || directive == "script-src-elem") && containsKeyword(list, `'strict-dynamic'`)
+}
+
+// explainMismatch describes why none of the source expressions in the list
+// matched.
+func explainMismatch(directive string, list *SourceListItem, self *Origin) string {
+	exprs := list.expressions()
+
+	if len(exprs) == 0 {
+		return fmt.Sprintf("`%s` does not allow any sources", directive)
+	}
+
+	checked := make([]string, 0, len(exprs))
+	selfInert := false
+
+	for i := range exprs {
+		checked = append(checked, "`"+exprs[i].String()+"`")
+
+		if strings.EqualFold(exprs[i].KeywordSource, `'self'`) && self == nil {
+			selfInert = true
+		}
+	}
+
+	reason := fmt.Sprintf("no source expression in `%s` matched (checked %s)", directive, strings.Join(checked, ", "))
+
+	if selfInert {
+		reason += "; `'self'` could not match because the origin of the protected document is unknown or opaque"
+	}
+
+	return reason
+}
+
+// fallbacksFor returns the directive fallback list for the directive.
+func fallbacksFor(directive string) []string {
+	if fallbacks, ok := directiveFallbackList[directive]; ok {
+		return fallbacks
+	}
+
+	return []string{directive}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+type (
+	// Fetcher is implemented by anything that can retrieve a URL. Every network
+	// feature of this package goes through a Fetcher, so that callers can
+	// substitute their own HTTP stack (e.g., custom authentication, an internal
+	// service mesh, or recorded fixtures in tests).
+	Fetcher interface {
+		// Fetch performs a GET request for the URL. The caller closes the body of
+		// the response.
+		Fetch(ctx context.Context, rawURL string) (*http.Response, error)
+	}
+
+	// HTTPFetcher is a Fetcher backed by an *http.Client.
+	HTTPFetcher struct {
+		// Client is the client used for requests. If nil, http.DefaultClient is
+		// used.
+		Client *http.Client
+	}
+)
+
+// maxFetchedBody is the largest HTML document that FetchPolicies will read
+// while looking for `<meta>` policies.
+const maxFetchedBody = 10 * 1024 * 1024
+
+// Fetch implements Fetcher.
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+/*
+FetchPolicies retrieves the URL with the fetcher and parses every policy that
+applies to the response: the `Content-Security-Policy` and
+`Content-Security-Policy-Report-Only` headers and, for HTML documents, any
+`<meta http-equiv="Content-Security-Policy">` elements.
+
+----
+
+  - ctx (context.Context): Controls cancellation and deadlines.
+
+  - fetcher (Fetcher): Retrieves the URL. If nil, an HTTPFetcher with
+    http.DefaultClient is used.
+
+  - rawURL (string): The absolute URL of the document.
+
+  - opts (...Option): Optional settings that change how the policies are
+    parsed.
+*/
+func FetchPolicies(ctx context.Context, fetcher Fetcher, rawURL string, opts ...Option) ([]*Policy, error) {
+	var errs *multierror.Error
+
+	if fetcher == nil {
+		fetcher = &HTTPFetcher{}
+	}
+
+	resp, err := fetcher.Fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	// The final URL may differ from rawURL after redirects.
+	currentURL := rawURL
+	if resp.Request != nil && resp.Request.URL != nil {
+		currentURL = resp.Request.URL.String()
+	}
+
+	policies, err := ParseHeaderContext(ctx, currentURL, resp.Header, opts...)
+	errs = multierror.Append(errs, err)
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/html" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchedBody))
+		if err != nil {
+			return policies, multierror.Append(errs, err).ErrorOrNil()
+		}
+
+		// Continue the numbering of the header policies, and do not repeat the
+		// diagnostics about the call as a whole.
+		meta, err := ParseHTMLContext(
+			ctx,
+			currentURL,
+			bytes.NewReader(body),
+			append(opts, withPolicyOffset(len(policies), true))...,
+		)
+		errs = multierror.Append(errs, err)
+		policies = append(policies, meta...)
+	}
+
+	errs = multierror.Append(errs, newConfig(opts).filterSeverity(checkResponseNonces(policies))...)
+
+	return policies, errs.ErrorOrNil()
+}
+
+/*
+checkResponseNonces reports nonce values that appear in more than one of the
+policies of a single response.
+
+----
+
+  - policies ([]*Policy): Every policy of the response.
+*/
+func checkResponseNonces(policies []*Policy) []error {
+	errs := []error{}
+	nonces := []string{}
+	indexes := map[string][]string{}
+
+	for i := range policies {
+		for _, use := range policyNonces(policies[i]) {
+			index := strconv.Itoa(i)
+
+			if !slices.Contains(nonces, use.nonce) {
+				nonces = append(nonces, use.nonce)
+			}
+
+			if !slices.Contains(indexes[use.nonce], index) {
+				indexes[use.nonce] = append(indexes[use.nonce], index)
+			}
+		}
+	}
+
+	for _, nonce := range nonces {
+		if len(indexes[nonce]) > 1 {
+			errs = append(errs, fmt.Errorf(errCSP0017, nonce, strings.Join(indexes[nonce], ", ")))
+		}
+	}
+
+	return errs
+}
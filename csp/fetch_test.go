@@ -0,0 +1,91 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureFetcher returns a recorded response instead of making a request.
+type fixtureFetcher struct {
+	header http.Header
+	body   string
+}
+
+func (f *fixtureFetcher) Fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     f.header,
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestFetchPolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	fetcher := &fixtureFetcher{
+		header: http.Header{
+			"Content-Type":                        []string{"text/html; charset=utf-8"},
+			"Content-Security-Policy":             []string{"default-src 'self'"},
+			"Content-Security-Policy-Report-Only": []string{"script-src 'none'; report-uri /csp"},
+		},
+		body: `<html><head><meta http-equiv="Content-Security-Policy" content="img-src 'self'"></head></html>`,
+	}
+
+	policies, err := FetchPolicies(context.Background(), fetcher, "https://example.com/", MinSeverity(SeverityError))
+	assert.Len(policies, 3)
+
+	assert.Equal(DispositionEnforce, policies[0].Disposition)
+	assert.Equal(DispositionReport, policies[1].Disposition)
+	assert.Equal(DeliveryMeta, policies[2].Delivery)
+
+	if err != nil {
+		assert.NotContains(err.Error(), "[CSP-0001]")
+	}
+}
+
+func TestFetchPoliciesNonces(t *testing.T) {
+	assert := assert.New(t)
+
+	fetcher := &fixtureFetcher{
+		header: http.Header{
+			"Content-Type":                        []string{"text/html"},
+			"Content-Security-Policy":             []string{"script-src 'nonce-abc123'"},
+			"Content-Security-Policy-Report-Only": []string{"script-src 'nonce-abc123' 'strict-dynamic'"},
+		},
+		body: `<meta http-equiv="Content-Security-Policy" content="script-src 'nonce-abc123' 'nonce-xyz'">`,
+	}
+
+	_, err := FetchPolicies(context.Background(), fetcher, "https://example.com/")
+	assert.ErrorContains(err, "[INFO] nonce `abc123` appears in policies 0, 1, 2 of the same response [CSP-0017]")
+	assert.NotContains(err.Error(), "nonce `xyz`")
+
+	_, err = FetchPolicies(context.Background(), fetcher, "https://example.com/", MinSeverity(SeverityWarning))
+	if err != nil {
+		assert.NotContains(err.Error(), "[CSP-0017]")
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package format renders the policies and diagnostics returned by the csp package
+as JSON, text, Markdown, SARIF, or a risk register, in the same way as the csp-parser CLI.
+*/
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+type (
+	// Renderer writes a Result in a single output format.
+	Renderer interface {
+		Render(w io.Writer, result *Result) error
+	}
+
+	// Result is everything that a call to csp.Parse (or one of its variants)
+	// returned.
+	Result struct {
+		Policies    []*csp.Policy `json:"policies"`
+		Diagnostics []Diagnostic  `json:"diagnostics"`
+		Summary     Summary       `json:"summary"`
+	}
+
+	// Document is a Result as a single JSON document for automation, with the
+	// diagnostics split in two: Diagnostics has the ones found while parsing
+	// (and the ones about the call as a whole), and Findings has the ones from
+	// the checks that look across a whole policy. Pointers are relative to the
+	// document (e.g., `/policies/0/directives/1`).
+	Document struct {
+		Policies    []*csp.Policy `json:"policies"`
+		Diagnostics []Diagnostic  `json:"diagnostics"`
+		Findings    []Diagnostic  `json:"findings"`
+		Summary     Summary       `json:"summary"`
+	}
+
+	// Diagnostic is a single diagnostic, split into its parts.
+	Diagnostic struct {
+		// Code is the code of the diagnostic (e.g., `CSP-0100`).
+		Code string `json:"code,omitempty"`
+
+		// Severity is `INFO`, `WARN`, or `ERROR`.
+		Severity string `json:"severity"`
+
+		// Message is the message, without the severity prefix or the code.
+		Message string `json:"message"`
+
+		// PolicyIndex is the index of the policy that the diagnostic belongs to, or
+		// -1 if it belongs to the call as a whole.
+		PolicyIndex int `json:"policyIndex"`
+
+		Disposition csp.Disposition `json:"disposition,omitempty"`
+		Phase       csp.Phase       `json:"phase,omitempty"`
+		Span        *csp.Span       `json:"span,omitempty"`
+		Pointer     string          `json:"pointer,omitempty"`
+	}
+)
+
+// reMessage splits a diagnostic message into its severity, text, and code.
+var reMessage = regexp.MustCompile(`^\[([A-Z]+)\] (.*) \[(CSP-[0-9]+)\]$`)
+
+// renderers are the built-in renderers, by name.
+var renderers = map[string]func() Renderer{
+	"json":          func() Renderer { return &JSON{Indent: "  "} },
+	"markdown":      func() Renderer { return &Markdown{} },
+	"risk-register": func() Renderer { return &RiskRegister{} },
+	"sarif":         func() Renderer { return &SARIF{} },
+	"text":          func() Renderer { return &Text{} },
+}
+
+/*
+NewResult collects the policies and diagnostics returned by csp.Parse into a
+Result.
+
+----
+
+  - policies ([]*csp.Policy): The parsed policies.
+
+  - err (error): The diagnostics, usually a multierror. May be nil.
+*/
+func NewResult(policies []*csp.Policy, err error) *Result {
+	result := &Result{
+		Policies:    policies,
+		Diagnostics: []Diagnostic{},
+	}
+
+	if result.Policies == nil {
+		result.Policies = []*csp.Policy{}
+	}
+
+	var errs []error
+
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	} else if err != nil {
+		errs = []error{err}
+	}
+
+	for _, e := range errs {
+		result.Diagnostics = append(result.Diagnostics, NewDiagnostic(e))
+	}
+
+	result.Summary = NewSummary(result.Diagnostics)
+
+	return result
+}
+
+/*
+NewDocument splits the diagnostics of a Result into a Document.
+
+----
+
+  - result (*Result): The result to convert.
+*/
+func NewDocument(result *Result) *Document {
+	doc := &Document{
+		Policies:    result.Policies,
+		Diagnostics: []Diagnostic{},
+		Findings:    []Diagnostic{},
+		Summary:     result.Summary,
+	}
+
+	for _, d := range result.Diagnostics {
+		if d.Pointer != "" {
+			d.Pointer = "/policies" + d.Pointer
+		}
+
+		if d.Phase == csp.PhasePolicy {
+			doc.Findings = append(doc.Findings, d)
+		} else {
+			doc.Diagnostics = append(doc.Diagnostics, d)
+		}
+	}
+
+	return doc
+}
+
+/*
+NewDiagnostic splits a single diagnostic into its parts. Errors that are not
+diagnostics of the csp package (e.g., context cancellation) become an `ERROR`
+without a code.
+
+----
+
+  - err (error): A single diagnostic (not a multierror).
+*/
+func NewDiagnostic(err error) Diagnostic {
+	d := Diagnostic{
+		Severity:    csp.SeverityOf(err).String(),
+		Message:     err.Error(),
+		PolicyIndex: -1,
+	}
+
+	if m := reMessage.FindStringSubmatch(err.Error()); m != nil {
+		d.Code, d.Message = m[3], m[2]
+	}
+
+	var pe *csp.PolicyError
+	if errors.As(err, &pe) {
+		d.PolicyIndex = pe.PolicyIndex
+		d.Disposition = pe.Disposition
+		d.Phase = pe.Phase
+		d.Span = pe.Span
+		d.Pointer = pe.Pointer
+	}
+
+	return d
+}
+
+/*
+ByName returns a new built-in renderer with its default settings.
+
+----
+
+  - name (string): One of the names returned by Names.
+*/
+func ByName(name string) (Renderer, error) {
+	newRenderer, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format `%s`; expected one of: %s", name, strings.Join(Names(), ", "))
+	}
+
+	return newRenderer(), nil
+}
+
+// Names returns the names of the built-in renderers, sorted.
+func Names() []string {
+	names := make([]string, 0, len(renderers))
+
+	for name := range renderers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,193 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseResult(t *testing.T) *Result {
+	t.Helper()
+
+	policies, err := csp.Parse("", "", []string{"default-src 'self'; img-src https://example.com:0 | x"})
+
+	return NewResult(policies, err)
+}
+
+func TestNewResult(t *testing.T) {
+	assert := assert.New(t)
+
+	result := parseResult(t)
+
+	assert.Len(result.Policies, 1)
+	assert.Equal(Diagnostic{
+		Code:        "CSP-0001",
+		Severity:    "INFO",
+		Message:     "currentURL is empty, so validation of 'self' sources is disabled",
+		PolicyIndex: -1,
+	}, result.Diagnostics[0])
+
+	d := result.Diagnostics[2]
+	assert.Equal("CSP-0105", d.Code)
+	assert.Equal("ERROR", d.Severity)
+	assert.Equal(0, d.PolicyIndex)
+	assert.Equal(csp.PhaseParse, d.Phase)
+	assert.Equal("/0/directives/1/values/0", d.Pointer)
+	assert.Equal(&csp.Span{Start: 28, End: 49}, d.Span)
+
+	assert.Equal([]Diagnostic{}, NewResult(nil, nil).Diagnostics)
+	assert.Equal([]*csp.Policy{}, NewResult(nil, nil).Policies)
+	assert.Equal(
+		[]Diagnostic{{Severity: "ERROR", Message: "context canceled", PolicyIndex: -1}},
+		NewResult(nil, errors.New("context canceled")).Diagnostics,
+	)
+}
+
+func TestNewDocument(t *testing.T) {
+	assert := assert.New(t)
+
+	result := parseResult(t)
+	doc := NewDocument(result)
+
+	assert.Equal(result.Policies, doc.Policies)
+	assert.Equal(result.Summary, doc.Summary)
+	assert.Len(doc.Diagnostics, 4)
+	assert.Len(doc.Findings, 1)
+	assert.Equal("CSP-0021", doc.Findings[0].Code)
+	assert.Equal("/policies/0/directives/1/values/0", doc.Diagnostics[2].Pointer)
+
+	// The result is not changed.
+	assert.Equal("/0/directives/1/values/0", result.Diagnostics[2].Pointer)
+
+	doc = NewDocument(NewResult(nil, nil))
+	assert.Equal([]Diagnostic{}, doc.Diagnostics)
+	assert.Equal([]Diagnostic{}, doc.Findings)
+}
+
+func TestSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	summary := parseResult(t).Summary
+	assert.Equal(Summary{Errors: 2, Info: 3, Grade: GradeError}, summary)
+	assert.Equal("errors=2 warnings=0 info=3 grade=error", summary.String())
+
+	assert.Equal("errors=0 warnings=0 info=0 grade=clean", NewResult(nil, nil).Summary.String())
+	assert.Equal("errors=0 warnings=0 info=0 grade=clean", Summary{}.String())
+
+	summary = Summary{}
+	summary.Add("INFO")
+	assert.Equal(GradeInfo, summary.Grade)
+	summary.Add("WARN")
+	summary.Add("INFO")
+	assert.Equal(Summary{Warnings: 1, Info: 2, Grade: GradeWarn}, summary)
+}
+
+func TestByName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"json", "markdown", "risk-register", "sarif", "text"}, Names())
+
+	for _, name := range Names() {
+		r, err := ByName(name)
+		assert.NoError(err)
+		assert.NotNil(r)
+	}
+
+	_, err := ByName("yaml")
+	assert.ErrorContains(err, "unknown format `yaml`")
+}
+
+func TestRenderers(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Renderer Renderer
+		Contains []string
+	}{
+		"json": {
+			Renderer: &JSON{},
+			Contains: []string{`"policies":[{`, `"code":"CSP-0105"`, `"pointer":"/0/directives/1/values/0"`},
+		},
+		"text": {
+			Renderer: &Text{},
+			Contains: []string{
+				"INFO CSP-0001: currentURL is empty",
+				"ERROR CSP-0105 policy=0 offset=28-49 pointer=/0/directives/1/values/0: directive `img-src`",
+			},
+		},
+		"markdown": {
+			Renderer: &Markdown{},
+			Contains: []string{
+				"## Notes\n\n| Severity | Code | Location | Message |",
+				"## Policy 0 (enforce, header)\n\n",
+				"| ERROR | CSP-0105 | /0/directives/1/values/0 | directive `img-src`",
+				"`\\|`",
+			},
+		},
+		"sarif": {
+			Renderer: &SARIF{ArtifactURI: "policy.txt"},
+			Contains: []string{
+				`"version": "2.1.0"`,
+				`"ruleId": "CSP-0105"`,
+				`"level": "note"`,
+				`"charOffset": 28`,
+				`"uri": "policy.txt"`,
+			},
+		},
+		"risk-register": {
+			Renderer: &RiskRegister{Asset: "https://example.com/"},
+			Contains: []string{
+				`"id": "CSP-0105-1"`,
+				`"title": "Directive ` + "`img-src`" + `: host-source ` + "`https://example.com:0`" + ` has an invalid port"`,
+				`"remediation": "It must be ` + "`*`" + ` or a number from 1 to 65535."`,
+				`"likelihood": "High"`,
+				`"impact": "Low"`,
+				`Found in policy 0 (enforce).`,
+				`"asset": "https://example.com/"`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var b bytes.Buffer
+
+			assert.NoError(tc.Renderer.Render(&b, parseResult(t)))
+
+			for _, s := range tc.Contains {
+				assert.Contains(b.String(), s)
+			}
+
+			if name == "json" || name == "sarif" || name == "risk-register" {
+				assert.True(json.Valid(b.Bytes()))
+			}
+		})
+	}
+}
+
+func TestMarkdownPolicyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, err := csp.ParsePolicyFile("", "", "# Scripts from the CDN.\nscript-src https://cdn.example.com\n")
+
+	var b bytes.Buffer
+
+	assert.NoError((&Markdown{}).Render(&b, NewResult(policies, err)))
+	assert.Contains(b.String(), "```\n# Scripts from the CDN.\nscript-src https://cdn.example.com;\n```\n")
+}
@@ -0,0 +1,35 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSON renders the Result as a single JSON document.
+type JSON struct {
+	// Indent is the indentation for each level. If empty, the document is
+	// compact.
+	Indent string
+}
+
+// Render implements Renderer.
+func (r *JSON) Render(w io.Writer, result *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", r.Indent)
+
+	return enc.Encode(result)
+}
@@ -0,0 +1,94 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+// Markdown renders each policy and its diagnostics as a section, with the
+// diagnostics in a table (e.g., for pull request comments).
+type Markdown struct{}
+
+// Render implements Renderer.
+func (r *Markdown) Render(w io.Writer, result *Result) error {
+	var b strings.Builder
+
+	if general := result.diagnosticsFor(-1); len(general) > 0 {
+		b.WriteString("## Notes\n\n")
+		writeMarkdownTable(&b, general)
+	}
+
+	for i, policy := range result.Policies {
+		fmt.Fprintf(&b, "## Policy %d (%s, %s)\n\n", i, policy.Disposition, policy.Delivery)
+
+		// A policy read from a policy file is shown with its comments.
+		if policy.HasComments() {
+			fmt.Fprintf(&b, "```\n%s```\n\n", csp.FormatPolicyFile(policy))
+		} else {
+			fmt.Fprintf(&b, "```\n%s\n```\n\n", policy.Raw)
+		}
+
+		if diagnostics := result.diagnosticsFor(i); len(diagnostics) > 0 {
+			writeMarkdownTable(&b, diagnostics)
+		} else {
+			b.WriteString("No diagnostics.\n\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// diagnosticsFor returns the diagnostics that belong to the policy, or to the
+// call as a whole for -1.
+func (r *Result) diagnosticsFor(policyIndex int) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for i := range r.Diagnostics {
+		if r.Diagnostics[i].PolicyIndex == policyIndex {
+			diagnostics = append(diagnostics, r.Diagnostics[i])
+		}
+	}
+
+	return diagnostics
+}
+
+// writeMarkdownTable writes the diagnostics as a Markdown table.
+func writeMarkdownTable(b *strings.Builder, diagnostics []Diagnostic) {
+	b.WriteString("| Severity | Code | Location | Message |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+
+	for i := range diagnostics {
+		fmt.Fprintf(b, "| %s | %s | %s | %s |\n",
+			diagnostics[i].Severity,
+			diagnostics[i].Code,
+			markdownCell(diagnostics[i].Pointer),
+			markdownCell(diagnostics[i].Message),
+		)
+	}
+
+	b.WriteString("\n")
+}
+
+// markdownCell escapes the text for a table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,147 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+type (
+	// RiskRegister renders the diagnostics as entries of a simple risk register
+	// (in the style of the OWASP Risk Rating Methodology), for import into
+	// governance, risk, and compliance (GRC) tools.
+	//
+	// https://owasp.org/www-community/OWASP_Risk_Rating_Methodology
+	RiskRegister struct {
+		// Asset is the affected asset of every entry, normally the URL of the
+		// page that the policies protect.
+		Asset string
+	}
+
+	// RiskEntry is a single entry of a risk register.
+	RiskEntry struct {
+		// ID identifies the entry within the register (e.g., `CSP-0105-1`).
+		ID string `json:"id"`
+
+		// Code is the code of the diagnostic (e.g., `CSP-0105`).
+		Code string `json:"code,omitempty"`
+
+		Title       string `json:"title"`
+		Description string `json:"description"`
+
+		// Likelihood and Impact are `Low`, `Medium`, or `High`.
+		Likelihood string `json:"likelihood"`
+		Impact     string `json:"impact"`
+
+		Remediation string `json:"remediation"`
+		Asset       string `json:"asset,omitempty"`
+	}
+
+	riskRegister struct {
+		Risks []RiskEntry `json:"risks"`
+	}
+)
+
+// riskClauses separate the main clause of a diagnostic message, which becomes
+// the title of its entry, from the explanation.
+var riskClauses = []string{"; ", ", since ", ", so ", ", but ", ", because ", ", which "}
+
+// Render implements Renderer.
+func (r *RiskRegister) Render(w io.Writer, result *Result) error {
+	register := riskRegister{Risks: []RiskEntry{}}
+	seen := map[string]int{}
+
+	for i := range result.Diagnostics {
+		register.Risks = append(register.Risks, r.entry(&result.Diagnostics[i], seen))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(register)
+}
+
+// entry converts a diagnostic into a risk register entry. seen counts the
+// entries for each code, so that IDs are unique.
+func (r *RiskRegister) entry(d *Diagnostic, seen map[string]int) RiskEntry {
+	code := d.Code
+	if code == "" {
+		code = "CSP"
+	}
+
+	seen[code]++
+
+	rating := riskRating(d.Severity)
+	entry := RiskEntry{
+		ID:          fmt.Sprintf("%s-%d", code, seen[code]),
+		Code:        d.Code,
+		Title:       capitalize(d.Message),
+		Description: capitalize(d.Message) + ".",
+		Likelihood:  rating,
+		Impact:      rating,
+		Asset:       r.Asset,
+	}
+
+	for _, clause := range riskClauses {
+		if title, _, ok := strings.Cut(d.Message, clause); ok && len(title) < len(entry.Title) {
+			entry.Title = capitalize(title)
+		}
+	}
+
+	if d.PolicyIndex >= 0 {
+		entry.Description += fmt.Sprintf(" Found in policy %d (%s).", d.PolicyIndex, d.Disposition)
+	}
+
+	// Messages that end with advice (e.g., "...; use `frame-src` instead") carry
+	// their own remediation.
+	if i := strings.LastIndex(d.Message, "; "); i >= 0 {
+		entry.Remediation = capitalize(d.Message[i+2:]) + "."
+	} else if d.Code != "" {
+		entry.Remediation = fmt.Sprintf("Update the policy so that %s is no longer reported.", d.Code)
+	} else {
+		entry.Remediation = "Investigate the error, and validate the policy again."
+	}
+
+	return entry
+}
+
+// riskRating converts a severity into a likelihood and impact rating.
+func riskRating(severity string) string {
+	switch severity {
+	case csp.SeverityInfo.String():
+		return "Low"
+	case csp.SeverityWarning.String():
+		return "Medium"
+	}
+
+	return "High"
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,169 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+type (
+	// SARIF renders the diagnostics as a SARIF 2.1.0 log, for code scanning
+	// tools (e.g., GitHub code scanning).
+	//
+	// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+	SARIF struct {
+		// ArtifactURI is the URI of the file that the policies were read from. If
+		// empty, results have no physical location.
+		ArtifactURI string
+
+		// ToolVersion is the version of the tool reported in the log.
+		ToolVersion string
+	}
+
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId,omitempty"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		CharOffset int `json:"charOffset"`
+		CharLength int `json:"charLength"`
+	}
+)
+
+// Render implements Renderer.
+func (r *SARIF) Render(w io.Writer, result *Result) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "csp-parser",
+			Version:        r.ToolVersion,
+			InformationURI: "https://github.com/northwood-labs/csp-parser",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	catalog := csp.Capabilities().ErrorCodes
+
+	for i := range result.Diagnostics {
+		d := &result.Diagnostics[i]
+
+		if d.Code != "" && !slices.ContainsFunc(run.Tool.Driver.Rules, func(rule sarifRule) bool {
+			return rule.ID == d.Code
+		}) {
+			rule := sarifRule{ID: d.Code, ShortDescription: sarifMessage{Text: d.Message}}
+
+			if j := slices.IndexFunc(catalog, func(c csp.ErrorCode) bool { return c.Code == d.Code }); j >= 0 {
+				rule.ShortDescription.Text = catalog[j].Message
+			}
+
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		res := sarifResult{
+			RuleID:  d.Code,
+			Level:   sarifLevel(d.Severity),
+			Message: sarifMessage{Text: d.Message},
+		}
+
+		if r.ArtifactURI != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: r.ArtifactURI},
+			}}
+
+			if d.Span != nil {
+				loc.PhysicalLocation.Region = &sarifRegion{
+					CharOffset: d.Span.Start,
+					CharLength: d.Span.End - d.Span.Start,
+				}
+			}
+
+			res.Locations = []sarifLocation{loc}
+		}
+
+		run.Results = append(run.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLevel converts a severity into a SARIF result level.
+func sarifLevel(severity string) string {
+	switch severity {
+	case csp.SeverityInfo.String():
+		return "note"
+	case csp.SeverityWarning.String():
+		return "warning"
+	}
+
+	return "error"
+}
@@ -0,0 +1,95 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"fmt"
+
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+// Grades, from best to worst. The grade of a run is the severity of its worst
+// diagnostic, the same as in `csp-parser report`.
+const (
+	GradeClean = "clean"
+	GradeInfo  = "info"
+	GradeWarn  = "warn"
+	GradeError = "error"
+)
+
+// Summary counts diagnostics by severity, so that callers do not have to tally
+// them.
+type Summary struct {
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+	Info     int    `json:"info"`
+	Grade    string `json:"grade"`
+}
+
+/*
+NewSummary counts the diagnostics by severity.
+
+----
+
+  - diagnostics ([]Diagnostic): The diagnostics to count.
+*/
+func NewSummary(diagnostics []Diagnostic) Summary {
+	s := Summary{Grade: GradeClean}
+
+	for i := range diagnostics {
+		s.Add(diagnostics[i].Severity)
+	}
+
+	return s
+}
+
+/*
+Add counts one diagnostic, and updates the grade.
+
+----
+
+  - severity (string): The severity of the diagnostic, as returned by
+    csp.Severity.String (e.g., `WARN`).
+*/
+func (s *Summary) Add(severity string) {
+	switch severity {
+	case csp.SeverityInfo.String():
+		s.Info++
+	case csp.SeverityWarning.String():
+		s.Warnings++
+	default:
+		s.Errors++
+	}
+
+	switch {
+	case s.Errors > 0:
+		s.Grade = GradeError
+	case s.Warnings > 0:
+		s.Grade = GradeWarn
+	default:
+		s.Grade = GradeInfo
+	}
+}
+
+// String returns the summary as a single machine-parsable line (e.g.,
+// `errors=1 warnings=2 info=0 grade=error`).
+func (s Summary) String() string {
+	grade := s.Grade
+	if grade == "" {
+		grade = GradeClean
+	}
+
+	return fmt.Sprintf("errors=%d warnings=%d info=%d grade=%s", s.Errors, s.Warnings, s.Info, grade)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"fmt"
+	"io"
+)
+
+// Text renders each diagnostic on its own line, labeled like the CLI's log
+// output.
+type Text struct{}
+
+// Render implements Renderer.
+func (r *Text) Render(w io.Writer, result *Result) error {
+	for i := range result.Diagnostics {
+		if _, err := fmt.Fprintln(w, result.Diagnostics[i].text()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// text returns the diagnostic as a single line (e.g., `ERROR CSP-0100
+// policy=0 pointer=/0/directives/1: directive ...`).
+func (d *Diagnostic) text() string {
+	s := d.Severity
+
+	if d.Code != "" {
+		s += " " + d.Code
+	}
+
+	if d.PolicyIndex >= 0 {
+		s += fmt.Sprintf(" policy=%d", d.PolicyIndex)
+	}
+
+	if d.Span != nil {
+		s += fmt.Sprintf(" offset=%d-%d", d.Span.Start, d.Span.End)
+	}
+
+	if d.Pointer != "" {
+		s += " pointer=" + d.Pointer
+	}
+
+	return s + ": " + d.Message
+}
@@ -0,0 +1,139 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import "fmt"
+
+/*
+AllowsFraming determines whether each policy allows the protected document to
+be framed by the chain of ancestors, and returns an Explanation for each policy.
+The document is only rendered when every enforced policy allows it, so allowed
+is true only if every Explanation for an enforced policy is allowed.
+
+----
+
+  - policies ([]*Policy): The parsed policies, as returned by Parse.
+
+  - currentURL (string): The URL of the protected document. May be an empty
+    string, but then `'self'` will never match.
+
+  - ancestorOrigins ([]string): The origins of the documents that embed the
+    protected document, from the parent to the top-level document (the order
+    of `location.ancestorOrigins`).
+*/
+func AllowsFraming(policies []*Policy, currentURL string, ancestorOrigins []string) (bool, []Explanation) {
+	allowed := true
+	explanations := make([]Explanation, 0, len(policies))
+
+	for i := range policies {
+		rendered, explanation := policies[i].AllowsFraming(currentURL, ancestorOrigins)
+
+		explanation.PolicyIndex = i
+		allowed = allowed && rendered
+		explanations = append(explanations, explanation)
+	}
+
+	return allowed, explanations
+}
+
+/*
+AllowsFraming implements the `frame-ancestors` check from CSP Level 3, § 6.4.2.
+Each ancestor's origin must match the `frame-ancestors` source list, and the
+first one that does not blocks the document. An ancestor with an opaque origin
+(e.g., a sandboxed frame, serialized as `null`) never matches.
+
+allowed is false only when the document would not be rendered, so it is true
+for a report-only policy even when the Explanation is not allowed.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#frame-ancestors-navigation-response
+
+----
+
+  - currentURL (string): The URL of the protected document. May be an empty
+    string, but then `'self'` will never match.
+
+  - ancestorOrigins ([]string): The origins of the documents that embed the
+    protected document, from the parent to the top-level document (the order
+    of `location.ancestorOrigins`). An empty slice means that the document is
+    not framed.
+*/
+func (p *Policy) AllowsFraming(currentURL string, ancestorOrigins []string) (allowed bool, explanation Explanation) {
+	explanation = Explanation{Directive: "frame-ancestors"}
+
+	switch {
+	case p.Delivery == DeliveryMeta:
+		explanation.Allowed = true
+		explanation.Reason = "`frame-ancestors` is ignored in a `<meta>` element, so framing is not restricted"
+
+		return true, explanation
+	case len(p.FrameAncestors) == 0:
+		explanation.Allowed = true
+		explanation.Reason = "the policy has no `frame-ancestors` directive, so framing is not restricted"
+
+		return true, explanation
+	case len(ancestorOrigins) == 0:
+		explanation.Allowed = true
+		explanation.Reason = "the document is not framed"
+
+		return true, explanation
+	}
+
+	explanation.EffectiveDirective = "frame-ancestors"
+	list, _ := mergeSourceList(p, "frame-ancestors")
+
+	// An invalid or opaque currentURL leaves self nil, which `'self'` never
+	// matches.
+	self, _ := ParseOrigin(currentURL)
+
+	for i := range ancestorOrigins {
+		explanation.URL = ancestorOrigins[i]
+
+		origin, err := ParseOrigin(ancestorOrigins[i])
+		if err != nil || origin == nil {
+			explanation.Reason = fmt.Sprintf(
+				"ancestor %d (`%s`) has an opaque origin, which never matches `frame-ancestors`",
+				i, ancestorOrigins[i],
+			)
+
+			return p.Disposition == DispositionReport, explanation
+		}
+
+		u, err := parseRequestURL(origin.String())
+		if err != nil {
+			explanation.Reason = err.Error()
+
+			return p.Disposition == DispositionReport, explanation
+		}
+
+		matched, ok := matchesSourceList(u, &list, self)
+		if !ok {
+			explanation.Reason = fmt.Sprintf(
+				"ancestor %d (`%s`): %s", i, origin.String(), explainMismatch("frame-ancestors", &list, self),
+			)
+
+			return p.Disposition == DispositionReport, explanation
+		}
+
+		explanation.Matched = matched
+	}
+
+	explanation.Allowed = true
+	explanation.Reason = fmt.Sprintf(
+		"every ancestor matched `frame-ancestors`; the top-level document matched `%s`",
+		explanation.Matched.String(),
+	)
+
+	return true, explanation
+}
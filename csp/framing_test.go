@@ -0,0 +1,119 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyAllowsFraming(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CSP         string
+		Opts        []Option
+		Ancestors   []string
+		Allowed     bool
+		Rendered    bool
+		URL         string
+		ReasonSubst string
+	}{
+		"not framed": {
+			CSP:         "frame-ancestors 'none'",
+			Allowed:     true,
+			Rendered:    true,
+			ReasonSubst: "not framed",
+		},
+		"no frame-ancestors": {
+			CSP:         "default-src 'self'",
+			Ancestors:   []string{"https://evil.example"},
+			Allowed:     true,
+			Rendered:    true,
+			ReasonSubst: "no `frame-ancestors` directive",
+		},
+		"'self' chain": {
+			CSP:       "frame-ancestors 'self'",
+			Ancestors: []string{"https://example.com", "https://example.com"},
+			Allowed:   true,
+			Rendered:  true,
+			URL:       "https://example.com",
+		},
+		"partner embeds the widget": {
+			CSP:       "frame-ancestors 'self' https://*.partner.example",
+			Ancestors: []string{"https://shop.partner.example"},
+			Allowed:   true,
+			Rendered:  true,
+			URL:       "https://shop.partner.example",
+		},
+		"top-level document does not match": {
+			CSP:         "frame-ancestors 'self' https://*.partner.example",
+			Ancestors:   []string{"https://shop.partner.example", "https://evil.example"},
+			URL:         "https://evil.example",
+			ReasonSubst: "ancestor 1 (`https://evil.example`): no source expression in `frame-ancestors` matched",
+		},
+		"opaque ancestor": {
+			CSP:         "frame-ancestors *",
+			Ancestors:   []string{"null"},
+			URL:         "null",
+			ReasonSubst: "opaque origin",
+		},
+		"'none'": {
+			CSP:       "frame-ancestors 'none'",
+			Ancestors: []string{"https://example.com"},
+			URL:       "https://example.com",
+		},
+		"report-only": {
+			CSP:       "frame-ancestors 'none'",
+			Opts:      []Option{WithDisposition(DispositionReport)},
+			Ancestors: []string{"https://example.com"},
+			Rendered:  true,
+			URL:       "https://example.com",
+		},
+		"meta": {
+			CSP:         "frame-ancestors 'none'",
+			Opts:        []Option{WithDelivery(DeliveryMeta)},
+			Ancestors:   []string{"https://example.com"},
+			Allowed:     true,
+			Rendered:    true,
+			ReasonSubst: "ignored in a `<meta>` element",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, _ := Parse("https://example.com", "", []string{tc.CSP}, tc.Opts...)
+			rendered, explanation := policies[0].AllowsFraming("https://example.com/widget", tc.Ancestors)
+
+			assert.Equal(tc.Rendered, rendered)
+			assert.Equal(tc.Allowed, explanation.Allowed)
+			assert.Equal(tc.URL, explanation.URL)
+			assert.Contains(explanation.Reason, tc.ReasonSubst)
+		})
+	}
+}
+
+func TestAllowsFraming(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := Parse("", "", []string{"frame-ancestors https://partner.example", "frame-ancestors 'self'"})
+
+	allowed, explanations := AllowsFraming(policies, "https://example.com", []string{"https://partner.example"})
+	assert.False(allowed)
+	assert.Len(explanations, 2)
+	assert.True(explanations[0].Allowed)
+	assert.Equal("https://partner.example", explanations[0].Matched.String())
+	assert.Equal(1, explanations[1].PolicyIndex)
+	assert.False(explanations[1].Allowed)
+}
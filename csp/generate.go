@@ -0,0 +1,183 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// generatedDirectives are the directives that GeneratePolicy chooses from.
+// Deprecated directives (e.g., `child-src`), and directives that need context to
+// validate (e.g., `report-to`), are left out.
+var generatedDirectives = append(
+	slices.DeleteFunc(slices.Clone(sourceListDirectives), func(name string) bool { return name == "child-src" }),
+	"frame-ancestors",
+	"sandbox",
+	"upgrade-insecure-requests",
+	"require-trusted-types-for",
+)
+
+// generatedKeywords are the keyword-sources that GeneratePolicy uses in script
+// directives. Other directives only get `'self'`.
+var generatedKeywords = []string{
+	`'self'`,
+	`'report-sample'`,
+	`'strict-dynamic'`,
+	`'unsafe-eval'`,
+	`'unsafe-hashes'`,
+	`'unsafe-inline'`,
+	`'wasm-unsafe-eval'`,
+}
+
+/*
+GeneratePolicy returns a random policy that parses without errors (warnings and
+notes are possible), for property-testing tools built on top of this package.
+The same seed and complexity always produce the same policy.
+
+----
+
+  - r (*rand.Rand): The source of randomness (e.g.,
+    `rand.New(rand.NewSource(42))`).
+
+  - complexity (int): Roughly how large the policy is: the maximum number of
+    directives, and of values in each directive. Values below 1 are treated as
+    1.
+*/
+func GeneratePolicy(r *rand.Rand, complexity int) string {
+	complexity = max(complexity, 1)
+	count := min(1+r.Intn(complexity), len(generatedDirectives))
+	directives := make([]string, 0, count)
+
+	for _, i := range r.Perm(len(generatedDirectives))[:count] {
+		name := generatedDirectives[i]
+		values := generateValues(r, name, complexity)
+
+		directives = append(directives, strings.TrimSpace(name+" "+strings.Join(values, " ")))
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// generateValues returns random values for the directive.
+func generateValues(r *rand.Rand, name string, complexity int) []string {
+	switch name {
+	case "upgrade-insecure-requests":
+		return nil
+	case "require-trusted-types-for":
+		return []string{`'script'`}
+	case "sandbox":
+		// Without allow-same-origin, `'self'` in other directives is an error.
+		values := []string{"allow-same-origin"}
+
+		tokens := standardSandboxTokens
+
+		for _, i := range r.Perm(len(tokens))[:r.Intn(min(complexity, len(tokens))+1)] {
+			if tokens[i] != "allow-same-origin" {
+				values = append(values, tokens[i])
+			}
+		}
+
+		return values
+	}
+
+	if r.Intn(8) == 0 {
+		return []string{`'none'`}
+	}
+
+	values := []string{}
+
+	for range 1 + r.Intn(complexity) {
+		var value string
+
+		switch n := r.Intn(10); {
+		case n < 4:
+			value = generateHostSource(r)
+		case n < 5:
+			value = []string{"https:", "data:", "blob:", "wss:"}[r.Intn(4)]
+		case name == "frame-ancestors":
+			value = generateHostSource(r)
+		case n < 7 && (name == "default-src" || strings.HasPrefix(name, "script-src")):
+			value = generatedKeywords[r.Intn(len(generatedKeywords))]
+		case n < 7:
+			value = `'self'`
+		case n < 8:
+			value = fmt.Sprintf("'nonce-%s'", generateBase64(r, 16))
+		default:
+			value = fmt.Sprintf("'sha256-%s'", generateBase64(r, 32))
+		}
+
+		if !slicesContainsFold(values, value) {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// generateHostSource returns a random host-source (e.g.,
+// `https://*.abcd.example:8443/static`).
+func generateHostSource(r *rand.Rand) string {
+	var b strings.Builder
+
+	if r.Intn(2) == 0 {
+		b.WriteString("https://")
+	}
+
+	if r.Intn(4) == 0 {
+		b.WriteString("*.")
+	}
+
+	for range 3 + r.Intn(6) {
+		b.WriteByte(byte('a' + r.Intn(26)))
+	}
+
+	b.WriteString(".example")
+
+	if r.Intn(4) == 0 {
+		fmt.Fprintf(&b, ":%d", 1024+r.Intn(64000))
+	}
+
+	if r.Intn(4) == 0 {
+		b.WriteString([]string{"/static", "/js/app.js", "/a%20b"}[r.Intn(3)])
+	}
+
+	return b.String()
+}
+
+// generateBase64 returns n random bytes, base64-encoded.
+func generateBase64(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+
+	for i := range b {
+		b[i] = byte(r.Intn(256))
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// slicesContainsFold reports whether the list contains the value, ignoring case.
+func slicesContainsFold(list []string, value string) bool {
+	for i := range list {
+		if strings.EqualFold(list[i], value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(
+		GeneratePolicy(rand.New(rand.NewSource(42)), 5),
+		GeneratePolicy(rand.New(rand.NewSource(42)), 5),
+	)
+	assert.NotEqual(
+		GeneratePolicy(rand.New(rand.NewSource(42)), 5),
+		GeneratePolicy(rand.New(rand.NewSource(43)), 5),
+	)
+
+	for seed := int64(0); seed < 20; seed++ {
+		policy := GeneratePolicy(rand.New(rand.NewSource(seed)), 0)
+		assert.NotContains(policy, ";", "complexity 0 should produce a single directive")
+	}
+
+	for seed := int64(0); seed < 500; seed++ {
+		policy := GeneratePolicy(rand.New(rand.NewSource(seed)), int(seed%10)+1)
+
+		policies, err := Parse("", "", []string{policy})
+		assert.Len(policies, 1)
+
+		if merr, ok := err.(*multierror.Error); ok {
+			for _, e := range merr.Errors {
+				assert.NotEqual(SeverityError, SeverityOf(e), "seed %d: %s\n%v", seed, policy, e)
+			}
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for seed := int64(0); seed < 50; seed++ {
+		f.Add(GeneratePolicy(rand.New(rand.NewSource(seed)), int(seed%10)+1))
+	}
+
+	f.Fuzz(func(t *testing.T, policy string) {
+		// Commas separate serialized policies, so there may be more than one.
+		policies, _ := Parse("https://example.com", "", []string{policy})
+
+		for i := range policies {
+			if !strings.Contains(policy, policies[i].Raw) {
+				t.Fatalf("Raw %q is not part of %q", policies[i].Raw, policy)
+			}
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// HeaderCSP is the name of the header that delivers enforced policies.
+	HeaderCSP = "Content-Security-Policy"
+
+	// HeaderCSPReportOnly is the name of the header that delivers report-only
+	// policies.
+	HeaderCSPReportOnly = "Content-Security-Policy-Report-Only"
+
+	// HeaderReportingEndpoints is the name of the header that defines the
+	// endpoints used by the `report-to` directive.
+	HeaderReportingEndpoints = "Reporting-Endpoints"
+
+	// HeaderXCSP is the prefixed header that Firefox and Internet Explorer used
+	// before the standard header existed.
+	HeaderXCSP = "X-Content-Security-Policy"
+
+	// HeaderXWebKitCSP is the prefixed header that Chrome and Safari used before
+	// the standard header existed.
+	HeaderXWebKitCSP = "X-WebKit-CSP"
+)
+
+// legacyHeaders are the obsolete, prefixed names of HeaderCSP.
+var legacyHeaders = []string{HeaderXCSP, HeaderXWebKitCSP}
+
+/*
+ParseHeader finds the `Content-Security-Policy`,
+`Content-Security-Policy-Report-Only`, and `Reporting-Endpoints` headers (including
+multiple occurrences of each) and parses every policy they contain. Enforced
+policies are returned first, followed by report-only policies; each Policy's
+Disposition says which header it came from.
+
+Policies in the obsolete `X-Content-Security-Policy` and `X-WebKit-CSP` headers
+are parsed as enforced policies and returned last, with a diagnostic
+recommending the standard header.
+
+Source expressions that another enforced policy narrows or makes irrelevant
+(e.g., anything next to `'none'` in another policy) are reported. See Combine
+for the combined behavior of the policies.
+
+----
+
+  - currentURL (string): The URL of the response. May be an empty string, but
+    then `'self'` sources cannot be validated.
+
+  - header (http.Header): The response headers.
+
+  - opts (...Option): Optional settings that change how the policies are
+    parsed. WithDisposition is ignored, because the header name determines the
+    disposition.
+*/
+func ParseHeader(currentURL string, header http.Header, opts ...Option) ([]*Policy, error) {
+	return ParseHeaderContext(context.Background(), currentURL, header, opts...)
+}
+
+/*
+ParseHeaderContext is like ParseHeader, but stops early when the context is
+canceled or its deadline is exceeded.
+
+See ParseHeader for the remaining parameters.
+*/
+func ParseHeaderContext(
+	ctx context.Context,
+	currentURL string,
+	header http.Header,
+	opts ...Option,
+) ([]*Policy, error) {
+	var errs *multierror.Error
+
+	// Multiple Reporting-Endpoints headers are combined into one list.
+	reportingEndpoints := strings.Join(headerValues(header, HeaderReportingEndpoints), ", ")
+
+	for _, name := range []string{HeaderCSP, HeaderCSPReportOnly, HeaderReportingEndpoints} {
+		if casings := headerCasings(header, name); len(casings) > 1 {
+			errs = multierror.Append(errs, newConfig(opts).filterSeverity([]error{
+				fmt.Errorf(errCSP0015, name, strings.Join(casings, "`, `")),
+			})...)
+		}
+	}
+
+	enforced, err := ParseContext(
+		ctx,
+		currentURL,
+		reportingEndpoints,
+		headerValues(header, HeaderCSP),
+		append(opts, WithDisposition(DispositionEnforce))...,
+	)
+	errs = multierror.Append(errs, err)
+
+	// Report-only diagnostics continue the policy numbering of the enforced
+	// policies, so that PolicyIndexOf matches the index in the returned slice.
+	reportOnly, err := ParseContext(
+		ctx,
+		currentURL,
+		reportingEndpoints,
+		headerValues(header, HeaderCSPReportOnly),
+		append(opts, WithDisposition(DispositionReport), withPolicyOffset(len(enforced), true))...,
+	)
+	errs = multierror.Append(errs, err)
+	policies := append(enforced, reportOnly...)
+
+	for _, name := range legacyHeaders {
+		values := headerValues(header, name)
+		if len(values) == 0 {
+			continue
+		}
+
+		errs = multierror.Append(errs, newConfig(opts).filterSeverity([]error{fmt.Errorf(errCSP0013, name)})...)
+
+		legacy, err := ParseContext(
+			ctx,
+			currentURL,
+			reportingEndpoints,
+			values,
+			append(opts, WithDisposition(DispositionEnforce), withPolicyOffset(len(policies), true))...,
+		)
+		errs = multierror.Append(errs, err)
+		policies = append(policies, legacy...)
+	}
+
+	errs = multierror.Append(errs, newConfig(opts).filterSeverity(checkShadowedSources(policies))...)
+
+	return policies, errs.ErrorOrNil()
+}
+
+/*
+ParseResponse is like ParseHeader, but takes the headers from the response, and
+uses the URL of the request that produced it as the current URL.
+
+Policies found in the trailer of the response (e.g., HTTP/2 trailers) are parsed
+so that they can be validated, and returned last with DeliveryTrailer, along
+with a diagnostic that browsers ignore them. Trailers are only available after
+the body has been read to the end.
+
+----
+
+  - resp (*http.Response): The response. If resp.Request is nil, the current URL
+    is an empty string.
+
+  - opts (...Option): Optional settings that change how the policies are
+    parsed.
+*/
+func ParseResponse(resp *http.Response, opts ...Option) ([]*Policy, error) {
+	ctx := context.Background()
+	currentURL := ""
+
+	if resp.Request != nil {
+		ctx = resp.Request.Context()
+
+		if resp.Request.URL != nil {
+			currentURL = resp.Request.URL.String()
+		}
+	}
+
+	policies, err := ParseHeaderContext(ctx, currentURL, resp.Header, opts...)
+	if len(resp.Trailer) == 0 {
+		return policies, err
+	}
+
+	errs := multierror.Append(nil, err)
+	reportingEndpoints := strings.Join(headerValues(resp.Header, HeaderReportingEndpoints), ", ")
+
+	for _, t := range []struct {
+		name        string
+		disposition Disposition
+	}{
+		{HeaderCSP, DispositionEnforce},
+		{HeaderCSPReportOnly, DispositionReport},
+	} {
+		values := headerValues(resp.Trailer, t.name)
+		if len(values) == 0 {
+			continue
+		}
+
+		errs = multierror.Append(errs, newConfig(opts).filterSeverity([]error{fmt.Errorf(errCSP0016, t.name)})...)
+
+		trailer, err := ParseContext(
+			ctx,
+			currentURL,
+			reportingEndpoints,
+			values,
+			append(
+				opts,
+				WithDisposition(t.disposition),
+				WithDelivery(DeliveryTrailer),
+				withPolicyOffset(len(policies), true),
+			)...,
+		)
+		errs = multierror.Append(errs, err)
+		policies = append(policies, trailer...)
+	}
+
+	return policies, errs.ErrorOrNil()
+}
+
+/*
+headerValues returns the values of every field with the name, compared
+case-insensitively. Keys in canonical form (as set by net/http) come first,
+followed by other casings in sorted order. This handles headers that were built
+from raw captures without canonicalization.
+
+----
+
+  - header (http.Header): The header fields.
+
+  - name (string): The name of the field.
+*/
+func headerValues(header http.Header, name string) []string {
+	values := []string{}
+
+	for _, key := range headerCasings(header, name) {
+		values = append(values, header[key]...)
+	}
+
+	return values
+}
+
+// headerCasings returns the keys of the header that match the name
+// case-insensitively, with the canonical key first.
+func headerCasings(header http.Header, name string) []string {
+	canonical := http.CanonicalHeaderKey(name)
+	keys := []string{}
+
+	for key := range header {
+		if key != canonical && strings.EqualFold(key, name) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	if _, ok := header[canonical]; ok {
+		keys = append([]string{canonical}, keys...)
+	}
+
+	return keys
+}
@@ -0,0 +1,150 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Add("Content-Security-Policy", "default-src 'self'")
+	header.Add("Content-Security-Policy", "img-src https://example.com, script-src foo'")
+	header.Add("Content-Security-Policy-Report-Only", "script-src 'none'; report-to main")
+	header.Add("Reporting-Endpoints", `main="https://example.com/reports"`)
+
+	policies, err := ParseHeader("", header)
+	assert.Len(policies, 4)
+
+	assert.Equal(DispositionEnforce, policies[0].Disposition)
+	assert.Equal(DispositionEnforce, policies[2].Disposition)
+	assert.Equal(DispositionReport, policies[3].Disposition)
+	assert.Len(policies[3].ReportTo, 1)
+
+	merr, ok := err.(*multierror.Error)
+	assert.True(ok)
+
+	count := 0
+
+	for i := range merr.Errors {
+		if strings.Contains(merr.Errors[i].Error(), "[CSP-0001]") {
+			count++
+		}
+
+		// The Reporting-Endpoints header was found, so `report-to` is validated.
+		assert.NotContains(merr.Errors[i].Error(), "[CSP-0002]")
+
+		if strings.Contains(merr.Errors[i].Error(), "[CSP-0100]") {
+			assert.Equal(2, PolicyIndexOf(merr.Errors[i]))
+		}
+	}
+
+	assert.Equal(1, count)
+}
+
+func TestParseHeaderLegacy(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Add("Content-Security-Policy", "default-src 'self'")
+	header.Add("X-Content-Security-Policy", "default-src 'self'; img-src *")
+	header.Add("X-WebKit-CSP", "script-src 'none'")
+
+	policies, err := ParseHeader("https://example.com", header)
+	assert.Len(policies, 3)
+
+	assert.Equal(DispositionEnforce, policies[1].Disposition)
+	assert.Len(policies[1].ImageSource, 1)
+	assert.Len(policies[2].ScriptSource, 1)
+
+	assert.ErrorContains(err, "header `X-Content-Security-Policy` is obsolete")
+	assert.ErrorContains(err, "header `X-WebKit-CSP` is obsolete")
+
+	_, err = ParseHeader("https://example.com", header, MinSeverity(SeverityError))
+	assert.NoError(err)
+}
+
+func TestParseResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	req, _ := http.NewRequest(http.MethodGet, "file:///index.html", http.NoBody)
+	resp := &http.Response{
+		Header:  http.Header{"Content-Security-Policy": []string{"script-src 'self'"}},
+		Request: req,
+	}
+
+	policies, err := ParseResponse(resp)
+	assert.Len(policies, 1)
+
+	// The current URL came from the request, so CSP-0001 is not reported, and
+	// 'self' is checked against the opaque origin of the file: URL.
+	assert.NotContains(err.Error(), "[CSP-0001]")
+	assert.ErrorContains(err, "because `file:///index.html` has an opaque origin")
+}
+
+func TestParseHeaderCasings(t *testing.T) {
+	assert := assert.New(t)
+
+	// A header built from a raw capture, without canonicalization.
+	header := http.Header{
+		"Content-Security-Policy": []string{"default-src 'self'"},
+		"content-security-policy": []string{"img-src *"},
+		"CONTENT-SECURITY-POLICY": []string{"script-src 'none'"},
+	}
+
+	policies, err := ParseHeader("https://example.com", header)
+	assert.Len(policies, 3)
+
+	assert.Len(policies[0].DefaultSource, 1)
+	assert.Len(policies[1].ScriptSource, 1)
+	assert.Len(policies[2].ImageSource, 1)
+
+	assert.ErrorContains(
+		err,
+		"header `Content-Security-Policy` appears with several casings (`Content-Security-Policy`, "+
+			"`CONTENT-SECURITY-POLICY`, `content-security-policy`)",
+	)
+}
+
+func TestParseResponseTrailer(t *testing.T) {
+	assert := assert.New(t)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", http.NoBody)
+	resp := &http.Response{
+		Header:  http.Header{"Content-Security-Policy": []string{"script-src 'self'"}},
+		Trailer: http.Header{"content-security-policy": []string{"script-src 'none'"}},
+		Request: req,
+	}
+
+	policies, err := ParseResponse(resp)
+	assert.Len(policies, 2)
+
+	assert.Equal(DeliveryHeader, policies[0].Delivery)
+	assert.Equal(DeliveryTrailer, policies[1].Delivery)
+	assert.ErrorContains(err, "header `Content-Security-Policy` was sent in the trailer of the response")
+
+	// The trailer policy does not block anything.
+	explanation, err := Combine(policies).Explain("https://example.com/", "script-src-elem", "https://example.com/a.js")
+	assert.NoError(err)
+	assert.True(explanation.Allowed)
+	assert.Equal([]int{0}, Combine(policies).Enforced)
+}
@@ -0,0 +1,117 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+// HostStrictness controls how strictly the host-part of a host-source is
+// validated.
+type HostStrictness string
+
+const (
+	// HostStrictnessDefault accepts the host-parts that this package has always
+	// accepted. It is the default.
+	HostStrictnessDefault HostStrictness = "default"
+
+	// HostStrictnessSpec only accepts host-parts that follow the CSP grammar:
+	// labels of ALPHA, DIGIT, and `-`, an optional leading `*.`, and an optional
+	// trailing `.`.
+	HostStrictnessSpec HostStrictness = "spec"
+
+	// HostStrictnessLenient also accepts underscores, which are common in
+	// real-world headers, with a warning.
+	HostStrictnessLenient HostStrictness = "lenient"
+)
+
+// reSpecHostPart matches a host-part that follows the CSP grammar.
+//
+//	host-part = "*" / [ "*." ] 1*host-char *( "." 1*host-char ) [ "." ]
+//	host-char = ALPHA / DIGIT / "-"
+var reSpecHostPart = regexp.MustCompile(`^(\*|(\*\.)?[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*\.?)$`)
+
+// WithHostStrictness sets how strictly the host-part of each host-source is
+// validated. The default is HostStrictnessDefault.
+func WithHostStrictness(s HostStrictness) Option {
+	return func(c *config) {
+		c.hostStrictness = s
+	}
+}
+
+/*
+isHostSource checks whether or not the string is a host-source at the
+configured HostStrictness. A trailing dot in the host-part (an absolute DNS
+name, e.g., `example.com.`) is accepted at every HostStrictness, and removed by
+the parser. Values that pass are checked further by hostPartErrors.
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func (c *config) isHostSource(s string) bool {
+	s = trimHostPartDot(s)
+
+	if c.hostStrictness == HostStrictnessLenient {
+		return isHostSource(s) || isHostSource(strings.ReplaceAll(s, "_", "-"))
+	}
+
+	return isHostSource(s)
+}
+
+/*
+hostPartErrors returns the diagnostics for the host-part of a host-source at the
+configured HostStrictness. ok is false if the host-source must be dropped.
+
+----
+
+  - key (string): The name of the directive.
+
+  - value (string): The host-source, as written in the policy.
+
+  - host (string): The host-source, converted to ASCII.
+*/
+func (c *config) hostPartErrors(key, value, host string) (ok bool, errs []error) {
+	_, hostPart, _, _ := hostsource.Split(host)
+
+	switch c.hostStrictness {
+	case HostStrictnessSpec:
+		if !reSpecHostPart.MatchString(hostPart) {
+			return false, []error{fmt.Errorf(errCSP0116, key, value)}
+		}
+	case HostStrictnessLenient:
+		if strings.Contains(hostPart, "_") {
+			errs = append(errs, fmt.Errorf(errCSP0117, key, value))
+		}
+	}
+
+	return true, errs
+}
+
+// trimHostPartDot removes a single trailing `.` from the host-part of a
+// host-source. A host-part that is only a dot or a wildcard (e.g., `*.`) is
+// left as it is.
+func trimHostPartDot(s string) string {
+	scheme, host, port, path := hostsource.Split(s)
+	if !strings.HasSuffix(host, ".") || host == "." || host == "*." {
+		return s
+	}
+
+	return hostsource.Join(scheme, strings.TrimSuffix(host, "."), port, path)
+}
@@ -0,0 +1,162 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostStrictness(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value      string
+		strictness HostStrictness
+		wantHost   string
+		wantCodes  []string
+	}{
+		"default: plain host": {
+			value:      "cdn.example.com",
+			strictness: HostStrictnessDefault,
+			wantHost:   "cdn.example.com",
+		},
+		"default: underscore is invalid": {
+			value:      "_dmarc.example.com",
+			strictness: HostStrictnessDefault,
+			wantCodes:  []string{"[CSP-0100]"},
+		},
+		"default: trailing dot is removed": {
+			value:      "https://*.example.com./path",
+			strictness: HostStrictnessDefault,
+			wantHost:   "https://*.example.com/path",
+			wantCodes: []string{
+				"host-source `https://*.example.com./path` has a trailing dot in its host-part, which names the " +
+					"same host as `https://*.example.com/path`; the dot was removed [CSP-0118]",
+			},
+		},
+		"default: a wildcard with a trailing dot is invalid": {
+			value:      "*.",
+			strictness: HostStrictnessDefault,
+			wantCodes:  []string{"[CSP-0100]"},
+		},
+		"default: leading dot is accepted": {
+			value:      ".example.com",
+			strictness: HostStrictnessDefault,
+			wantHost:   ".example.com",
+		},
+		"spec: plain host": {
+			value:      "https://*.example.com:443/path",
+			strictness: HostStrictnessSpec,
+			wantHost:   "https://*.example.com:443/path",
+		},
+		"spec: trailing dot is in the grammar": {
+			value:      "example.com.",
+			strictness: HostStrictnessSpec,
+			wantHost:   "example.com",
+			wantCodes:  []string{"[CSP-0118]"},
+		},
+		"spec: leading dot is rejected": {
+			value:      ".example.com",
+			strictness: HostStrictnessSpec,
+			wantCodes:  []string{"[CSP-0116]"},
+		},
+		"spec: wildcard inside a label is rejected": {
+			value:      "*example.com",
+			strictness: HostStrictnessSpec,
+			wantCodes:  []string{"[CSP-0116]"},
+		},
+		"spec: underscore is invalid": {
+			value:      "_dmarc.example.com",
+			strictness: HostStrictnessSpec,
+			wantCodes:  []string{"[CSP-0100]"},
+		},
+		"lenient: underscore": {
+			value:      "my_cdn.example.com",
+			strictness: HostStrictnessLenient,
+			wantHost:   "my_cdn.example.com",
+			wantCodes:  []string{"[CSP-0117]"},
+		},
+		"lenient: trailing dot with port": {
+			value:      "https://example.com.:8443",
+			strictness: HostStrictnessLenient,
+			wantHost:   "https://example.com:8443",
+			wantCodes:  []string{"[CSP-0118]"},
+		},
+		"lenient: both": {
+			value:      "my_cdn.example.com.",
+			strictness: HostStrictnessLenient,
+			wantHost:   "my_cdn.example.com",
+			wantCodes:  []string{"[CSP-0117]", "[CSP-0118]"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, err := Parse("https://example.com", "", []string{"img-src " + tc.value + "; report-to main"},
+				WithHostStrictness(tc.strictness), WithoutReportingValidation())
+
+			var hosts []string
+
+			for _, expr := range policies[0].ImageSource[0].SourceExprs {
+				hosts = append(hosts, expr.HostSource)
+			}
+
+			if tc.wantHost == "" {
+				assert.Empty(hosts)
+			} else {
+				assert.Equal([]string{tc.wantHost}, hosts)
+			}
+
+			if len(tc.wantCodes) == 0 {
+				assert.NoError(err)
+			}
+
+			for _, code := range tc.wantCodes {
+				assert.ErrorContains(err, code)
+			}
+		})
+	}
+}
+
+func TestTrailingDot(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, err := Parse("https://example.com", "", []string{
+		"img-src cdn.example.com. https://bücher.example.; frame-ancestors https://partner.example.com.",
+	})
+	assert.ErrorContains(err, "[CSP-0108]")
+	assert.NotContains(err.Error(), "[CSP-0912]")
+	assert.ErrorContains(err, "host-source `cdn.example.com.` has a trailing dot")
+
+	assert.Equal([]SourceExpr{
+		{HostSource: "cdn.example.com", TrailingDot: true},
+		{
+			HostSource:        "https://xn--bcher-kva.example",
+			UnicodeHostSource: "https://bücher.example.",
+			TrailingDot:       true,
+		},
+	}, policies[0].ImageSource[0].SourceExprs)
+	assert.Equal(
+		[]AncestorExpr{{HostSource: "https://partner.example.com", TrailingDot: true}},
+		policies[0].FrameAncestors[0].AncestorExprs,
+	)
+
+	// The absolute and relative DNS names of a host match each other.
+	for _, target := range []string{"https://cdn.example.com/a.png", "https://cdn.example.com./a.png"} {
+		explanation, err := policies[0].Explain("https://example.com", "img-src", target)
+		assert.NoError(err)
+		assert.True(explanation.Allowed, target)
+	}
+}
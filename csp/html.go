@@ -0,0 +1,110 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/net/html"
+)
+
+/*
+ParseHTML finds the `<meta http-equiv="Content-Security-Policy">` elements in an
+HTML document and parses the policies they deliver. Browsers only honor these
+elements inside `<head>`; elements elsewhere are reported and skipped.
+
+----
+
+  - currentURL (string): The URL of the document. May be an empty string, but
+    then `'self'` sources cannot be validated.
+
+  - r (io.Reader): The HTML document.
+
+  - opts (...Option): Optional settings that change how the policies are
+    parsed.
+*/
+func ParseHTML(currentURL string, r io.Reader, opts ...Option) ([]*Policy, error) {
+	return ParseHTMLContext(context.Background(), currentURL, r, opts...)
+}
+
+/*
+ParseHTMLContext is like ParseHTML, but stops early when the context is
+canceled or its deadline is exceeded.
+
+See ParseHTML for the remaining parameters.
+*/
+func ParseHTMLContext(ctx context.Context, currentURL string, r io.Reader, opts ...Option) ([]*Policy, error) {
+	var errs *multierror.Error
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, ignored := collectMetaPolicies(doc, false, nil, 0)
+
+	if ignored > 0 {
+		errs = multierror.Append(errs, newConfig(opts).filterSeverity([]error{fmt.Errorf(errCSP0011, ignored)})...)
+	}
+
+	// There is no Reporting-Endpoints header for a document on disk, and
+	// `report-to` is ignored in <meta> elements anyway.
+	parsed, err := ParseContext(ctx, currentURL, "", policies, append(opts, WithDelivery(DeliveryMeta))...)
+	errs = multierror.Append(errs, err)
+
+	return parsed, errs.ErrorOrNil()
+}
+
+/*
+collectMetaPolicies walks the document and returns the `content` of each
+`<meta http-equiv="Content-Security-Policy">` element inside `<head>`, in
+document order, along with the number of such elements outside `<head>`.
+
+----
+
+  - n (*html.Node): The node to walk.
+
+  - inHead (bool): Whether or not n is inside `<head>`.
+
+  - policies ([]string): The policies found so far.
+
+  - ignored (int): The number of elements outside `<head>` found so far.
+*/
+func collectMetaPolicies(n *html.Node, inHead bool, policies []string, ignored int) ([]string, int) {
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "head":
+			inHead = true
+		case "meta":
+			if strings.EqualFold(strings.TrimSpace(attr(n, "http-equiv")), HeaderCSP) && hasAttr(n, "content") {
+				if inHead {
+					policies = append(policies, attr(n, "content"))
+				} else {
+					ignored++
+				}
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		policies, ignored = collectMetaPolicies(c, inHead, policies, ignored)
+	}
+
+	return policies, ignored
+}
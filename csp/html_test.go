@@ -0,0 +1,47 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	doc := `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta http-equiv="content-security-policy" content="default-src 'self'">
+	<meta http-equiv="Content-Security-Policy" content="img-src https://example.com; frame-ancestors 'none'">
+</head>
+<body>
+	<meta http-equiv="Content-Security-Policy" content="script-src *">
+</body>
+</html>`
+
+	policies, err := ParseHTML("https://example.com", strings.NewReader(doc), MinSeverity(SeverityWarning))
+	assert.Len(policies, 2)
+
+	assert.Equal([]SourceExpr{{KeywordSource: "'self'"}}, policies[0].DefaultSource[0].SourceExprs)
+	assert.Equal([]SourceExpr{{HostSource: "https://example.com"}}, policies[1].ImageSource[0].SourceExprs)
+	assert.Equal(DeliveryMeta, policies[0].Delivery)
+	assert.ErrorContains(err, "directive `frame-ancestors` is ignored in a policy delivered by a `<meta>` element")
+	assert.ErrorContains(err, "found 1 `<meta http-equiv=\"Content-Security-Policy\">` element(s) outside of `<head>`")
+}
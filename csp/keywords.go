@@ -0,0 +1,119 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// KeywordOptions describes a keyword-source registered with RegisterKeyword.
+type KeywordOptions struct {
+	// Directives lists the directives where the keyword has meaning (e.g.,
+	// `script-src`). Using it in any other directive produces a warning. If
+	// empty, the keyword may be used in every directive that takes a source
+	// list.
+	Directives []string
+}
+
+var (
+	// reKeyword matches a well-formed keyword-source.
+	reKeyword = regexp.MustCompile(`^'[a-zA-Z0-9][a-zA-Z0-9-]*'$`)
+
+	registeredKeywordsMu sync.RWMutex
+
+	// registeredKeywords holds the keywords added with RegisterKeyword, by their
+	// lowercase form.
+	registeredKeywords = map[string]KeywordOptions{}
+)
+
+/*
+RegisterKeyword adds a keyword-source that the parser recognizes, in addition to
+the ones that it knows about (e.g., for testing an origin trial or a
+vendor-prefixed keyword). Registered keywords are parsed into
+SourceExpr.KeywordSource, are written back unchanged, and are listed by
+Capabilities. Registration applies to every later call to Parse in the process,
+and is safe for concurrent use.
+
+----
+
+  - keyword (string): The keyword, including its single quotes (e.g.,
+    `'my-vendor-keyword'`). Keywords are matched case-insensitively.
+
+  - opts (KeywordOptions): Where the keyword has meaning.
+*/
+func RegisterKeyword(keyword string, opts KeywordOptions) error {
+	lower := strings.ToLower(keyword)
+
+	switch {
+	case !reKeyword.MatchString(keyword),
+		lower == `'none'`,
+		strings.HasPrefix(lower, "'nonce-"),
+		strings.HasPrefix(lower, "'sha256-"),
+		strings.HasPrefix(lower, "'sha384-"),
+		strings.HasPrefix(lower, "'sha512-"):
+		return fmt.Errorf(errCSP0018, keyword)
+	case isKeywordSource(keyword):
+		return fmt.Errorf(errCSP0019, keyword)
+	}
+
+	directives := make([]string, 0, len(opts.Directives))
+
+	for _, d := range opts.Directives {
+		if !slices.Contains(sourceListDirectives, strings.ToLower(d)) {
+			return fmt.Errorf(errCSP0020, d)
+		}
+
+		directives = append(directives, strings.ToLower(d))
+	}
+
+	registeredKeywordsMu.Lock()
+	defer registeredKeywordsMu.Unlock()
+
+	registeredKeywords[lower] = KeywordOptions{Directives: directives}
+
+	return nil
+}
+
+// registeredKeyword returns the options of a keyword added with
+// RegisterKeyword.
+func registeredKeyword(s string) (KeywordOptions, bool) {
+	registeredKeywordsMu.RLock()
+	defer registeredKeywordsMu.RUnlock()
+
+	opts, ok := registeredKeywords[strings.ToLower(s)]
+
+	return opts, ok
+}
+
+// allKeywords returns the built-in keyword-sources, followed by the registered
+// ones in sorted order.
+func allKeywords() []string {
+	registeredKeywordsMu.RLock()
+	defer registeredKeywordsMu.RUnlock()
+
+	registered := make([]string, 0, len(registeredKeywords))
+
+	for k := range registeredKeywords {
+		registered = append(registered, k)
+	}
+
+	slices.Sort(registered)
+
+	return append(slices.Clone(keywordSources), registered...)
+}
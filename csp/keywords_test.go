@@ -0,0 +1,77 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterKeyword(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Cleanup(func() {
+		registeredKeywordsMu.Lock()
+		defer registeredKeywordsMu.Unlock()
+
+		delete(registeredKeywords, "'vendor-anywhere'")
+		delete(registeredKeywords, "'vendor-scripts'")
+	})
+
+	assert.NoError(RegisterKeyword("'vendor-anywhere'", KeywordOptions{}))
+	assert.NoError(RegisterKeyword("'Vendor-Scripts'", KeywordOptions{Directives: []string{"Script-Src"}}))
+
+	for name, tc := range map[string]struct {
+		Keyword     string
+		Directives  []string
+		ErrorSubstr string
+	}{
+		"unquoted":       {Keyword: "vendor", ErrorSubstr: "[CSP-0018]"},
+		"none":           {Keyword: "'NONE'", ErrorSubstr: "[CSP-0018]"},
+		"nonce-like":     {Keyword: "'nonce-abc'", ErrorSubstr: "[CSP-0018]"},
+		"built-in":       {Keyword: "'SELF'", ErrorSubstr: "keyword `'SELF'` is already recognized [CSP-0019]"},
+		"registered":     {Keyword: "'vendor-anywhere'", ErrorSubstr: "[CSP-0019]"},
+		"not a src list": {Keyword: "'vendor-x'", Directives: []string{"sandbox"}, ErrorSubstr: "[CSP-0020]"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorContains(
+				RegisterKeyword(tc.Keyword, KeywordOptions{Directives: tc.Directives}),
+				tc.ErrorSubstr,
+			)
+		})
+	}
+
+	policies, err := Parse(
+		"",
+		"",
+		[]string{"script-src 'vendor-scripts' 'vendor-anywhere'; img-src 'VENDOR-ANYWHERE' 'vendor-scripts'"},
+		WithoutSelfValidation(),
+		WithoutReportingValidation(),
+	)
+
+	assert.Equal([]SourceExpr{
+		{KeywordSource: "'vendor-scripts'"},
+		{KeywordSource: "'vendor-anywhere'"},
+	}, policies[0].ScriptSource[0].SourceExprs)
+	assert.Equal("script-src 'vendor-scripts' 'vendor-anywhere'; img-src 'VENDOR-ANYWHERE' 'vendor-scripts'",
+		policies[0].Raw)
+
+	assert.ErrorContains(err, "[WARN] directive `img-src`: keyword `'vendor-scripts'` was registered for "+
+		"`script-src` only, and is ignored here [CSP-0113]")
+	assert.NotContains(err.Error(), "[CSP-0100]")
+
+	assert.Contains(Capabilities().Keywords, "'vendor-scripts'")
+}
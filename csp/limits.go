@@ -0,0 +1,46 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+// Limits guards Parse against pathological inputs (e.g., multi-megabyte crafted
+// headers). A limit of zero means "no limit".
+type Limits struct {
+	// MaxPolicyLength is the maximum length of a single serialized policy, in
+	// bytes. Longer policies are not parsed.
+	MaxPolicyLength int `json:"maxPolicyLength"`
+
+	// MaxDirectives is the maximum number of directives in a single policy.
+	// Directives beyond the limit are not parsed.
+	MaxDirectives int `json:"maxDirectives"`
+
+	// MaxSourcesPerDirective is the maximum number of values in a single
+	// directive. Values beyond the limit are not parsed.
+	MaxSourcesPerDirective int `json:"maxSourcesPerDirective"`
+}
+
+// DefaultLimits are the limits used when WithLimits is not passed. They are far
+// larger than any real-world policy.
+var DefaultLimits = Limits{
+	MaxPolicyLength:        256 * 1024,
+	MaxDirectives:          256,
+	MaxSourcesPerDirective: 2048,
+}
+
+// WithLimits replaces DefaultLimits for a call to Parse.
+func WithLimits(limits Limits) Option {
+	return func(c *config) {
+		c.limits = limits
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+// Directive describes a directive that allows a given resource. When the
+// directive is absent from the policy and fallback applies, EffectiveDirective
+// is the directive whose source list was used instead. When neither the
+// directive nor any of its fallbacks are present, EffectiveDirective is empty
+// and the resource is allowed because the policy does not restrict it.
+type Directive struct {
+	Name               string      `json:"name"`
+	EffectiveDirective string      `json:"effectiveDirective,omitempty"`
+	Matched            *SourceExpr `json:"matched,omitempty"`
+}
+
+/*
+WhereAllowed scans every directive in the policy (with fallback applied) and
+reports each one that would permit the given resource. This answers the first
+question asked during incident response: "where could this origin have been
+loaded from?"
+
+`'self'` matches the origin of the protected document (Policy.Origin, from the
+currentURL passed to Parse). When the policy has no origin, `'self'` is never
+considered a match. Host-sources and scheme-sources in `script-src` and
+`script-src-elem` are not considered a match when the list contains
+`'strict-dynamic'`, because browsers ignore them. A target that cannot be parsed
+as a URL is allowed nowhere, so an empty slice is returned.
+
+----
+
+  - target (string): An absolute URL (e.g., `https://evil.cdn.com/x.js`), a bare
+    host (e.g., `evil.cdn.com`), or a scheme (e.g., `data:`).
+*/
+func (p *Policy) WhereAllowed(target string) []Directive {
+	directives := []Directive{}
+
+	u, err := parseRequestURL(normalizeLookupTarget(target))
+	if err != nil {
+		return directives
+	}
+
+	for _, name := range sourceListDirectives {
+		effective, list, ok := p.effectiveSourceList(name)
+		if !ok {
+			directives = append(directives, Directive{Name: name})
+
+			continue
+		}
+
+		if ignoresURLSources(name, list) {
+			continue
+		}
+
+		if matched, ok := matchesSourceList(u, list, p.Origin); ok {
+			directives = append(directives, Directive{
+				Name:               name,
+				EffectiveDirective: effective,
+				Matched:            matched,
+			})
+		}
+	}
+
+	if len(p.FrameAncestors) == 0 {
+		return append(directives, Directive{Name: "frame-ancestors"})
+	}
+
+	for _, expr := range p.FrameAncestors[0].AncestorExprs {
+		sourceExpr := SourceExpr{
+			SchemeSource:  expr.SchemeSource,
+			HostSource:    expr.HostSource,
+			KeywordSource: expr.KeywordSource,
+		}
+
+		if matchesSourceExpr(u, &sourceExpr, p.Origin) {
+			directives = append(directives, Directive{
+				Name:               "frame-ancestors",
+				EffectiveDirective: "frame-ancestors",
+				Matched:            &sourceExpr,
+			})
+
+			break
+		}
+	}
+
+	return directives
+}
+
+/*
+HostSources returns the distinct host-parts (e.g., `cdn.example.com`, or
+`*.example.com` for a wildcard) of every host-source in the policy, including
+`frame-ancestors`, lowercased and in the order they first appear. Scheme-parts,
+port-parts, and path-parts are dropped, so that `https://cdn.example.com/js/` and
+`cdn.example.com:443` count as the same host.
+*/
+func (p *Policy) HostSources() []string {
+	hosts := []string{}
+
+	add := func(hostSource string) {
+		if hostSource == "" {
+			return
+		}
+
+		_, host, _, _ := hostsource.Split(hostSource)
+		host = strings.ToLower(host)
+
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+
+	for _, directive := range sourceListDirectives {
+		list, ok := p.sourceList(directive)
+		if !ok {
+			continue
+		}
+
+		for i := range list.SourceExprs {
+			add(list.SourceExprs[i].HostSource)
+		}
+	}
+
+	for i := range p.FrameAncestors {
+		for j := range p.FrameAncestors[i].AncestorExprs {
+			add(p.FrameAncestors[i].AncestorExprs[j].HostSource)
+		}
+	}
+
+	return hosts
+}
+
+// normalizeLookupTarget turns a bare host or scheme into an absolute URL that
+// can be matched against source lists.
+func normalizeLookupTarget(target string) string {
+	switch {
+	case strings.Contains(target, "://"):
+		return target
+	case isSchemeSource(target):
+		return target + "x"
+	}
+
+	return "https://" + target + "/"
+}
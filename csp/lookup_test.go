@@ -0,0 +1,46 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostSources(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Policy   string
+		Expected []string
+	}{
+		"none": {
+			Policy:   "default-src 'self' https:",
+			Expected: []string{},
+		},
+		"deduplicated": {
+			Policy: "script-src https://CDN.example.com/js/ cdn.example.com:443 *.example.net; " +
+				"img-src cdn.example.com; frame-ancestors https://partner.example.org",
+			Expected: []string{"cdn.example.com", "*.example.net", "partner.example.org"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, _ := Parse("", "", []string{tc.Policy})
+
+			assert.Equal(tc.Expected, policies[0].HostSources())
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	whatwg "github.com/nlnwa/whatwg-url/url"
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+// requestURL is the subset of a parsed URL that the matching algorithms need.
+type requestURL struct {
+	Scheme string
+	Host   string
+	Port   int
+	Path   string
+}
+
+/*
+parseRequestURL parses an absolute URL that represents a resource being loaded
+by the protected document.
+
+----
+
+  - s (string): The absolute URL to parse.
+*/
+func parseRequestURL(s string) (*requestURL, error) {
+	u, err := whatwg.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf(errCSP0004, s)
+	}
+
+	return &requestURL{
+		Scheme: u.Scheme(),
+		Host:   u.Hostname(),
+		Port:   u.DecodedPort(),
+		Path:   u.Pathname(),
+	}, nil
+}
+
+/*
+matchesSourceList implements "Does url match source list in origin with redirect
+count?" from CSP Level 3, § 6.7.2.5, ignoring redirects.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#match-url-to-source-list
+
+----
+
+  - u (*requestURL): The URL of the resource being loaded.
+
+  - list (*SourceListItem): The source list to match against.
+
+  - self (*Origin): The origin of the protected document. May be nil.
+*/
+func matchesSourceList(u *requestURL, list *SourceListItem, self *Origin) (*SourceExpr, bool) {
+	for i := range list.SourceExprs {
+		if matchesSourceExpr(u, &list.SourceExprs[i], self) {
+			return &list.SourceExprs[i], true
+		}
+	}
+
+	return nil, false
+}
+
+/*
+matchesSourceExpr implements "Does url match expression in origin with redirect
+count?" from CSP Level 3, § 6.7.2.8, ignoring redirects.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#match-url-to-source-expression
+
+----
+
+  - u (*requestURL): The URL of the resource being loaded.
+
+  - expr (*SourceExpr): The source expression to match against.
+
+  - self (*Origin): The origin of the protected document. May be nil.
+*/
+func matchesSourceExpr(u *requestURL, expr *SourceExpr, self *Origin) bool {
+	switch {
+	case expr.HostSource == "*":
+		return hostsource.IsHTTPScheme(u.Scheme) || (self != nil && strings.EqualFold(u.Scheme, self.Scheme))
+	case expr.SchemeSource != "":
+		return hostsource.SchemeMatches(strings.TrimSuffix(expr.SchemeSource, ":"), u.Scheme)
+	case expr.HostSource != "":
+		return hostSourceMatches(u, expr.HostSource, self)
+	case strings.EqualFold(expr.KeywordSource, `'self'`):
+		return selfMatches(u, self)
+	}
+
+	return false
+}
+
+/*
+hostSourceMatches implements the host-source branch of "Does url match
+expression in origin with redirect count?" from CSP Level 3, § 6.7.2.8.
+
+----
+
+  - u (*requestURL): The URL of the resource being loaded.
+
+  - hostSource (string): The host-source expression.
+
+  - self (*Origin): The origin of the protected document. May be nil.
+*/
+func hostSourceMatches(u *requestURL, hostSource string, self *Origin) bool {
+	if u.Host == "" {
+		return false
+	}
+
+	scheme, host, port, path := hostsource.Split(hostSource)
+
+	switch {
+	case scheme != "":
+		if !hostsource.SchemeMatches(scheme, u.Scheme) {
+			return false
+		}
+	case self != nil:
+		if !hostsource.SchemeMatches(self.Scheme, u.Scheme) {
+			return false
+		}
+	case !hostsource.IsHTTPScheme(u.Scheme):
+		// When the origin of the protected document is unknown, assume that it
+		// was delivered over HTTP(S).
+		return false
+	}
+
+	if !hostsource.HostMatches(host, u.Host) {
+		return false
+	}
+
+	if !portPartMatches(port, u) {
+		return false
+	}
+
+	return hostsource.PathMatches(path, u.Path)
+}
+
+/*
+portPartMatches implements "port-part matching" from CSP Level 3, § 6.7.2.9.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#match-ports
+
+----
+
+  - port (string): The port-part from the source expression.
+
+  - u (*requestURL): The URL being loaded.
+*/
+func portPartMatches(port string, u *requestURL) bool {
+	if port == "*" {
+		return true
+	}
+
+	if port == "" {
+		return u.Port == hostsource.DefaultPort(u.Scheme)
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+
+	return n == u.Port
+}
+
+/*
+selfMatches implements the `'self'` branch of "Does url match expression in
+origin with redirect count?" from CSP Level 3, § 6.7.2.8. A nil (opaque) origin
+never matches.
+
+----
+
+  - u (*requestURL): The URL being loaded.
+
+  - self (*Origin): The origin of the protected document. May be nil.
+*/
+func selfMatches(u *requestURL, self *Origin) bool {
+	if self == nil || !strings.EqualFold(u.Host, self.Host) {
+		return false
+	}
+
+	if strings.EqualFold(u.Scheme, self.Scheme) && u.Port == self.Port {
+		return true
+	}
+
+	// A secure upgrade may change the port only from one default port to
+	// another (e.g., `http://example.com` to `https://example.com`), never from
+	// a custom port.
+	samePort := u.Port == self.Port ||
+		(self.Port == hostsource.DefaultPort(self.Scheme) && u.Port == hostsource.DefaultPort(u.Scheme))
+	scheme := strings.ToLower(u.Scheme)
+
+	return samePort &&
+		(scheme == "https" || scheme == "wss" ||
+			(strings.EqualFold(self.Scheme, "http") && scheme == "http"))
+}
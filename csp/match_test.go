@@ -0,0 +1,271 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestMatchesSourceExpr(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Self     string
+		URL      string
+		Expr     SourceExpr
+		Expected bool
+	}{
+		"* matches https": {
+			Self:     "https://example.com",
+			URL:      "https://cdn.example.net/app.js",
+			Expr:     SourceExpr{HostSource: "*"},
+			Expected: true,
+		},
+		"* does not match data:": {
+			Self:     "https://example.com",
+			URL:      "data:text/javascript,alert(1)",
+			Expr:     SourceExpr{HostSource: "*"},
+			Expected: false,
+		},
+		"https: matches https": {
+			URL:      "https://cdn.example.net/app.js",
+			Expr:     SourceExpr{SchemeSource: "https:"},
+			Expected: true,
+		},
+		"http: upgrades to https": {
+			URL:      "https://cdn.example.net/app.js",
+			Expr:     SourceExpr{SchemeSource: "http:"},
+			Expected: true,
+		},
+		"https: does not downgrade to http": {
+			URL:      "http://cdn.example.net/app.js",
+			Expr:     SourceExpr{SchemeSource: "https:"},
+			Expected: false,
+		},
+		"host without scheme inherits https": {
+			Self:     "https://example.com",
+			URL:      "http://cdn.example.net/app.js",
+			Expr:     SourceExpr{HostSource: "cdn.example.net"},
+			Expected: false,
+		},
+		"host without scheme": {
+			Self:     "https://example.com",
+			URL:      "https://cdn.example.net/app.js",
+			Expr:     SourceExpr{HostSource: "cdn.example.net"},
+			Expected: true,
+		},
+		"wildcard host matches subdomain": {
+			URL:      "https://a.b.example.net/app.js",
+			Expr:     SourceExpr{HostSource: "*.example.net"},
+			Expected: true,
+		},
+		"wildcard host does not match apex": {
+			URL:      "https://example.net/app.js",
+			Expr:     SourceExpr{HostSource: "*.example.net"},
+			Expected: false,
+		},
+		"non-default port": {
+			URL:      "https://cdn.example.net:8443/app.js",
+			Expr:     SourceExpr{HostSource: "https://cdn.example.net"},
+			Expected: false,
+		},
+		"explicit port": {
+			URL:      "https://cdn.example.net:8443/app.js",
+			Expr:     SourceExpr{HostSource: "https://cdn.example.net:8443"},
+			Expected: true,
+		},
+		"wildcard port": {
+			URL:      "https://cdn.example.net:8443/app.js",
+			Expr:     SourceExpr{HostSource: "https://cdn.example.net:*"},
+			Expected: true,
+		},
+		"path prefix": {
+			URL:      "https://cdn.example.net/js/app.js",
+			Expr:     SourceExpr{HostSource: "cdn.example.net/js/"},
+			Expected: true,
+		},
+		"exact path mismatch": {
+			URL:      "https://cdn.example.net/js/app.js",
+			Expr:     SourceExpr{HostSource: "cdn.example.net/js"},
+			Expected: false,
+		},
+		"'self' same origin": {
+			Self:     "https://example.com",
+			URL:      "https://example.com/app.js",
+			Expr:     SourceExpr{KeywordSource: "'self'"},
+			Expected: true,
+		},
+		"'self' upgrade": {
+			Self:     "http://example.com",
+			URL:      "https://example.com/app.js",
+			Expr:     SourceExpr{KeywordSource: "'self'"},
+			Expected: true,
+		},
+		"'self' subdomain": {
+			Self:     "https://example.com",
+			URL:      "https://www.example.com/app.js",
+			Expr:     SourceExpr{KeywordSource: "'self'"},
+			Expected: false,
+		},
+		"'self' without origin": {
+			URL:      "https://example.com/app.js",
+			Expr:     SourceExpr{KeywordSource: "'self'"},
+			Expected: false,
+		},
+		"nonce never matches a URL": {
+			URL:      "https://example.com/app.js",
+			Expr:     SourceExpr{NonceSource: "'nonce-abcdefgh'"},
+			Expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var self *Origin
+
+			if tc.Self != "" {
+				o, err := ParseOrigin(tc.Self)
+				assert.NoError(err)
+
+				self = o
+			}
+
+			u, err := parseRequestURL(tc.URL)
+			assert.NoError(err)
+
+			actual := matchesSourceExpr(u, &tc.Expr, self)
+
+			assert.Equalf(tc.Expected, actual, "Expected `%v`, but got `%v`.", tc.Expected, actual)
+		})
+	}
+}
+
+func TestWhereAllowed(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := Parse("", "", []string{
+		"default-src 'none'; script-src https://*.cdn.com; img-src *; frame-ancestors https:",
+	})
+
+	allowed := map[string]Directive{}
+
+	for _, d := range policies[0].WhereAllowed("evil.cdn.com") {
+		allowed[d.Name] = d
+	}
+
+	assert.Contains(allowed, "script-src")
+	assert.Contains(allowed, "script-src-elem")
+	assert.Equal("script-src", allowed["script-src-elem"].EffectiveDirective)
+	assert.Equal("https://*.cdn.com", allowed["worker-src"].Matched.HostSource)
+	assert.Contains(allowed, "img-src")
+	assert.Contains(allowed, "frame-ancestors")
+	assert.NotContains(allowed, "connect-src")
+
+	// base-uri and form-action do not fall back to default-src.
+	assert.Contains(allowed, "base-uri")
+	assert.Empty(allowed["base-uri"].EffectiveDirective)
+
+	dataAllowed := policies[0].WhereAllowed("data:")
+	assert.Len(dataAllowed, 2)
+
+	// With 'strict-dynamic', host-sources are ignored for scripts, but not for
+	// workers.
+	policies, _ = Parse("", "", []string{"script-src 'nonce-abc' 'strict-dynamic' https://cdn.com"})
+
+	allowed = map[string]Directive{}
+
+	for _, d := range policies[0].WhereAllowed("https://cdn.com/app.js") {
+		allowed[d.Name] = d
+	}
+
+	assert.NotContains(allowed, "script-src")
+	assert.NotContains(allowed, "script-src-elem")
+	assert.Equal("script-src", allowed["worker-src"].EffectiveDirective)
+
+	assert.Empty(policies[0].WhereAllowed("https://[::1"))
+
+	// 'self' matches the origin of the policy, when it has one.
+	policies, _ = Parse("https://example.com/", "", []string{"img-src 'self'"})
+	assert.Contains(policies[0].WhereAllowed("https://example.com/logo.png"), Directive{
+		Name:               "img-src",
+		EffectiveDirective: "img-src",
+		Matched:            &policies[0].ImageSource[0].SourceExprs[0],
+	})
+	assert.NotContains(policies[0].WhereAllowed("https://example.org/logo.png"), Directive{
+		Name:               "img-src",
+		EffectiveDirective: "img-src",
+		Matched:            &policies[0].ImageSource[0].SourceExprs[0],
+	})
+}
+
+func TestExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := Parse("", "", []string{"default-src 'self'", "script-src https://cdn.example.com; img-src *"})
+
+	allowed, explanations, err := Explain(policies, "https://example.com", "script-src-elem", "https://cdn.example.com/a.js")
+	assert.NoError(err)
+	assert.False(allowed)
+	assert.Len(explanations, 2)
+
+	assert.Equal("default-src", explanations[0].EffectiveDirective)
+	assert.False(explanations[0].Allowed)
+	assert.Contains(explanations[0].Reason, "no source expression in `default-src` matched")
+
+	assert.Equal(1, explanations[1].PolicyIndex)
+	assert.Equal("script-src", explanations[1].EffectiveDirective)
+	assert.True(explanations[1].Allowed)
+	assert.Equal("https://cdn.example.com", explanations[1].Matched.String())
+
+	explanation, err := policies[1].Explain("", "connect-src", "https://api.example.com/")
+	assert.NoError(err)
+	assert.True(explanation.Allowed)
+	assert.Empty(explanation.EffectiveDirective)
+	assert.Contains(explanation.Reason, "so the load is not restricted")
+
+	_, _, err = Explain(policies, "", "img-src", "not a url")
+	assert.Error(err)
+
+	policies, _ = Parse("", "", []string{"frame-src https://ads.example.com"})
+
+	explanation, err = policies[0].Explain("", "fenced-frame-src", "https://ads.example.com/ad.html")
+	assert.NoError(err)
+	assert.True(explanation.Allowed)
+	assert.Equal("frame-src", explanation.EffectiveDirective)
+
+	// 'strict-dynamic' makes browsers ignore host-sources, scheme-sources, and 'self' for scripts.
+	policies, _ = Parse("", "", []string{
+		"default-src 'self'; script-src 'nonce-cmFuZG9tLW5vbmNl' 'strict-dynamic' https: 'self'",
+	})
+
+	explanation, err = policies[0].Explain("https://example.com", "script-src-elem", "https://cdn.example.com/a.js")
+	assert.NoError(err)
+	assert.False(explanation.Allowed)
+	assert.Nil(explanation.Matched)
+	assert.Equal("https:", explanation.Ignored.String())
+	assert.Contains(explanation.Reason, "is ignored because `script-src` contains `'strict-dynamic'`")
+
+	explanation, err = policies[0].Explain("https://example.com", "script-src", "http://cdn.example.com/a.js")
+	assert.NoError(err)
+	assert.False(explanation.Allowed)
+	assert.Nil(explanation.Ignored)
+	assert.Contains(explanation.Reason, "`'strict-dynamic'` is present")
+
+	// Other directives are not affected.
+	explanation, err = policies[0].Explain("https://example.com", "img-src", "https://example.com/a.png")
+	assert.NoError(err)
+	assert.True(explanation.Allowed)
+}
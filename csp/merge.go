@@ -0,0 +1,214 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+/*
+Merge combines policy fragments (e.g., one per feature of a site) into a single
+serialized policy, directive by directive:
+
+  - Source lists are combined with SourceListItem.Union, so duplicate and
+    subsumed sources are dropped. A fragment that doesn't have the directive
+    contributes the list that it falls back to (e.g., `default-src`), so that
+    the merged directive doesn't block what the fragment allowed. When one
+    fragment has `'none'` and another allows sources, the merged directive
+    allows them, and CSP-0910 is reported.
+
+  - Directives without values (e.g., `upgrade-insecure-requests`) are kept if
+    any fragment has them.
+
+  - Other directives (e.g., `sandbox` or `report-to`) are kept as they appear
+    in the first fragment that has them. CSP-0911 is reported when another
+    fragment has different values.
+
+Directives appear in the order they are first seen. Only the first occurrence of
+a directive in each fragment is used, like browsers do. The result should be
+passed to Parse to validate it.
+
+----
+
+  - fragments ([]*Policy): The parsed policy fragments. Indexes in the
+    diagnostics refer to this slice.
+*/
+func Merge(fragments []*Policy) (string, error) {
+	var (
+		errs  *multierror.Error
+		names []string
+	)
+
+	for i := range fragments {
+		for j := range fragments[i].Directives {
+			name := strings.ToLower(fragments[i].Directives[j].Name)
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	directives := make([]string, 0, len(names))
+
+	for _, name := range names {
+		var (
+			values []string
+			err    error
+		)
+
+		if name == "frame-ancestors" || slices.Contains(sourceListDirectives, name) ||
+			name == "navigate-to" || name == "prefetch-src" {
+			values, err = mergeSourceLists(fragments, name)
+		} else {
+			values, err = mergeRawDirectives(fragments, name)
+		}
+
+		errs = multierror.Append(errs, err)
+		directives = append(directives, strings.Join(append([]string{name}, values...), " "))
+	}
+
+	return strings.Join(directives, "; "), errs.ErrorOrNil()
+}
+
+/*
+mergeSourceLists returns the union of the named source list across the
+fragments, as values of a directive.
+
+----
+
+  - fragments ([]*Policy): The parsed policy fragments.
+
+  - name (string): The lowercase name of the directive.
+*/
+func mergeSourceLists(fragments []*Policy, name string) ([]string, error) {
+	merged := SourceListItem{}
+	noneFrom, allowFrom := -1, -1
+
+	for i := range fragments {
+		list, ok := mergeSourceList(fragments[i], name)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case list.None && noneFrom < 0:
+			noneFrom = i
+		case len(list.SourceExprs) > 0 && allowFrom < 0:
+			allowFrom = i
+		}
+
+		merged = merged.Union(list)
+	}
+
+	if len(merged.SourceExprs) == 0 {
+		return []string{`'none'`}, nil
+	}
+
+	values := make([]string, 0, len(merged.SourceExprs))
+	for i := range merged.SourceExprs {
+		values = append(values, merged.SourceExprs[i].String())
+	}
+
+	if noneFrom >= 0 {
+		return values, fmt.Errorf(errCSP0910, name, noneFrom, allowFrom, strings.Join(values, "`, `"))
+	}
+
+	return values, nil
+}
+
+/*
+mergeSourceList returns the named source list of a fragment, after the directive
+fallback list is applied. The ancestor source list of `frame-ancestors` is
+converted to a SourceListItem, so that it can be combined the same way.
+
+----
+
+  - policy (*Policy): The parsed policy fragment.
+
+  - name (string): The lowercase name of the directive.
+*/
+func mergeSourceList(policy *Policy, name string) (SourceListItem, bool) {
+	if name != "frame-ancestors" {
+		_, list, ok := policy.effectiveSourceList(name)
+		if !ok {
+			return SourceListItem{}, false
+		}
+
+		return *list, true
+	}
+
+	if len(policy.FrameAncestors) == 0 {
+		return SourceListItem{}, false
+	}
+
+	ancestors := policy.FrameAncestors[0]
+	list := SourceListItem{None: ancestors.None, Empty: ancestors.Empty}
+
+	for i := range ancestors.AncestorExprs {
+		list.SourceExprs = append(list.SourceExprs, SourceExpr{
+			SchemeSource:  ancestors.AncestorExprs[i].SchemeSource,
+			HostSource:    ancestors.AncestorExprs[i].HostSource,
+			KeywordSource: ancestors.AncestorExprs[i].KeywordSource,
+		})
+	}
+
+	return list, true
+}
+
+/*
+mergeRawDirectives returns the values of the named directive from the first
+fragment that has it, and reports the fragments that disagree.
+
+----
+
+  - fragments ([]*Policy): The parsed policy fragments.
+
+  - name (string): The lowercase name of the directive.
+*/
+func mergeRawDirectives(fragments []*Policy, name string) ([]string, error) {
+	var (
+		errs   *multierror.Error
+		values []string
+	)
+
+	first := -1
+
+	for i := range fragments {
+		idx := slices.IndexFunc(fragments[i].Directives, func(d RawDirective) bool {
+			return strings.EqualFold(d.Name, name)
+		})
+		if idx < 0 {
+			continue
+		}
+
+		current := fragments[i].Directives[idx].Values
+
+		if first < 0 {
+			first, values = i, current
+
+			continue
+		}
+
+		if !strings.EqualFold(strings.Join(current, " "), strings.Join(values, " ")) {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0911, name, first, i, strings.Join(values, " "), first))
+		}
+	}
+
+	return values, errs.ErrorOrNil()
+}
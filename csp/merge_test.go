@@ -0,0 +1,93 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Fragments   []string
+		Expected    string
+		ErrorSubstr []string
+	}{
+		"union with dedup": {
+			Fragments: []string{
+				"default-src 'self'; script-src 'self' cdn.example.com",
+				"script-src 'self' https://analytics.example.com cdn.example.com; img-src *.example.com",
+				"img-src cdn.example.com",
+			},
+			Expected: "default-src 'self'; script-src 'self' cdn.example.com https://analytics.example.com; " +
+				"img-src 'self' *.example.com",
+		},
+		"default-src fallback": {
+			Fragments: []string{"default-src 'self'", "script-src https://cdn.example"},
+			Expected:  "default-src 'self'; script-src 'self' https://cdn.example",
+		},
+		"script-src fallback": {
+			Fragments: []string{"script-src 'self'", "script-src-elem https://cdn.example"},
+			Expected:  "script-src 'self'; script-src-elem 'self' https://cdn.example",
+		},
+		"none conflicts with sources": {
+			Fragments:   []string{"frame-src 'none'", "frame-src https://www.youtube.com"},
+			Expected:    "frame-src https://www.youtube.com",
+			ErrorSubstr: []string{"[WARN] directive `frame-src`: fragment 0 has `'none'`, but fragment 1 allows"},
+		},
+		"none everywhere": {
+			Fragments: []string{"object-src 'none'; frame-ancestors 'none'", "object-src 'none'"},
+			Expected:  "object-src 'none'; frame-ancestors 'none'",
+		},
+		"frame-ancestors": {
+			Fragments: []string{"frame-ancestors 'none'", "frame-ancestors https://partner.example.com"},
+			Expected:  "frame-ancestors https://partner.example.com",
+			ErrorSubstr: []string{
+				"directive `frame-ancestors`: fragment 0 has `'none'`, but fragment 1 allows sources, so the " +
+					"merged directive allows `https://partner.example.com` [CSP-0910]",
+			},
+		},
+		"valueless and raw directives": {
+			Fragments: []string{
+				"upgrade-insecure-requests; report-to main",
+				"REPORT-TO main; sandbox allow-scripts",
+				"report-to other; upgrade-insecure-requests",
+			},
+			Expected: "upgrade-insecure-requests; report-to main; sandbox allow-scripts",
+			ErrorSubstr: []string{
+				"[WARN] directive `report-to` differs between fragments 0 and 2; the merged policy keeps `main` " +
+					"from fragment 0 [CSP-0911]",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fragments, _ := Parse("", "", tc.Fragments)
+
+			merged, err := Merge(fragments)
+			assert.Equal(tc.Expected, merged)
+
+			if len(tc.ErrorSubstr) == 0 {
+				assert.NoError(err)
+			}
+
+			for _, substr := range tc.ErrorSubstr {
+				assert.ErrorContains(err, substr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Mode controls how closely the parser follows the CSP grammar. It sets the
+// defaults for the more specific options (e.g., WithHostStrictness), which may
+// still be passed after it to override them.
+type Mode string
+
+const (
+	// ModeDefault is the mix of checks that this package has always made. It is
+	// the default.
+	ModeDefault Mode = "default"
+
+	// ModeStrict enforces the exact CSP grammar: directive names, host-parts,
+	// `webrtc` values, and the whitespace between directives must all follow
+	// the ABNF, even where browsers recover from the mistake.
+	ModeStrict Mode = "strict"
+
+	// ModeLenient mirrors the error recovery of browsers: it accepts the
+	// host-parts that browsers accept (with a warning), and only strips the
+	// ASCII whitespace around a directive, as browsers do.
+	ModeLenient Mode = "lenient"
+)
+
+// Modes is the list of modes, in order from least to most strict.
+var Modes = []Mode{ModeLenient, ModeDefault, ModeStrict}
+
+// WithMode sets how closely the parser follows the CSP grammar. The default is
+// ModeDefault.
+func WithMode(m Mode) Option {
+	return func(c *config) {
+		c.mode = m
+
+		switch m {
+		case ModeStrict:
+			c.hostStrictness = HostStrictnessSpec
+		case ModeLenient:
+			c.hostStrictness = HostStrictnessLenient
+		default:
+			c.hostStrictness = HostStrictnessDefault
+		}
+	}
+}
+
+// Strict is shorthand for WithMode(ModeStrict).
+func Strict() Option {
+	return WithMode(ModeStrict)
+}
+
+/*
+trimDirective removes the whitespace around a directive, and reports whether
+any of it was non-ASCII whitespace, which the CSP grammar does not allow. In
+ModeLenient, only ASCII whitespace is removed, as browsers do, so that anything
+else stays part of the directive name or value.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#parse-serialized-policy
+
+----
+
+  - raw (string): The directive, as split from the serialized policy.
+*/
+func (c *config) trimDirective(raw string) (trimmed, directive string, nonASCII bool) {
+	nonASCII = strings.TrimFunc(raw, isSpaceRune) != strings.TrimSpace(raw)
+
+	isSpace := unicode.IsSpace
+	if c.mode == ModeLenient {
+		isSpace = isSpaceRune
+	}
+
+	trimmed = strings.TrimLeftFunc(raw, isSpace)
+	directive = strings.TrimRightFunc(trimmed, isSpace)
+
+	return trimmed, directive, nonASCII
+}
@@ -0,0 +1,93 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CSP         string
+		Opts        []Option
+		ErrorSubstr string
+		Directive   string
+	}{
+		"default: non-ASCII whitespace is stripped": {
+			CSP:       "default-src 'self';\u00a0script-src 'self'",
+			Directive: "script-src",
+		},
+		"strict: non-ASCII whitespace": {
+			CSP:         "default-src 'self';\u00a0script-src 'self'",
+			Opts:        []Option{WithMode(ModeStrict)},
+			ErrorSubstr: "[CSP-0913]",
+			Directive:   "script-src",
+		},
+		"lenient: non-ASCII whitespace is part of the name": {
+			CSP:         "default-src 'self';\u00a0script-src 'self'",
+			Opts:        []Option{WithMode(ModeLenient)},
+			ErrorSubstr: "[CSP-0912]",
+			Directive:   "\u00a0script-src",
+		},
+		"strict: host-part grammar": {
+			CSP:         "script-src .example.com",
+			Opts:        []Option{WithMode(ModeStrict)},
+			ErrorSubstr: "[CSP-0116]",
+			Directive:   "script-src",
+		},
+		"lenient: underscore in host-part": {
+			CSP:         "script-src https://my_host.example.com",
+			Opts:        []Option{WithMode(ModeLenient)},
+			ErrorSubstr: "[CSP-0117]",
+			Directive:   "script-src",
+		},
+		"strict: directive name grammar": {
+			CSP:         "script src 'self'",
+			Opts:        []Option{Strict()},
+			ErrorSubstr: "[CSP-0903]",
+			Directive:   "script",
+		},
+		"lenient: directive name grammar": {
+			CSP:         "script src 'self'",
+			Opts:        []Option{WithMode(ModeLenient)},
+			ErrorSubstr: "[CSP-0901]",
+			Directive:   "script",
+		},
+		"strict mode with host strictness override": {
+			CSP:         "script-src https://my_host.example.com",
+			Opts:        []Option{WithMode(ModeStrict), WithHostStrictness(HostStrictnessLenient)},
+			ErrorSubstr: "[CSP-0117]",
+			Directive:   "script-src",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			opts := append([]Option{MinSeverity(SeverityWarning), WithoutReportingValidation()}, tc.Opts...)
+			policies, err := Parse("https://example.com", "", []string{tc.CSP}, opts...)
+
+			if tc.ErrorSubstr == "" {
+				assert.NoError(err)
+			} else {
+				assert.ErrorContains(err, tc.ErrorSubstr)
+			}
+
+			assert.Len(policies, 1)
+			assert.Equal(tc.Directive, policies[0].Directives[len(policies[0].Directives)-1].Name)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+String serializes the policy from its directives, in order, as a single header
+value (e.g., `default-src 'self'; img-src https:`). Duplicate and unknown
+directives are kept. Comments are not included.
+*/
+func (p *Policy) String() string {
+	directives := make([]string, 0, len(p.Directives))
+
+	for i := range p.Directives {
+		directives = append(directives, strings.Join(append([]string{p.Directives[i].Name},
+			p.Directives[i].Values...), " "))
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+/*
+SetDirective replaces the values of the named directive, or adds the directive
+to the end of the policy when it is not present. Later occurrences of the
+directive, which browsers ignore, are removed.
+
+The policy is serialized and parsed again, so that Raw, Directives, and the
+parsed fields stay consistent. If the result has errors, they are returned and
+the policy is left unchanged. The policy keeps its disposition, delivery, and
+origin; other options passed to the original call to Parse are not reapplied.
+
+----
+
+  - name (string): The name of the directive (e.g., `img-src`).
+
+  - values (...string): The values of the directive. May be empty for
+    directives without values (e.g., `upgrade-insecure-requests`).
+*/
+func (p *Policy) SetDirective(name string, values ...string) error {
+	directives := []RawDirective{}
+	replaced := false
+
+	for i := range p.Directives {
+		if !strings.EqualFold(p.Directives[i].Name, name) {
+			directives = append(directives, p.Directives[i])
+
+			continue
+		}
+
+		if !replaced {
+			directive := p.Directives[i]
+			directive.Values = values
+			directives = append(directives, directive)
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		directives = append(directives, RawDirective{Name: strings.ToLower(name), Values: values})
+	}
+
+	return p.update(directives)
+}
+
+/*
+AddSources adds sources to the named source list directive, skipping the ones
+that it already has, and removes `'none'`. When the directive is not present,
+it is added with the list that it falls back to (e.g., `default-src`) and the
+new sources, so that it still allows what the policy allowed before.
+
+See SetDirective for how the policy is updated.
+
+----
+
+  - name (string): The name of the directive (e.g., `script-src`).
+
+  - sources (...string): The source expressions to add (e.g.,
+    `https://cdn.example.com`).
+*/
+func (p *Policy) AddSources(name string, sources ...string) error {
+	values := []string{}
+
+	if _, list, ok := p.effectiveSourceList(name); ok {
+		for i := range list.SourceExprs {
+			values = append(values, list.SourceExprs[i].String())
+		}
+	}
+
+	if idx := slices.IndexFunc(p.Directives, func(d RawDirective) bool {
+		return strings.EqualFold(d.Name, name)
+	}); idx >= 0 {
+		values = slices.DeleteFunc(slices.Clone(p.Directives[idx].Values), func(v string) bool {
+			return strings.EqualFold(v, `'none'`)
+		})
+	}
+
+	for _, source := range sources {
+		if !slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, source) }) {
+			values = append(values, source)
+		}
+	}
+
+	return p.SetDirective(name, values...)
+}
+
+/*
+RemoveDirective removes every occurrence of the named directive. Removing a
+directive that is not present is not an error.
+
+See SetDirective for how the policy is updated.
+
+----
+
+  - name (string): The name of the directive (e.g., `report-uri`).
+*/
+func (p *Policy) RemoveDirective(name string) error {
+	return p.update(slices.DeleteFunc(slices.Clone(p.Directives), func(d RawDirective) bool {
+		return strings.EqualFold(d.Name, name)
+	}))
+}
+
+/*
+update serializes the directives and replaces the policy with the result of
+parsing them. The comments of the policy and of its directives are kept.
+
+----
+
+  - directives ([]RawDirective): The directives of the updated policy.
+*/
+func (p *Policy) update(directives []RawDirective) error {
+	for i := range directives {
+		for _, token := range append([]string{directives[i].Name}, directives[i].Values...) {
+			if token == "" || strings.ContainsAny(token, ";, \t\n\f\r") {
+				return fmt.Errorf(errCSP0031, token, directives[i].Name)
+			}
+		}
+	}
+
+	updated := &Policy{Directives: directives}
+	opts := []Option{MinSeverity(SeverityError), WithoutReportingValidation()}
+	currentURL := ""
+
+	if p.Disposition != "" {
+		opts = append(opts, WithDisposition(p.Disposition))
+	}
+
+	if p.Delivery != "" {
+		opts = append(opts, WithDelivery(p.Delivery))
+	}
+
+	if p.Origin != nil {
+		currentURL = p.Origin.String()
+	} else {
+		opts = append(opts, WithoutSelfValidation())
+	}
+
+	policies, err := Parse(currentURL, "", []string{updated.String()}, opts...)
+	if err != nil {
+		return err
+	}
+
+	updated = policies[0]
+	updated.Comments = p.Comments
+
+	for i := range updated.Directives {
+		idx := slices.IndexFunc(p.Directives, func(d RawDirective) bool {
+			return strings.EqualFold(d.Name, updated.Directives[i].Name)
+		})
+		if idx >= 0 {
+			updated.Directives[i].Comments = p.Directives[idx].Comments
+		}
+	}
+
+	*p = *updated
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyMutation(t *testing.T) {
+	assert := assert.New(t)
+
+	policy, err := Preset("static-site")
+	assert.NoError(err)
+
+	assert.NoError(policy.AddSources("script-src", "https://cdn.example.com"))
+	assert.NoError(policy.AddSources("img-src", "https:", "'self'"))
+	assert.NoError(policy.SetDirective("form-action", "'none'"))
+	assert.NoError(policy.RemoveDirective("upgrade-insecure-requests"))
+	assert.NoError(policy.SetDirective("report-to", "csp"))
+
+	expected := "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'none'; " +
+		"frame-ancestors 'none'; script-src 'self' https://cdn.example.com; img-src 'self' https:; report-to csp"
+
+	assert.Equal(expected, policy.String())
+	assert.Equal(expected, policy.Raw)
+	assert.False(policy.UpgradeInsecureReq)
+	assert.Equal([]SourceListItem{{None: true}}, policy.FormAction)
+	assert.Len(policy.ScriptSource[0].SourceExprs, 2)
+	assert.Equal(DispositionEnforce, policy.Disposition)
+
+	// `'none'` is dropped when sources are added.
+	assert.NoError(policy.AddSources("form-action", "'self'"))
+	assert.Equal([]string{"'self'"}, policy.Directives[3].Values)
+
+	// Errors leave the policy unchanged.
+	assert.ErrorContains(policy.SetDirective("script-src", "https://a.example.com;"), "[CSP-0031]")
+	assert.ErrorContains(policy.SetDirective("img-src", "https://exa mple.com"), "[CSP-0031]")
+	assert.ErrorContains(policy.SetDirective("frame-ancestors", "'unsafe-inline'"), "[ERROR]")
+	assert.Len(policy.ScriptSource[0].SourceExprs, 2)
+
+	// 'self' is still validated against the origin of the policy.
+	policies, _ := Parse("https://example.com", "", []string{"default-src 'self'"})
+	assert.NoError(policies[0].AddSources("connect-src", "https://api.example.com"))
+	assert.Equal("https://example.com", policies[0].Origin.String())
+
+	// Duplicates are removed.
+	policies, _ = Parse("", "", []string{"img-src a.example.com; img-src b.example.com"})
+	assert.NoError(policies[0].SetDirective("IMG-SRC", "c.example.com"))
+	assert.Equal("img-src c.example.com", policies[0].String())
+}
@@ -0,0 +1,56 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import "strings"
+
+/*
+canonicalToken returns the spelling of a directive name or value that the
+parser uses in the typed fields of a Policy. Directive names and keyword sources
+are case-insensitive, and are lowercased. Every other token is returned as it
+was written.
+
+----
+
+  - token (string): The directive name or value, as it was written.
+
+  - isName (bool): Whether the token is a directive name.
+*/
+func canonicalToken(token string, isName bool) string {
+	if isName || isKeywordSource(token) {
+		return strings.ToLower(token)
+	}
+
+	return token
+}
+
+// rawTokens returns the name and values of a directive as RawTokens, marking the
+// ones whose spelling the parser normalized.
+func rawTokens(name string, values []string) []RawToken {
+	tokens := make([]RawToken, 0, len(values)+1)
+
+	for i, token := range append([]string{name}, values...) {
+		raw := RawToken{Text: token}
+
+		if canonical := canonicalToken(token, i == 0); canonical != token {
+			raw.Canonical = canonical
+			raw.Normalized = true
+		}
+
+		tokens = append(tokens, raw)
+	}
+
+	return tokens
+}
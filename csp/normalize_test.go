@@ -0,0 +1,51 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRawTokens(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, err := Parse("https://example.com", "", []string{"Script-Src 'SELF' https://CDN.example.com; " +
+		"frame-ancestors 'Self'; report-to main"}, WithoutReportingValidation(), WithRawTokens())
+	assert.NoError(err)
+
+	// The typed fields use the normalized spelling.
+	assert.Equal("'self'", policies[0].ScriptSource[0].SourceExprs[0].KeywordSource)
+	assert.Equal("'self'", policies[0].FrameAncestors[0].AncestorExprs[0].KeywordSource)
+
+	// The lossless AST keeps the original spelling, and marks what was changed.
+	assert.Equal("Script-Src", policies[0].Directives[0].Name)
+	assert.Equal([]RawToken{
+		{Text: "Script-Src", Canonical: "script-src", Normalized: true},
+		{Text: "'SELF'", Canonical: "'self'", Normalized: true},
+		{Text: "https://CDN.example.com"},
+	}, policies[0].Directives[0].Tokens)
+	assert.Equal([]RawToken{
+		{Text: "frame-ancestors"},
+		{Text: "'Self'", Canonical: "'self'", Normalized: true},
+	}, policies[0].Directives[1].Tokens)
+
+	// Tokens are only recorded on request.
+	policies, err = Parse("https://example.com", "", []string{"script-src 'SELF'; report-to main"},
+		WithoutReportingValidation())
+	assert.NoError(err)
+	assert.Nil(policies[0].Directives[0].Tokens)
+}
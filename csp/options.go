@@ -0,0 +1,169 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import "time"
+
+type (
+	// Option configures the behavior of Parse.
+	Option func(*config)
+
+	// config holds the options for a single call to Parse, as well as the state
+	// that the options need while parsing.
+	config struct {
+		trace            func(TraceEvent)
+		minSeverity      Severity
+		currentURL       string
+		policyIndex      int
+		mode             Mode
+		hostStrictness   HostStrictness
+		disposition      Disposition
+		delivery         Delivery
+		executionContext ExecutionContext
+		limits           Limits
+		stats            *Stats
+		started          time.Time
+
+		// withoutSelf and withoutReporting are true when the caller opted out of
+		// validating `'self'` and `report-to`, respectively.
+		withoutSelf      bool
+		withoutReporting bool
+
+		// rawTokens is true when Policy.Directives should include the tokens of
+		// each directive.
+		rawTokens bool
+
+		// rules configures which directives each evaluation rule applies to.
+		rules Rules
+
+		// schemeRules are checked before DefaultSchemeRules.
+		schemeRules []SchemeRule
+
+		// reportingEndpoints is the Reporting-Endpoints header passed to Parse,
+		// missingReportingSeverity is the severity of CSP-0021 and CSP-0022, and
+		// insecureReportingSeverity is the severity of CSP-0404 and CSP-0518.
+		reportingEndpoints        string
+		missingReportingSeverity  Severity
+		insecureReportingSeverity Severity
+
+		// policyOffset is added to the index of each policy, and continued is
+		// true when the call-level diagnostics (e.g., CSP-0001) were already
+		// reported by an earlier call. Both are used when one logical list of
+		// policies is parsed in more than one call (e.g., by ParseHeader).
+		policyOffset int
+		continued    bool
+
+		// directiveOrder is the lowercase names of the directives in the current
+		// policy, in the order they appear.
+		directiveOrder []string
+
+		// span is the location of the directive being parsed, or nil between
+		// directives.
+		span *Span
+
+		// directiveIndex is the index in Policy.Directives of the directive being
+		// parsed, or -1 between directives, and valueSpans are the locations of
+		// its values.
+		directiveIndex int
+		valueSpans     []Span
+	}
+)
+
+// newConfig applies the options on top of the defaults.
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		disposition:      DispositionEnforce,
+		delivery:         DeliveryHeader,
+		executionContext: ContextDocument,
+		limits:           DefaultLimits,
+
+		missingReportingSeverity:  SeverityInfo,
+		insecureReportingSeverity: SeverityWarning,
+
+		directiveIndex: -1,
+	}
+
+	for i := range opts {
+		opts[i](cfg)
+	}
+
+	return cfg
+}
+
+// WithTrace registers a callback that is invoked on each parsing decision. See
+// TraceEvent for details.
+func WithTrace(fn func(TraceEvent)) Option {
+	return func(c *config) {
+		c.trace = fn
+	}
+}
+
+// WithoutSelfValidation opts out of validating `'self'` sources (e.g., for a
+// policy that is served from many origins). The currentURL passed to Parse is
+// ignored, and the CSP-0001 note for an empty currentURL is not reported, so
+// that an empty currentURL is only reported when it was left empty by accident.
+func WithoutSelfValidation() Option {
+	return func(c *config) {
+		c.withoutSelf = true
+	}
+}
+
+// WithoutReportingValidation opts out of validating `report-to` against the
+// `Reporting-Endpoints` header (e.g., when the header is set by another layer).
+// Endpoint names are accepted without being looked up, and the CSP-0002 note
+// for an empty header is not reported.
+func WithoutReportingValidation() Option {
+	return func(c *config) {
+		c.withoutReporting = true
+	}
+}
+
+// WithMissingReportingSeverity sets the severity of the diagnostics for a policy
+// that cannot report its violations: CSP-0021 (no `report-to` or `report-uri`
+// directive) and CSP-0022 (no valid reporting destination). The default is
+// SeverityInfo.
+func WithMissingReportingSeverity(s Severity) Option {
+	return func(c *config) {
+		c.missingReportingSeverity = s
+	}
+}
+
+// WithInsecureReportingSeverity sets the severity of the diagnostics for a
+// reporting URL that is not potentially trustworthy, which browsers will not send
+// reports to: CSP-0404 (a `report-uri` URL) and CSP-0518 (the Reporting-Endpoints
+// URL of a `report-to` endpoint). The default is SeverityWarning.
+func WithInsecureReportingSeverity(s Severity) Option {
+	return func(c *config) {
+		c.insecureReportingSeverity = s
+	}
+}
+
+// WithRawTokens records each directive name and value in RawDirective.Tokens,
+// and marks the ones whose spelling the parser normalized (directive names and
+// keyword sources are lowercased), so that auditors can see exactly what was
+// sent.
+func WithRawTokens() Option {
+	return func(c *config) {
+		c.rawTokens = true
+	}
+}
+
+// withPolicyOffset continues the policy numbering of an earlier call to Parse.
+func withPolicyOffset(offset int, continued bool) Option {
+	return func(c *config) {
+		c.policyOffset = offset
+		c.continued = continued
+	}
+}
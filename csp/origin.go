@@ -0,0 +1,175 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	whatwg "github.com/nlnwa/whatwg-url/url"
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+/*
+Origin is a tuple origin (scheme, host, port) as defined by the HTML
+specification. An opaque origin is represented by a nil *Origin.
+
+The scheme and host are always lowercase, and the port is always the effective
+port: a URL without an explicit port takes the default port of its scheme, so
+`https://example.com` and `https://example.com:443` have equal origins.
+
+https://html.spec.whatwg.org/multipage/browsers.html#concept-origin
+*/
+type Origin struct {
+	Scheme string `json:"scheme"`
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+}
+
+/*
+NewOrigin returns the tuple origin for the scheme, host, and port. A port of 0
+is replaced with the default port of the scheme, if it has one.
+
+----
+
+  - scheme (string): The scheme, without the trailing colon (e.g., `https`).
+
+  - host (string): The host (e.g., `example.com`).
+
+  - port (int): The port, or 0 to use the default port of the scheme.
+*/
+func NewOrigin(scheme, host string, port int) *Origin {
+	scheme = strings.ToLower(scheme)
+
+	if port == 0 {
+		port = hostsource.DefaultPort(scheme)
+	}
+
+	return &Origin{
+		Scheme: scheme,
+		Host:   strings.ToLower(host),
+		Port:   port,
+	}
+}
+
+/*
+ParseOrigin parses an absolute URL and returns its origin. URLs without a host
+(e.g., `file:`, `data:`, `about:`) have an opaque origin, which is returned as
+nil without an error.
+
+----
+
+  - s (string): The absolute URL to parse.
+*/
+func ParseOrigin(s string) (*Origin, error) {
+	u, err := whatwg.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf(errCSP0003, s)
+	}
+
+	if u.Hostname() == "" {
+		return nil, nil
+	}
+
+	return NewOrigin(u.Scheme(), u.Hostname(), u.DecodedPort()), nil
+}
+
+/*
+parseCurrentURL validates and normalizes the URL of the protected document, and
+returns its origin (nil for an opaque origin). A URL that is not a valid
+absolute URL is returned as an empty string, along with an error. A URL that is
+not HTTP(S) is kept, along with an error, because browsers still enforce
+policies for it (e.g., `file:` or `data:`), and its opaque origin is what makes
+`'self'` match nothing.
+
+----
+
+  - s (string): The URL of the protected document. May be empty.
+*/
+func parseCurrentURL(s string) (normalized string, origin *Origin, err error) {
+	if s == "" {
+		return "", nil, nil
+	}
+
+	u, perr := whatwg.Parse(s)
+	if perr != nil {
+		return "", nil, fmt.Errorf(errCSP0027, s)
+	}
+
+	if u.Hostname() != "" {
+		origin = NewOrigin(u.Scheme(), u.Hostname(), u.DecodedPort())
+	}
+
+	if !hostsource.IsHTTPScheme(u.Scheme()) {
+		err = fmt.Errorf(errCSP0028, s, u.Protocol())
+	}
+
+	return u.Href(false), origin, err
+}
+
+// String returns the ASCII serialization of the origin. The port is omitted
+// when it is the default port of the scheme.
+func (o *Origin) String() string {
+	if o == nil {
+		return "null"
+	}
+
+	if o.Port == hostsource.DefaultPort(o.Scheme) {
+		return o.Scheme + "://" + o.Host
+	}
+
+	return o.Scheme + "://" + o.Host + ":" + strconv.Itoa(o.Port)
+}
+
+/*
+SameOrigin reports whether two origins are the same origin. An opaque (nil)
+origin is only the same as itself, and two nil pointers cannot be told apart, so
+an opaque origin is never reported as the same as any other.
+
+https://html.spec.whatwg.org/multipage/browsers.html#same-origin
+
+----
+
+  - other (*Origin): The origin to compare against. May be nil.
+*/
+func (o *Origin) SameOrigin(other *Origin) bool {
+	if o == nil || other == nil {
+		return false
+	}
+
+	return strings.EqualFold(o.Scheme, other.Scheme) &&
+		strings.EqualFold(o.Host, other.Host) &&
+		o.Port == other.Port
+}
+
+/*
+MatchesSelf reports whether the target URL matches `'self'` in a policy served
+from this origin, using the same rules as the parser. Unlike SameOrigin, this
+also allows the secure upgrades that CSP Level 3 permits (e.g., `https:` and
+`wss:` URLs on the default port match an `http:` origin).
+
+----
+
+  - target (string): The absolute URL of the resource being loaded.
+*/
+func (o *Origin) MatchesSelf(target string) (bool, error) {
+	u, err := parseRequestURL(target)
+	if err != nil {
+		return false, err
+	}
+
+	return selfMatches(u, o), nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrigin(t *testing.T) {
+	for name, tc := range map[string]struct {
+		URL      string
+		Expected string
+		Port     int
+	}{
+		"default port omitted": {
+			URL:      "https://example.com",
+			Expected: "https://example.com",
+			Port:     443,
+		},
+		"explicit default port": {
+			URL:      "https://example.com:443/path?query",
+			Expected: "https://example.com",
+			Port:     443,
+		},
+		"custom port": {
+			URL:      "http://example.com:8080/",
+			Expected: "http://example.com:8080",
+			Port:     8080,
+		},
+		"uppercase scheme and host": {
+			URL:      "HTTPS://Example.COM/",
+			Expected: "https://example.com",
+			Port:     443,
+		},
+		"opaque origin": {
+			URL:      "data:text/html,hello",
+			Expected: "null",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			o, err := ParseOrigin(tc.URL)
+			assert.NoError(err)
+			assert.Equal(tc.Expected, o.String())
+
+			if o != nil {
+				assert.Equal(tc.Port, o.Port)
+			}
+		})
+	}
+}
+
+func TestParseOriginInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseOrigin("not a url")
+	assert.ErrorContains(err, "[CSP-0003]")
+}
+
+func TestOriginSameOrigin(t *testing.T) {
+	for name, tc := range map[string]struct {
+		A        string
+		B        string
+		Expected bool
+	}{
+		"explicit and implicit default port": {
+			A:        "https://example.com",
+			B:        "https://example.com:443",
+			Expected: true,
+		},
+		"different port": {
+			A:        "https://example.com",
+			B:        "https://example.com:8443",
+			Expected: false,
+		},
+		"different scheme": {
+			A:        "http://example.com",
+			B:        "https://example.com",
+			Expected: false,
+		},
+		"paths are ignored": {
+			A:        "https://example.com/a",
+			B:        "https://example.com/b",
+			Expected: true,
+		},
+		"opaque": {
+			A:        "about:blank",
+			B:        "about:blank",
+			Expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			a, err := ParseOrigin(tc.A)
+			assert.NoError(err)
+
+			b, err := ParseOrigin(tc.B)
+			assert.NoError(err)
+
+			assert.Equal(tc.Expected, a.SameOrigin(b))
+		})
+	}
+}
+
+func TestOriginMatchesSelf(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Origin   string
+		Target   string
+		Expected bool
+	}{
+		"same origin": {
+			Origin:   "https://example.com",
+			Target:   "https://example.com/app.js",
+			Expected: true,
+		},
+		"secure upgrade on default ports": {
+			Origin:   "http://example.com",
+			Target:   "https://example.com/app.js",
+			Expected: true,
+		},
+		"websocket upgrade": {
+			Origin:   "https://example.com",
+			Target:   "wss://example.com/socket",
+			Expected: true,
+		},
+		"upgrade from a custom port": {
+			Origin:   "http://example.com:8080",
+			Target:   "https://example.com/app.js",
+			Expected: false,
+		},
+		"same custom port": {
+			Origin:   "http://example.com:8080",
+			Target:   "https://example.com:8080/app.js",
+			Expected: true,
+		},
+		"downgrade": {
+			Origin:   "https://example.com",
+			Target:   "http://example.com/app.js",
+			Expected: false,
+		},
+		"opaque origin": {
+			Origin:   "data:text/html,hello",
+			Target:   "https://example.com/app.js",
+			Expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			o, err := ParseOrigin(tc.Origin)
+			assert.NoError(err)
+
+			actual, err := o.MatchesSelf(tc.Target)
+			assert.NoError(err)
+			assert.Equal(tc.Expected, actual)
+		})
+	}
+}
+
+func TestNewOrigin(t *testing.T) {
+	assert := assert.New(t)
+
+	o := NewOrigin("WSS", "Example.com", 0)
+	assert.Equal(&Origin{Scheme: "wss", Host: "example.com", Port: 443}, o)
+	assert.Equal("wss://example.com", o.String())
+	assert.Equal("custom://example.com", NewOrigin("custom", "example.com", 0).String())
+}
@@ -15,12 +15,22 @@
 package csp
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
 	"fmt"
+	"math"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/nlnwa/whatwg-url/url"
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+	"golang.org/x/net/idna"
 )
 
 /*
@@ -29,43 +39,140 @@ struct.
 
 ----
 
-  - currentURL (string): The URL of the current document. May be an empty
-    string, but this will disable validation of 'self' sources.
+  - currentURL (string): The absolute HTTP(S) URL of the current document.
+    May be an empty string, but this will disable validation of 'self' sources
+    (pass WithoutSelfValidation to do so without a note). An invalid URL is
+    reported and ignored; Policy.Origin has the origin of a valid one.
 
   - reportingEndpointsHeader (string): The value of the `Reporting-Endpoints`
     header. Is used to validate the `report-to` directive. If there is no
-    `report-to` directive, this value can be an empty string.
+    `report-to` directive, this value can be an empty string (pass
+    WithoutReportingValidation to skip validation without a note).
 
   - policies ([]string): A slice of strings, each representing the value of a
     `Content-Security-Policy` header. Normally, there will only be one. However
-    there are specific rules to apply when combining multiple policies.
+    there are specific rules to apply when combining multiple policies. A
+    header value may contain several policies separated by commas; one Policy
+    is returned for each of them.
+
+  - opts (...Option): Optional settings that change how the policies are
+    parsed (e.g., WithTrace, MinSeverity).
+*/
+func Parse(currentURL, reportingEndpointsHeader string, policies []string, opts ...Option) ([]*Policy, error) {
+	return ParseContext(context.Background(), currentURL, reportingEndpointsHeader, policies, opts...)
+}
+
+/*
+ParseContext is like Parse, but stops early when the context is canceled or its
+deadline is exceeded. In that case, the policies parsed so far are returned, and
+the context's error is included in the returned errors.
+
+----
+
+  - ctx (context.Context): Controls cancellation and deadlines.
+
+See Parse for the remaining parameters.
 */
-func Parse(currentURL, reportingEndpointsHeader string, policies []string) ([]*Policy, error) {
+func ParseContext(
+	ctx context.Context,
+	currentURL, reportingEndpointsHeader string,
+	policies []string,
+	opts ...Option,
+) ([]*Policy, error) {
 	var (
 		key    string
 		values []string
 		errs   *multierror.Error
 
-		reWhitespace   = regexp.MustCompile(`\s+`)
 		parsedPolicies = []*Policy{}
+		cfg            = newConfig(opts)
 	)
 
-	if currentURL == "" {
+	if cfg.withoutSelf {
+		currentURL = ""
+	} else if currentURL == "" && !cfg.continued {
 		errs = multierror.Append(errs, fmt.Errorf(errCSP0001))
 	}
 
-	if reportingEndpointsHeader == "" {
+	currentURL, origin, err := parseCurrentURL(currentURL)
+	if err != nil && !cfg.continued {
+		errs = multierror.Append(errs, err)
+	}
+
+	if reportingEndpointsHeader == "" && !cfg.continued && !cfg.withoutReporting {
 		errs = multierror.Append(errs, fmt.Errorf(errCSP0002))
 	}
 
+	cfg.startStats()
+	cfg.currentURL = currentURL
+	cfg.reportingEndpoints = reportingEndpointsHeader
+	cfg.policyIndex = -1
+	cfg.traceDiagnostics(errorsOf(errs))
+
+	headerValues := len(policies)
+	policies, offsets, separators := splitSerializedPolicies(policies)
+
+	// Every caller expects at least one policy for each header value, so a list
+	// of only empty policies (e.g., `,`) is an error rather than no policy.
+	if headerValues > 0 && len(policies) == 0 {
+		errCount := len(errorsOf(errs))
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0030))
+		cfg.traceDiagnostics(errorsOf(errs)[errCount:])
+	}
+
 	for j := range policies {
 		policy := policies[j]
+		policyStarted := time.Now()
+		cfg.policyIndex = j + cfg.policyOffset
+		cfg.startPolicyStats(len(policy))
+
+		parsedPolicy := &Policy{
+			Disposition: cfg.disposition,
+			Delivery:    cfg.delivery,
+			Raw:         policy,
+			Origin:      origin,
+		}
+
+		if separators[j] != "" {
+			errCount := len(errorsOf(errs))
+			cfg.span = &Span{Start: offsets[j] - 1, End: offsets[j]}
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0914, separators[j]))
+			cfg.annotate(errs, errCount, PhaseParse)
+			cfg.traceDiagnostics(errorsOf(errs)[errCount:])
+			cfg.span = nil
+		}
+
+		if cfg.limits.MaxPolicyLength > 0 && len(policy) > cfg.limits.MaxPolicyLength {
+			errCount := len(errorsOf(errs))
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0008, len(policy), cfg.limits.MaxPolicyLength))
+			cfg.annotate(errs, errCount, PhaseParse)
+			cfg.traceDiagnostics(errorsOf(errs)[errCount:])
+
+			parsedPolicies = append(parsedPolicies, parsedPolicy)
+			cfg.endPolicyStats(policyStarted)
+
+			continue
+		}
 
 		rawDirectives := strings.Split(policy, ";")
-		parsedPolicy := &Policy{}
+		directiveOffset := offsets[j]
+		truncatedToken, truncated := truncatedTail(policy, offsets[j]+len(policy))
+		cfg.directiveOrder = cfg.directiveOrder[:0]
+		directiveCount := 0
+		seen := map[string]bool{}
 
 		for i := range rawDirectives {
-			directive := strings.TrimSpace(rawDirectives[i])
+			if err := ctx.Err(); err != nil {
+				errs = multierror.Append(errs, err)
+
+				return append(parsedPolicies, parsedPolicy), cfg.result(errs)
+			}
+
+			cfg.directiveIndex = -1
+			offset := directiveOffset
+			directiveOffset += len(rawDirectives[i]) + 1
+
+			trimmed, directive, nonASCIISpace := cfg.trimDirective(rawDirectives[i])
 
 			// Bail out early if the directive is empty.
 			// Or the last directive ends with a semicolon.
@@ -73,8 +180,20 @@ func Parse(currentURL, reportingEndpointsHeader string, policies []string) ([]*P
 				continue
 			}
 
-			directive = reWhitespace.ReplaceAllString(directive, " ")
-			kv := strings.Split(directive, " ")
+			kv, spans := splitDirective(directive, offset+len(rawDirectives[i])-len(trimmed))
+			cfg.span = &Span{Start: spans[0].Start, End: spans[len(spans)-1].End}
+
+			directiveCount++
+
+			if cfg.limits.MaxDirectives > 0 && directiveCount > cfg.limits.MaxDirectives {
+				errCount := len(errorsOf(errs))
+				errs = multierror.Append(errs, fmt.Errorf(errCSP0009, cfg.limits.MaxDirectives))
+				cfg.annotate(errs, errCount, PhaseParse)
+				cfg.traceDiagnostics(errorsOf(errs)[errCount:])
+
+				break
+			}
+
 			listItem := &SourceListItem{}
 			mediaTypeItem := &MediaTypeListItem{}
 			urlReference := &URLRef{}
@@ -82,127 +201,388 @@ func Parse(currentURL, reportingEndpointsHeader string, policies []string) ([]*P
 			sandboxToken := &SandboxToken{}
 			webrtcToken := &WebRTCToken{}
 			ancestorListItem := &AncestorSourceListItem{}
+			sinkGroups := &TrustedTypesSinkGroups{}
+			sriTypes := &SRIResourceTypes{}
+			referrerToken := &ReferrerToken{}
+
+			key = kv[0]
+			values = kv[1:]
 
-			if len(kv) > 0 {
-				key = kv[0]
-				values = kv[1:]
+			errCount := len(errorsOf(errs))
+
+			if cfg.limits.MaxSourcesPerDirective > 0 && len(values) > cfg.limits.MaxSourcesPerDirective {
+				errs = multierror.Append(
+					errs,
+					fmt.Errorf(errCSP0010, key, len(values), cfg.limits.MaxSourcesPerDirective),
+				)
+				values = values[:cfg.limits.MaxSourcesPerDirective]
+			}
+
+			parsedPolicy.Directives = append(parsedPolicy.Directives, RawDirective{
+				Name:       key,
+				Values:     values,
+				Raw:        rawDirectives[i],
+				NameSpan:   spans[0],
+				ValueSpans: spans[1 : len(values)+1],
+			})
+
+			if cfg.rawTokens {
+				parsedPolicy.Directives[len(parsedPolicy.Directives)-1].Tokens = rawTokens(key, values)
+			}
+			cfg.directiveIndex = len(parsedPolicy.Directives) - 1
+			cfg.valueSpans = spans[1 : len(values)+1]
+
+			cfg.countDirective(values)
+			cfg.directiveOrder = append(cfg.directiveOrder, strings.ToLower(key))
+			cfg.traceDirective(key, values, slices.Contains(knownDirectives, strings.ToLower(key)))
+
+			// Only the first offending token is reported, since browsers act on the
+			// directive as a whole. A Unicode host-source is reported as CSP-0108
+			// instead, since browsers convert it to Punycode.
+			hostValues := slices.Contains(sourceListDirectives, strings.ToLower(key)) ||
+				strings.EqualFold(key, "frame-ancestors")
+
+			for k, token := range kv[:len(values)+1] {
+				if k > 0 && hostValues && isIDNHostSource(trimHostPartDot(token)) {
+					continue
+				}
+
+				if r, kind, ok := invalidCharacter(token); ok {
+					err := fmt.Errorf(errCSP0912, key, token, kind, r)
+					if k > 0 {
+						err = atValue(k-1, err)
+					}
+
+					errs = multierror.Append(errs, err)
+
+					break
+				}
+			}
+
+			if nonASCIISpace && cfg.mode == ModeStrict {
+				errs = multierror.Append(errs, fmt.Errorf(errCSP0913, key))
 			}
 
+			// Browsers only enforce the first occurrence of a directive.
+			// https://www.w3.org/TR/2024/WD-CSP3-20240613/#parse-serialized-policy
+			if seen[strings.ToLower(key)] && slices.Contains(knownDirectives, strings.ToLower(key)) {
+				errs = multierror.Append(errs, fmt.Errorf(errCSP0907, key))
+				cfg.annotate(errs, errCount, PhaseParse)
+				cfg.traceDiagnostics(errorsOf(errs)[errCount:])
+
+				continue
+			}
+
+			seen[strings.ToLower(key)] = true
+
 			switch strings.ToLower(key) {
 			case "base-uri":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.BaseURI = append(parsedPolicy.BaseURI, *listItem)
 			case "block-all-mixed-content":
 				parsedPolicy.BlockAllMixedContent = true
+				errs = multierror.Append(errs, handleValueless(cfg, values, key))
 				errs = multierror.Append(errs, fmt.Errorf(errCSP0801, key))
 			case "child-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ChildSource = append(parsedPolicy.ChildSource, *listItem)
 				errs = multierror.Append(errs, fmt.Errorf(errCSP0802, key))
 			case "connect-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ConnectSource = append(parsedPolicy.ConnectSource, *listItem)
 			case "default-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.DefaultSource = append(parsedPolicy.DefaultSource, *listItem)
-			// case "fenced-frame-src":
-			// @TODO
+			case "fenced-frame-src":
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
+				parsedPolicy.FencedFrameSource = append(parsedPolicy.FencedFrameSource, *listItem)
 			case "font-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.FontSource = append(parsedPolicy.FontSource, *listItem)
 			case "form-action":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.FormAction = append(parsedPolicy.FormAction, *listItem)
 			case "frame-ancestors":
-				errs = multierror.Append(errs, handleAncestorExpr(values, key, ancestorListItem))
+				errs = multierror.Append(errs, handleAncestorExpr(cfg, values, key, ancestorListItem))
 				parsedPolicy.FrameAncestors = append(parsedPolicy.FrameAncestors, *ancestorListItem)
-				// Error on 'unsafe-eval' or 'unsafe-inline'
 			case "frame-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.FrameSource = append(parsedPolicy.FrameSource, *listItem)
 			case "img-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ImageSource = append(parsedPolicy.ImageSource, *listItem)
 			case "manifest-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ManifestSource = append(parsedPolicy.ManifestSource, *listItem)
 			case "media-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.MediaSource = append(parsedPolicy.MediaSource, *listItem)
 			case "navigate-to":
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
+				parsedPolicy.NavigateTo = append(parsedPolicy.NavigateTo, *listItem)
 				errs = multierror.Append(errs, fmt.Errorf(errCSP0803, key))
 			case "object-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ObjectSource = append(parsedPolicy.ObjectSource, *listItem)
 			case "plugin-types":
-				errs = multierror.Append(errs, handlePluginTypes(values, key, mediaTypeItem))
+				errs = multierror.Append(errs, handlePluginTypes(cfg, values, key, mediaTypeItem))
 				parsedPolicy.PluginTypes = append(parsedPolicy.PluginTypes, *mediaTypeItem)
 				errs = multierror.Append(errs, fmt.Errorf(errCSP0804, key))
 			case "prefetch-src":
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
+				parsedPolicy.PrefetchSource = append(parsedPolicy.PrefetchSource, *listItem)
 				errs = multierror.Append(errs, fmt.Errorf(errCSP0803, key))
+				errs = multierror.Append(errs, fmt.Errorf(errCSP0809, key))
 			case "referrer":
-				errs = multierror.Append(errs, fmt.Errorf(errCSP0803, key))
-			case "report-to":
-				value := ""
 				if len(values) != 1 {
-					errs = multierror.Append(errs, fmt.Errorf(errCSP0501, key))
+					errs = multierror.Append(errs, fmt.Errorf(errCSP0811, key))
 				}
 
-				value = values[0]
-				errs = multierror.Append(errs, handleReportTo(value, key, reportingEndpointsHeader, reportingReference))
+				if len(values) > 0 {
+					errs = multierror.Append(errs, atValue(0, handleReferrer(cfg, values[0], key, referrerToken)))
+					parsedPolicy.Referrer = append(parsedPolicy.Referrer, *referrerToken)
+				}
+
+				errs = multierror.Append(errs, fmt.Errorf(errCSP0803, key))
+			case "report-to":
+				errs = multierror.Append(
+					errs,
+					handleReportTo(cfg, values, key, reportingEndpointsHeader, reportingReference),
+				)
 				parsedPolicy.ReportTo = append(parsedPolicy.ReportTo, *reportingReference)
 			case "report-uri":
-				errs = multierror.Append(errs, handleReportingURLs(values, key, urlReference))
+				errs = multierror.Append(errs, handleReportingURLs(cfg, values, key, urlReference))
 				parsedPolicy.ReportURI = append(parsedPolicy.ReportURI, *urlReference)
 				errs = multierror.Append(errs, fmt.Errorf(errCSP0805, key))
-			// case "require-trusted-types-for":
-			// @TODO
+			case "require-sri-for":
+				errs = multierror.Append(errs, handleRequireSRIFor(cfg, values, key, sriTypes))
+				parsedPolicy.RequireSRIFor = append(parsedPolicy.RequireSRIFor, *sriTypes)
+				errs = multierror.Append(errs, fmt.Errorf(errCSP0806, key))
+			case "require-trusted-types-for":
+				errs = multierror.Append(errs, handleTrustedTypesSinkGroups(cfg, values, key, sinkGroups))
+				parsedPolicy.RequireTrustedTypes = append(parsedPolicy.RequireTrustedTypes, *sinkGroups)
 			case "sandbox":
-				errs = multierror.Append(errs, handleSandbox(values, key, sandboxToken))
+				errs = multierror.Append(errs, handleSandbox(cfg, values, key, sandboxToken))
 				parsedPolicy.Sandbox = append(parsedPolicy.Sandbox, *sandboxToken)
 			case "script-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ScriptSource = append(parsedPolicy.ScriptSource, *listItem)
 			case "script-src-attr":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ScriptSourceAttr = append(parsedPolicy.ScriptSourceAttr, *listItem)
 			case "script-src-elem":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.ScriptSourceElem = append(parsedPolicy.ScriptSourceElem, *listItem)
 			case "style-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.StyleSource = append(parsedPolicy.StyleSource, *listItem)
 			case "style-src-attr":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.StyleSourceAttr = append(parsedPolicy.StyleSourceAttr, *listItem)
 			case "style-src-elem":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.StyleSourceElem = append(parsedPolicy.StyleSourceElem, *listItem)
 			// case "trusted-types":
 			// @TODO
 			case "upgrade-insecure-requests":
 				parsedPolicy.UpgradeInsecureReq = true
+				errs = multierror.Append(errs, handleValueless(cfg, values, key))
 			case "webrtc":
-				value := ""
 				if len(values) != 1 {
 					errs = multierror.Append(errs, fmt.Errorf(errCSP0601, key))
 				}
 
-				value = values[0]
-				errs = multierror.Append(errs, handleWebRTC(value, key, webrtcToken))
+				if len(values) == 0 {
+					break
+				}
+
+				errs = multierror.Append(errs, atValue(0, handleWebRTC(cfg, values[0], key, webrtcToken)))
 				parsedPolicy.WebRTC = *webrtcToken
 			case "worker-src":
-				errs = multierror.Append(errs, handleSourceExpr(values, key, listItem))
+				errs = multierror.Append(errs, handleSourceExpr(cfg, values, key, listItem))
 				parsedPolicy.WorkerSource = append(parsedPolicy.WorkerSource, *listItem)
 			default:
-				errs = multierror.Append(errs, fmt.Errorf(errCSP0901, key))
+				if previous := previousDirective(cfg.directiveOrder); misplacedValue(previous, key) {
+					errs = multierror.Append(errs, fmt.Errorf(errCSP0915, key, previous))
+				} else {
+					errs = multierror.Append(errs, unknownDirective(cfg, key, values))
+				}
+
+				parsedPolicy.Unknown = append(parsedPolicy.Unknown, RawDirective{
+					Name:       key,
+					Values:     values,
+					NameSpan:   spans[0],
+					ValueSpans: spans[1 : len(values)+1],
+				})
+			}
+
+			// The diagnostics for a truncated directive would only be confusing,
+			// so they are replaced by one that explains the cause.
+			if truncated && i == len(rawDirectives)-1 {
+				if errs != nil {
+					errs.Errors = errs.Errors[:errCount]
+				}
+
+				err := fmt.Errorf(errCSP0023, truncatedToken)
+				if len(values) > 0 && values[len(values)-1] == truncatedToken {
+					err = atValue(len(values)-1, err)
+				}
+
+				errs = multierror.Append(errs, err)
 			}
+
+			cfg.annotate(errs, errCount, PhaseParse)
+			cfg.traceDiagnostics(errorsOf(errs)[errCount:])
 		}
 
+		cfg.span = nil
+		cfg.directiveIndex = -1
+
+		errCount := len(errorsOf(errs))
+		errs = multierror.Append(errs, checkPolicy(cfg, parsedPolicy))
+		cfg.annotate(errs, errCount, PhasePolicy)
+		cfg.traceDiagnostics(errorsOf(errs)[errCount:])
+
 		parsedPolicies = append(parsedPolicies, parsedPolicy)
+		cfg.endPolicyStats(policyStarted)
+	}
+
+	return parsedPolicies, cfg.result(errs)
+}
+
+/*
+unknownDirective returns the diagnostic for a directive name that the parser
+does not recognize. In strict mode, names that do not match the directive-name
+grammar get a more specific diagnostic (e.g., `script src`, where a space was
+typed instead of `-`). Names that are close to a known directive (e.g.,
+`scritp-src`) include a suggestion.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#framework-directives
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - key (string): The name of the directive.
+
+  - values ([]string): The values of the directive.
+*/
+func unknownDirective(cfg *config, key string, values []string) error {
+	// directive-name = 1*( ALPHA / DIGIT / "-" )
+	reDirectiveName := regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+
+	if cfg.mode != ModeStrict {
+		return unknownDirectiveSuggestion(key)
+	}
+
+	if len(values) > 0 {
+		joined := strings.ToLower(key + "-" + values[0])
+
+		if slices.Contains(knownDirectives, joined) {
+			return fmt.Errorf(errCSP0903, key+" "+values[0], joined)
+		}
+	}
+
+	if !reDirectiveName.MatchString(key) {
+		return fmt.Errorf(errCSP0902, key)
+	}
+
+	return unknownDirectiveSuggestion(key)
+}
+
+// unknownDirectiveSuggestion returns CSP-0916 when the unknown directive name is
+// close to a known one, or CSP-0901 otherwise.
+func unknownDirectiveSuggestion(key string) error {
+	if suggestion, ok := suggestDirective(key); ok {
+		return fmt.Errorf(errCSP0916, key, suggestion)
+	}
+
+	return fmt.Errorf(errCSP0901, key)
+}
+
+/*
+splitSerializedPolicies splits header values that contain more than one policy,
+separated by commas, into one string per policy. Empty policies between commas
+are dropped, as browsers do, so the result may be empty (e.g., for `,`). It also
+returns the byte offset of each policy within its header value, and, for each
+policy that follows a comma that looks like a separator mistake (see
+commaMistake), the tokens around that comma.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#parse-serialized-policy-list
+
+----
+
+  - policies ([]string): A slice of header values, each containing one or more
+    serialized policies.
+*/
+func splitSerializedPolicies(policies []string) (split []string, offsets []int, separators []string) {
+	split = make([]string, 0, len(policies))
+	offsets = make([]int, 0, len(policies))
+	separators = make([]string, 0, len(policies))
+
+	for i := range policies {
+		if !strings.Contains(policies[i], ",") {
+			split = append(split, policies[i])
+			offsets = append(offsets, 0)
+			separators = append(separators, "")
+
+			continue
+		}
+
+		offset := 0
+
+		for _, policy := range strings.Split(policies[i], ",") {
+			if strings.TrimSpace(policy) != "" {
+				context, _ := commaMistake(policies[i][:max(offset-1, 0)], policy)
+
+				split = append(split, policy)
+				offsets = append(offsets, offset)
+				separators = append(separators, context)
+			}
+
+			offset += len(policy) + 1
+		}
+	}
+
+	return split, offsets, separators
+}
+
+/*
+splitDirective splits a directive into its name and values on ASCII whitespace,
+and returns the span of each token.
+
+----
+
+  - directive (string): The text of the directive between semicolons.
+
+  - offset (int): The byte offset of the directive within its header value.
+*/
+func splitDirective(directive string, offset int) (tokens []string, spans []Span) {
+	start := -1
+
+	for i := 0; i <= len(directive); i++ {
+		if i < len(directive) && !isASCIIWhitespace(directive[i]) {
+			if start < 0 {
+				start = i
+			}
+
+			continue
+		}
+
+		if start >= 0 {
+			tokens = append(tokens, directive[start:i])
+			spans = append(spans, Span{Start: offset + start, End: offset + i})
+			start = -1
+		}
 	}
 
-	return parsedPolicies, errs.ErrorOrNil()
+	return tokens, spans
+}
+
+// isASCIIWhitespace reports whether the byte is ASCII whitespace, as matched by
+// `\s` in regular expressions.
+func isASCIIWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
 }
 
 /*
@@ -243,227 +623,846 @@ func isHostSource(s string) bool {
 	// path-part   = <https://datatracker.ietf.org/doc/html/rfc3986#section-3.3>
 	// port-part   = 1*DIGIT / "*"
 	reHostSource := regexp.MustCompile(
-		`^([a-zA-Z][a-zA-Z0-9+-.]*://)?(\*|(\*)?\.?([a-zA-Z0-9-]+))+(:(\*|[0-9]+))?(/[^/]+)*$`,
+		`^([a-zA-Z][a-zA-Z0-9+-.]*://)?(\*|(\*)?\.?([a-zA-Z0-9-]+))+(:(\*|[0-9]+))?$`,
 	)
 
 	reIPv4Dumb := regexp.MustCompile(`^(([0-9]{1,3}[.]){3}[0-9]{1,3})$`)
 
+	if hasUserinfo(s) || hasFragment(s) {
+		return false
+	}
+
+	s, path := cutHostSourcePath(s)
+	if !isValidPathPart(path) {
+		return false
+	}
+
 	return s == "127.0.0.1" || (reHostSource.MatchString(s) && !reIPv4Dumb.MatchString(s))
 }
 
+// cutHostSourcePath splits a host-source into everything before its path-part,
+// and the path-part (including the leading `/`).
+func cutHostSourcePath(s string) (before, path string) {
+	start := 0
+	if i := strings.Index(s, "://"); i >= 0 {
+		start = i + len("://")
+	}
+
+	if i := strings.Index(s[start:], "/"); i >= 0 {
+		return s[:start+i], s[start+i:]
+	}
+
+	return s, ""
+}
+
 /*
-isValidIPv4 checks whether or not the string is a valid IPv4 address. Allows IP
-"addresses" that are part of CIDR syntax (e.g., `.0`).
+isValidPathPart checks whether or not the path-part of a host-source matches
+`path-abempty` from RFC 3986 §3.3, including well-formed percent-encoding.
+Empty segments (e.g., a trailing `/`) are rejected, as they always have been.
 
-  - https://regex101.com/r/9mNoiZ/1
+	path-abempty = *( "/" segment )
+	segment      = *pchar
+	pchar        = unreserved / pct-encoded / sub-delims / ":" / "@"
+
+https://datatracker.ietf.org/doc/html/rfc3986#section-3.3
 
 ----
 
-  - s (string): The value that will be evaluated.
+  - path (string): The path-part, including the leading `/`, or an empty string.
 */
-func isValidIPv4(s string) bool {
-	reIPv4 := regexp.MustCompile(
-		`^(?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})[.](?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})[.]` +
-			`(?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})[.](?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})$`,
-	)
+func isValidPathPart(path string) bool {
+	rePathAbempty := regexp.MustCompile(`^(/([a-zA-Z0-9._~!$&'()*+,;=:@-]|%[0-9a-fA-F]{2})+)*$`)
 
-	return reIPv4.MatchString(s)
+	return rePathAbempty.MatchString(path)
 }
 
 /*
-isKeywordSource checks whether or not the string matches the keywords and
-quotations below.
-
-	'self', 'report-sample', 'strict-dynamic', 'unsafe-eval', 'unsafe-hashes',
-	'unsafe-inline', 'unsafe-allow-redirects', 'wasm-unsafe-eval'
-
-https://www.w3.org/TR/2024/WD-CSP3-20240613/#grammardef-keyword-source
+hasInvalidPath checks whether or not the value would be a valid host-source,
+except for its path-part (e.g., `cdn.example.com/%zz/foo`).
 
 ----
 
   - s (string): The value that will be evaluated.
 */
-func isKeywordSource(s string) bool {
-	return strings.EqualFold(s, `'self'`) ||
-		strings.EqualFold(s, `'report-sample'`) ||
-		strings.EqualFold(s, `'strict-dynamic'`) ||
-		strings.EqualFold(s, `'unsafe-eval'`) ||
-		strings.EqualFold(s, `'unsafe-hashes'`) ||
-		strings.EqualFold(s, `'unsafe-inline'`) ||
-		strings.EqualFold(s, `'unsafe-allow-redirects'`) ||
-		strings.EqualFold(s, `'wasm-unsafe-eval'`)
+func hasInvalidPath(s string) bool {
+	before, path := cutHostSourcePath(s)
+
+	return path != "" && !isValidPathPart(path) && isHostSource(before)
 }
 
 /*
-isSandboxSource checks whether or not the string matches the keywords below.
-
-	allow-forms, allow-pointer-lock, allow-popups, allow-same-origin,
-	allow-scripts, allow-top-navigation
-
-https://www.w3.org/TR/CSP2/#sandbox-usage
+hostSourcePort returns the port-part of a host-source. A missing port-part is
+returned as 0, and `*` sets anyPort. The port-part is invalid (ok is false) if it
+is not `*` or a number from 1 to 65535.
 
 ----
 
-  - s (string): The value that will be evaluated.
+  - s (string): A value for which isHostSource is true.
 */
-func isSandboxSource(s string) bool {
-	return strings.EqualFold(s, `allow-downloads`) ||
-		strings.EqualFold(s, `allow-forms`) ||
-		strings.EqualFold(s, `allow-modals`) ||
-		strings.EqualFold(s, `allow-orientation-lock`) ||
-		strings.EqualFold(s, `allow-pointer-lock`) ||
-		strings.EqualFold(s, `allow-popups`) ||
-		strings.EqualFold(s, `allow-popups-to-escape-sandbox`) ||
-		strings.EqualFold(s, `allow-presentation`) ||
-		strings.EqualFold(s, `allow-same-origin`) ||
-		strings.EqualFold(s, `allow-scripts`) ||
-		strings.EqualFold(s, `allow-top-navigation`) ||
-		strings.EqualFold(s, `allow-top-navigation-by-user-activation`) ||
-		strings.EqualFold(s, `allow-top-navigation-to-custom-protocols`)
+func hostSourcePort(s string) (port int, anyPort, ok bool) {
+	_, _, portPart, _ := hostsource.Split(s)
+
+	switch portPart {
+	case "":
+		return 0, false, true
+	case "*":
+		return 0, true, true
+	}
+
+	port, err := strconv.Atoi(portPart)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, false, false
+	}
+
+	return port, false, true
 }
 
 /*
-isNonceSource checks whether or not the string matches the required pattern.
+hostSourceToASCII converts a host-source with an internationalized (Unicode)
+host-part to its ASCII form, with each label converted to punycode (an
+A-label). ok is false if the host-part is already ASCII, or if the result is not
+a valid host-source.
 
 ----
 
   - s (string): The value that will be evaluated.
 */
-func isNonceSource(s string) bool {
-	// nonce-value  = base64-value
-	// nonce-source = "'nonce-" nonce-value "'"
-	reNonceSource := regexp.MustCompile(`^(?i)'nonce-[a-zA-Z0-9+/]*={0,2}'$`)
+func hostSourceToASCII(s string) (ascii string, ok bool) {
+	scheme, host, port, path := hostsource.Split(s)
+	if isASCII(host) {
+		return "", false
+	}
 
-	return reNonceSource.MatchString(s) && len(s) > 9
+	wildcard := strings.HasPrefix(host, "*.")
+	host = strings.TrimPrefix(host, "*.")
+
+	host, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", false
+	}
+
+	if wildcard {
+		host = "*." + host
+	}
+
+	ascii = hostsource.Join(scheme, host, port, path)
+
+	return ascii, isHostSource(ascii)
 }
 
 /*
-isHashSource checks whether or not the string matches the required pattern.
+isIDNHostSource checks whether or not the value is a host-source with an
+internationalized (Unicode) host-part (e.g., `οὐτοπία.δπθ.gr`).
 
 ----
 
   - s (string): The value that will be evaluated.
 */
-func isHashSource(s string) bool {
-	// hash-value  = base64-value
-	// hash-algo   = "sha256" / "sha384" / "sha512"
-	// hash-source = "'" hash-algo "-" hash-value "'"
-	reHashSource := regexp.MustCompile(`^(?i)'sha(256|384|512)-[a-zA-Z0-9+/]*={0,2}'$`)
+func isIDNHostSource(s string) bool {
+	_, ok := hostSourceToASCII(s)
 
-	return reHashSource.MatchString(s) && len(s) > 10
+	return ok
 }
 
 /*
-isMediaType checks whether or not the string matches the patterns used in the
-IANA Registered Media Types document.
+invalidCharacter returns the first character of a directive name or value that
+the CSP grammar does not allow: a non-ASCII character, or an ASCII control
+character. Whitespace never reaches this check, since it separates tokens.
 
-  - https://www.iana.org/assignments/media-types/media-types.xhtml
+	directive-name  = 1*( ALPHA / DIGIT / "-" )
+	directive-value = *( required-ascii-whitespace / ( %x21-%x2B / %x2D-%x3A / %x3C-%x7E ) )
 
 ----
 
-  - s (string): The value that will be evaluated.
+  - s (string): The directive name or value.
 */
-func isMediaType(s string) bool {
-	reMediaType := regexp.MustCompile(
-		`^(?i)(application|audio|font|example|image|message|model|multipart|text|video)/[a-zA-Z0-9_./+-]+$`,
-	)
+func invalidCharacter(s string) (r rune, kind string, ok bool) {
+	if isASCII(s) {
+		for _, r := range s {
+			if unicode.IsControl(r) {
+				return r, "a control character", true
+			}
+		}
 
-	return reMediaType.MatchString(s)
+		return 0, "", false
+	}
+
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return r, "a non-ASCII character", true
+		}
+	}
+
+	return 0, "", false
 }
 
-/*
-isValidReportingURL checks whether or not the string is a valid URL that can be
-used as a reporting URL. Implements the URL Living Standard (as of 2023-05-24).
+// isASCII reports whether the string contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
 
-  - https://url.spec.whatwg.org/commit-snapshots/eee49fdf4f99d59f717cbeb0bce29fda930196d4/
+	return true
+}
+
+/*
+hasUserinfo checks whether or not the authority of a host-source contains
+userinfo (e.g., `user@` or `user:password@`), which host-sources may not have.
+An `@` in the path-part (e.g., `cdn.example.com/npm/pkg@1.0/`) is allowed.
 
 ----
 
   - s (string): The value that will be evaluated.
 */
-func isValidReportingURL(s string) bool {
-	url, err := url.Parse(s)
-	if err != nil {
-		return false
+func hasUserinfo(s string) bool {
+	_, authority, _ := strings.Cut(s, "://")
+	if authority == "" {
+		authority = s
 	}
 
-	// URL fragment is not allowed.
-	if url.Href(true) != url.Href(false) {
-		return false
-	}
+	authority, _, _ = strings.Cut(authority, "/")
 
-	return true
+	return strings.Contains(authority, "@")
 }
 
 /*
-isWebRTCSource checks whether or not the string matches the required pattern.
+hasFragment checks whether or not a host-source contains a fragment (e.g.,
+`#main`), which host-sources may not have.
 
 ----
 
   - s (string): The value that will be evaluated.
 */
-func isWebRTCSource(s string) bool {
-	return strings.EqualFold(s, `'allow'`) || strings.EqualFold(s, `'block'`)
+func hasFragment(s string) bool {
+	return strings.Contains(s, "#")
 }
 
 /*
-handleSourceExpr handles the "source expression" type for the various
-directives. Given a common CSP directive:
-
-	directive value1 value2 value3 value4
+isValidIPv4 checks whether or not the string is a valid IPv4 address. Allows IP
+"addresses" that are part of CIDR syntax (e.g., `.0`).
 
-…this function will parse the values and determine if they are valid source
-expressions. If they are, they will be added to the SourceListItem struct.
+  - https://regex101.com/r/9mNoiZ/1
 
 ----
 
-  - values ([]string): A slice of strings, each representing a value for the
-    directive. (value*, above)
+  - s (string): The value that will be evaluated.
+*/
+func isValidIPv4(s string) bool {
+	reIPv4 := regexp.MustCompile(
+		`^(?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})[.](?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})[.]` +
+			`(?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})[.](?:25[0-5]|2[0-4][0-9]|[0-1]?[0-9]{1,2})$`,
+	)
 
-  - key (string): The name of the directive. (directive, above)
+	return reIPv4.MatchString(s)
+}
 
-  - listItem (*SourceListItem): A pointer to the SourceListItem struct that will
+/*
+isKeywordSource checks whether or not the string matches the keywords and
+quotations below.
+
+	'self', 'report-sample', 'strict-dynamic', 'unsafe-eval', 'unsafe-hashes',
+	'unsafe-inline', 'unsafe-allow-redirects', 'wasm-unsafe-eval',
+	'inline-speculation-rules'
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#grammardef-keyword-source
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isKeywordSource(s string) bool {
+	for i := range keywordSources {
+		if strings.EqualFold(s, keywordSources[i]) {
+			return true
+		}
+	}
+
+	_, ok := registeredKeyword(s)
+
+	return ok
+}
+
+// isUnsafeKeyword reports whether the value is an `'unsafe-*'` keyword, including
+// ones that are not defined (e.g., `'unsafe-everything'`).
+func isUnsafeKeyword(s string) bool {
+	return len(s) > len(`'unsafe-'`) && strings.HasSuffix(s, "'") &&
+		strings.HasPrefix(strings.ToLower(s), `'unsafe-`)
+}
+
+// isScriptElemDirective reports whether the directive governs `<script>`
+// elements, directly or as a fallback.
+func isScriptElemDirective(key string) bool {
+	return slices.Contains(directiveFallbackList["script-src-elem"], strings.ToLower(key))
+}
+
+/*
+nonceHashDirectives returns the directives in which browsers check nonce-sources
+or hash-sources: the ones that govern `<script>` and `<style>` elements, directly
+or as a fallback, and for hash-sources, also the ones that govern inline event
+handlers and `style` attributes.
+
+----
+
+  - hash (bool): Whether to return the directives for hash-sources instead of
+    nonce-sources.
+*/
+func nonceHashDirectives(hash bool) []string {
+	governed := []string{"script-src-elem", "style-src-elem"}
+	if hash {
+		governed = append(governed, "script-src-attr", "style-src-attr")
+	}
+
+	var directives []string
+
+	for _, directive := range governed {
+		for _, fallback := range directiveFallbackList[directive] {
+			if !slices.Contains(directives, fallback) {
+				directives = append(directives, fallback)
+			}
+		}
+	}
+
+	return directives
+}
+
+/*
+isSandboxSource checks whether or not the string is one of the tokens in
+sandboxRegistry.
+
+https://www.w3.org/TR/CSP2/#sandbox-usage
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isSandboxSource(s string) bool {
+	_, ok := lookupSandboxToken(s)
+
+	return ok
+}
+
+/*
+isNonceSource checks whether or not the string matches the required pattern.
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isNonceSource(s string) bool {
+	// nonce-value  = base64-value
+	// nonce-source = "'nonce-" nonce-value "'"
+	reNonceSource := regexp.MustCompile(`^(?i)'nonce-[a-zA-Z0-9+/]*={0,2}'$`)
+
+	return reNonceSource.MatchString(s) && len(s) > 9
+}
+
+// minNonceEntropy is the minimum entropy of a nonce, in bits.
+//
+// https://www.w3.org/TR/2024/WD-CSP3-20240613/#security-nonces
+const minNonceEntropy = 128
+
+/*
+nonceEntropy estimates the entropy of a nonce in bits, as its length times the
+bits per character of the smallest alphabet that it fits in (digits, hex
+digits, letters and digits, or base64). A nonce of decimal digits carries far
+less entropy than its length suggests.
+
+----
+
+  - nonce (string): The value of a nonce-source, without `'nonce-` and `'`.
+*/
+func nonceEntropy(nonce string) int {
+	nonce = strings.TrimRight(nonce, "=")
+
+	var bitsPerChar float64
+
+	switch {
+	case strings.Trim(nonce, "0123456789") == "":
+		bitsPerChar = math.Log2(10)
+	case strings.Trim(strings.ToLower(nonce), "0123456789abcdef") == "":
+		bitsPerChar = 4
+	case !strings.ContainsAny(nonce, "+/-_"):
+		bitsPerChar = math.Log2(62)
+	default:
+		bitsPerChar = 6
+	}
+
+	return int(float64(len(nonce)) * bitsPerChar)
+}
+
+/*
+isPatternedNonce checks whether or not a nonce follows an obvious pattern, which
+makes it guessable regardless of its length: (almost) a single repeated
+character, a sequence (e.g., `12345` or `abcdef`), or a short repeated unit
+(e.g., `abcabcabc`).
+
+----
+
+  - nonce (string): The value of a nonce-source, without `'nonce-` and `'`.
+*/
+func isPatternedNonce(nonce string) bool {
+	nonce = strings.TrimRight(nonce, "=")
+
+	distinct := map[rune]bool{}
+	for _, r := range nonce {
+		distinct[r] = true
+	}
+
+	if len(distinct) <= 2 {
+		return true
+	}
+
+	ascending, descending := true, true
+
+	for i := 1; i < len(nonce); i++ {
+		ascending = ascending && nonce[i] == nonce[i-1]+1
+		descending = descending && nonce[i] == nonce[i-1]-1
+	}
+
+	if ascending || descending {
+		return true
+	}
+
+	for size := 1; size <= 4 && size*2 <= len(nonce); size++ {
+		if strings.Repeat(nonce[:size], len(nonce)/size+1)[:len(nonce)] == nonce {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+noneConflicts returns the other values of a source list that contains `'none'`.
+Per the grammar, `'none'` must be the only value; browsers ignore it otherwise.
+
+----
+
+  - values ([]string): The values of the directive.
+*/
+func noneConflicts(values []string) []string {
+	isNone := func(v string) bool { return strings.EqualFold(v, `'none'`) }
+
+	if len(values) < 2 || !slices.ContainsFunc(values, isNone) {
+		return nil
+	}
+
+	return slices.DeleteFunc(slices.Clone(values), isNone)
+}
+
+/*
+sourceKey returns the form of a source expression used to detect duplicates.
+Keywords, schemes, and hosts are case-insensitive, but the base64 values of
+nonces and hashes are not.
+
+----
+
+  - value (string): A single source expression.
+*/
+func sourceKey(value string) string {
+	if isNonceSource(value) || isHashSource(value) {
+		prefix, payload, _ := strings.Cut(value, "-")
+
+		return strings.ToLower(prefix) + "-" + payload
+	}
+
+	return strings.ToLower(value)
+}
+
+/*
+isHashSource checks whether or not the string matches the required pattern.
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isHashSource(s string) bool {
+	// hash-value  = base64-value
+	// hash-algo   = "sha256" / "sha384" / "sha512"
+	// hash-source = "'" hash-algo "-" hash-value "'"
+	reHashSource := regexp.MustCompile(`^(?i)'sha(256|384|512)-[a-zA-Z0-9+/]*={0,2}'$`)
+
+	return reHashSource.MatchString(s) && len(s) > 10
+}
+
+/*
+hashDigestLength returns the algorithm of a hash-source, the length in bytes of
+its base64-decoded digest, and the length that the algorithm produces. A digest
+of any other length can never match.
+
+----
+
+  - s (string): A value for which isHashSource is true.
+*/
+func hashDigestLength(s string) (algo string, got, want int) {
+	algo, value, _ := strings.Cut(strings.Trim(s, "'"), "-")
+	algo = strings.ToLower(algo)
+
+	switch algo {
+	case "sha256":
+		want = sha256.Size
+	case "sha384":
+		want = sha512.Size384
+	default:
+		want = sha512.Size
+	}
+
+	// Each base64 character carries 6 bits; padding carries none.
+	got = len(strings.TrimRight(value, "=")) * 6 / 8
+
+	return algo, got, want
+}
+
+/*
+isMediaType checks whether or not the string matches the patterns used in the
+IANA Registered Media Types document.
+
+  - https://www.iana.org/assignments/media-types/media-types.xhtml
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isMediaType(s string) bool {
+	reMediaType := regexp.MustCompile(
+		`^(?i)(application|audio|font|example|image|message|model|multipart|text|video)/[a-zA-Z0-9_./+-]+$`,
+	)
+
+	return reMediaType.MatchString(s)
+}
+
+/*
+isValidReportingURL checks whether or not the string is a valid URL that can be
+used as a reporting URL. Implements the URL Living Standard (as of 2023-05-24).
+
+  - https://url.spec.whatwg.org/commit-snapshots/eee49fdf4f99d59f717cbeb0bce29fda930196d4/
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isValidReportingURL(s string) bool {
+	url, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	// URL fragment is not allowed.
+	if url.Href(true) != url.Href(false) {
+		return false
+	}
+
+	return true
+}
+
+// relativeReferenceBase is the base URL that relative references in
+// `report-uri` are parsed against when they cannot be resolved.
+const relativeReferenceBase = "https://relative.invalid/"
+
+/*
+resolveReportingURL resolves a relative reference in `report-uri` against the
+URL of the protected document, as browsers do. ok is false when there is no
+currentURL, or the reference does not resolve to a valid reporting URL.
+
+https://www.w3.org/TR/CSP3/#directive-report-uri
+
+----
+
+  - currentURL (string): The URL of the protected document. May be empty.
+
+  - ref (string): The value of `report-uri`.
+*/
+func resolveReportingURL(currentURL, ref string) (resolved string, ok bool) {
+	if currentURL == "" {
+		return "", false
+	}
+
+	u, err := url.ParseRef(currentURL, ref)
+	if err != nil || u.Fragment() != "" || strings.Contains(ref, "#") {
+		return "", false
+	}
+
+	return u.Href(false), true
+}
+
+/*
+isWebRTCSource checks whether or not the string matches the required pattern.
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isWebRTCSource(s string) bool {
+	return strings.EqualFold(s, `'allow'`) || strings.EqualFold(s, `'block'`)
+}
+
+/*
+referrerPolicyFor returns the `Referrer-Policy` header value that replaces a
+value of the obsolete `referrer` directive. Values from every draft of the
+directive are accepted, with or without single quotes.
+
+https://www.w3.org/TR/referrer-policy/#referrer-policies
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func referrerPolicyFor(s string) (string, bool) {
+	switch strings.ToLower(strings.Trim(s, `'`)) {
+	case "never", "none", "no-referrer":
+		return "no-referrer", true
+	case "default", "none-when-downgrade", "no-referrer-when-downgrade":
+		return "no-referrer-when-downgrade", true
+	case "origin":
+		return "origin", true
+	case "origin-when-crossorigin", "origin-when-cross-origin":
+		return "origin-when-cross-origin", true
+	case "always", "unsafe-url":
+		return "unsafe-url", true
+	}
+
+	return "", false
+}
+
+/*
+isSRIResourceType checks whether or not the string is a resource type for the
+obsolete `require-sri-for` directive.
+
+https://w3c.github.io/webappsec-subresource-integrity/#opt-in-require-sri-for
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isSRIResourceType(s string) bool {
+	return strings.EqualFold(s, "script") || strings.EqualFold(s, "style")
+}
+
+/*
+isTrustedTypesSinkGroup checks whether or not the string is a valid Trusted Types
+sink group.
+
+https://w3c.github.io/trusted-types/dist/spec/#require-trusted-types-for-csp-directive
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func isTrustedTypesSinkGroup(s string) bool {
+	return strings.EqualFold(s, `'script'`)
+}
+
+/*
+handleSourceExpr handles the "source expression" type for the various
+directives. Given a common CSP directive:
+
+	directive value1 value2 value3 value4
+
+…this function will parse the values and determine if they are valid source
+expressions. If they are, they will be added to the SourceListItem struct.
+
+----
+
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
+  - values ([]string): A slice of strings, each representing a value for the
+    directive. (value*, above)
+
+  - key (string): The name of the directive. (directive, above)
+
+  - listItem (*SourceListItem): A pointer to the SourceListItem struct that will
     be populated with the source expressions. This acts as a "collector".
 */
-func handleSourceExpr(values []string, key string, listItem *SourceListItem) error {
+func handleSourceExpr(cfg *config, values []string, key string, listItem *SourceListItem) error {
 	var errs *multierror.Error
 
-	// source-expression = scheme-source / host-source / keyword-source
-	//                     / nonce-source / hash-source
-	for i := range values {
-		switch {
-		case values[i] == `'none'`:
-			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
-				None: true,
-			})
-		case isSchemeSource(values[i]):
-			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
-				SchemeSource: values[i],
-			})
-		case isHostSource(values[i]):
+	if len(values) == 0 {
+		listItem.Empty = true
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0114, key))
+	}
+
+	seen := make(map[string]struct{}, len(values))
+
+	// source-expression = scheme-source / host-source / keyword-source
+	//                     / nonce-source / hash-source
+	for i := range values {
+		if _, ok := seen[sourceKey(values[i])]; ok {
+			cfg.traceToken(key, values[i], ClassDuplicate)
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0115, key, values[i])))
+
+			continue
+		}
+
+		seen[sourceKey(values[i])] = struct{}{}
+
+		switch {
+		case strings.EqualFold(values[i], `'none'`):
+			cfg.traceToken(key, values[i], ClassNone)
+
+			// `'none'` replaces the whole list, so it is recorded on the list
+			// rather than as an expression.
+			listItem.None = true
+		case isSchemeSource(values[i]):
+			cfg.traceToken(key, values[i], ClassSchemeSource)
+
+			if isJavaScriptScheme(values[i]) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0122, key, values[i])))
+			} else if err := cfg.schemeRiskError(key, values[i]); err != nil {
+				errs = multierror.Append(errs, atValue(i, err))
+			}
+
+			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
+				SchemeSource: values[i],
+				SchemeRisk:   ClassifyScheme(values[i]),
+			})
+		case cfg.isHostSource(values[i]) || isIDNHostSource(trimHostPartDot(values[i])):
+			host, unicodeHost := trimHostPartDot(values[i]), ""
+			trailingDot := host != values[i]
+
+			if isJavaScriptScheme(values[i]) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0122, key, values[i])))
+			}
+
+			if suggestion, ok := suggestKeyword(values[i], keywordCandidates()); ok {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0124, key, values[i], suggestion)))
+			}
+
+			if trailingDot {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0118, key, values[i], host)))
+			}
+
+			if ascii, ok := hostSourceToASCII(host); ok {
+				host, unicodeHost = ascii, values[i]
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0108, key, values[i], ascii)))
+			}
+
+			ok, hostErrs := cfg.hostPartErrors(key, values[i], host)
+			errs = multierror.Append(errs, atValue(i, multierror.Append(nil, hostErrs...)))
+
+			if !ok {
+				cfg.traceToken(key, values[i], ClassInvalid)
+
+				continue
+			}
+
+			port, anyPort, ok := hostSourcePort(host)
+			if !ok {
+				cfg.traceToken(key, values[i], ClassInvalid)
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0105, key, values[i])))
+
+				continue
+			}
+
+			if anyPort {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0106, key, values[i])))
+			}
+
+			if _, hostPart, _, _ := hostsource.Split(host); hostPart == "*" {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0119, key, values[i])))
+			}
+
+			cfg.traceToken(key, values[i], ClassHostSource)
+
 			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
-				HostSource: values[i],
+				HostSource:        host,
+				UnicodeHostSource: unicodeHost,
+				Port:              port,
+				AnyPort:           anyPort,
+				TrailingDot:       trailingDot,
 			})
 		case isKeywordSource(values[i]):
+			cfg.traceToken(key, values[i], ClassKeywordSource)
+
+			// 'unsafe-allow-redirects' was only ever defined for navigate-to.
+			if strings.EqualFold(values[i], `'unsafe-allow-redirects'`) && !strings.EqualFold(key, "navigate-to") {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0103, key, values[i])))
+			}
+
+			// 'inline-speculation-rules' only applies to inline
+			// <script type="speculationrules"> elements.
+			if strings.EqualFold(values[i], `'inline-speculation-rules'`) && !isScriptElemDirective(key) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0104, key, values[i])))
+			}
+
+			if opts, ok := registeredKeyword(values[i]); ok && len(opts.Directives) > 0 &&
+				!slices.Contains(opts.Directives, strings.ToLower(key)) {
+				errs = multierror.Append(
+					errs,
+					atValue(i, fmt.Errorf(errCSP0113, key, values[i], strings.Join(opts.Directives, "`, `"))),
+				)
+			}
+
 			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
-				KeywordSource: values[i],
+				KeywordSource: canonicalToken(values[i], false),
 			})
 		case isNonceSource(values[i]):
+			cfg.traceToken(key, values[i], ClassNonceSource)
+
+			if nonce := nonceValue(values[i]); isPatternedNonce(nonce) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0111, key, values[i])))
+			} else if bits := nonceEntropy(nonce); bits < minNonceEntropy {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0110, key, values[i], bits, minNonceEntropy)))
+			}
+
+			if directives := nonceHashDirectives(false); !slices.Contains(directives, strings.ToLower(key)) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0120, key, values[i], "nonce-sources",
+					strings.Join(directives, "`, `"))))
+			}
+
 			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
 				NonceSource: values[i],
 			})
 		case isHashSource(values[i]):
+			cfg.traceToken(key, values[i], ClassHashSource)
+
+			if algo, got, want := hashDigestLength(values[i]); got != want {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0109, key, values[i], got, algo, want)))
+			}
+
+			if directives := nonceHashDirectives(true); !slices.Contains(directives, strings.ToLower(key)) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0120, key, values[i], "hash-sources",
+					strings.Join(directives, "`, `"))))
+			}
+
 			listItem.SourceExprs = append(listItem.SourceExprs, SourceExpr{
 				HashSource: values[i],
 			})
+		case hasUserinfo(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0101, key, values[i])))
+		case hasFragment(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0102, key, values[i])))
+		case hasInvalidPath(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0107, key, values[i])))
 		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			if suggestion, ok := suggestKeyword(values[i], keywordCandidates()); ok {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0123, key, values[i], suggestion)))
+
+				continue
+			}
+
 			errs = multierror.Append(
 				errs,
-				fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0100]", key, values[i]),
+				atValue(i, fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0100]", key, values[i])),
 			)
 		}
 	}
 
+	if others := noneConflicts(values); len(others) > 0 {
+		listItem.None = false
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0112, key, strings.Join(others, "`, `")))
+	}
+
 	return errs
 }
 
@@ -479,6 +1478,9 @@ struct.
 
 ----
 
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
   - values ([]string): A slice of strings, each representing a value for the
     directive. (value*, above)
 
@@ -488,34 +1490,179 @@ struct.
     AncestorSourceListItem struct that will be populated with the ancestor
     expressions. This acts as a "collector".
 */
-func handleAncestorExpr(values []string, key string, ancestorListItem *AncestorSourceListItem) error {
+func handleAncestorExpr(cfg *config, values []string, key string, ancestorListItem *AncestorSourceListItem) error {
 	var errs *multierror.Error
 
+	if len(values) == 0 {
+		ancestorListItem.Empty = true
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0114, key))
+	}
+
+	seen := make(map[string]struct{}, len(values))
+
 	for i := range values {
+		if _, ok := seen[sourceKey(values[i])]; ok {
+			cfg.traceToken(key, values[i], ClassDuplicate)
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0115, key, values[i])))
+
+			continue
+		}
+
+		seen[sourceKey(values[i])] = struct{}{}
+
 		switch {
-		case values[i] == `'none'`:
-			ancestorListItem.AncestorExprs = append(ancestorListItem.AncestorExprs, AncestorExpr{
-				None: true,
-			})
+		case strings.EqualFold(values[i], `'none'`):
+			cfg.traceToken(key, values[i], ClassNone)
+
+			// `'none'` replaces the whole list, so it is recorded on the list
+			// rather than as an expression.
+			ancestorListItem.None = true
 		case isSchemeSource(values[i]):
+			cfg.traceToken(key, values[i], ClassSchemeSource)
+
+			if isJavaScriptScheme(values[i]) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0122, key, values[i])))
+			}
+
 			ancestorListItem.AncestorExprs = append(ancestorListItem.AncestorExprs, AncestorExpr{
 				SchemeSource: values[i],
 			})
-		case isHostSource(values[i]):
+		case cfg.isHostSource(values[i]) || isIDNHostSource(trimHostPartDot(values[i])):
+			host, unicodeHost := trimHostPartDot(values[i]), ""
+			trailingDot := host != values[i]
+
+			if isJavaScriptScheme(values[i]) {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0122, key, values[i])))
+			}
+
+			if suggestion, ok := suggestKeyword(values[i], ancestorKeywords); ok {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0124, key, values[i], suggestion)))
+			}
+
+			if trailingDot {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0118, key, values[i], host)))
+			}
+
+			if ascii, ok := hostSourceToASCII(host); ok {
+				host, unicodeHost = ascii, values[i]
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0108, key, values[i], ascii)))
+			}
+
+			ok, hostErrs := cfg.hostPartErrors(key, values[i], host)
+			errs = multierror.Append(errs, atValue(i, multierror.Append(nil, hostErrs...)))
+
+			if !ok {
+				cfg.traceToken(key, values[i], ClassInvalid)
+
+				continue
+			}
+
+			port, anyPort, ok := hostSourcePort(host)
+			if !ok {
+				cfg.traceToken(key, values[i], ClassInvalid)
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0105, key, values[i])))
+
+				continue
+			}
+
+			if anyPort {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0106, key, values[i])))
+			}
+
+			if _, hostPart, _, _ := hostsource.Split(host); hostPart == "*" {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0119, key, values[i])))
+			}
+
+			cfg.traceToken(key, values[i], ClassHostSource)
+
+			ancestorListItem.AncestorExprs = append(ancestorListItem.AncestorExprs, AncestorExpr{
+				HostSource:        host,
+				UnicodeHostSource: unicodeHost,
+				Port:              port,
+				AnyPort:           anyPort,
+				TrailingDot:       trailingDot,
+			})
+		case strings.EqualFold(values[i], `'self'`):
+			cfg.traceToken(key, values[i], ClassKeywordSource)
+
 			ancestorListItem.AncestorExprs = append(ancestorListItem.AncestorExprs, AncestorExpr{
-				HostSource: values[i],
+				KeywordSource: canonicalToken(values[i], false),
 			})
+		case isUnsafeKeyword(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0203, key, values[i])))
+		case isKeywordSource(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0201, key, values[i])))
+		case isNonceSource(values[i]) || isHashSource(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0202, key, values[i])))
+		case hasUserinfo(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0101, key, values[i])))
+		case hasFragment(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0102, key, values[i])))
+		case hasInvalidPath(values[i]):
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0107, key, values[i])))
 		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			if suggestion, ok := suggestKeyword(values[i], ancestorKeywords); ok {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0204, key, values[i], suggestion)))
+
+				continue
+			}
+
 			errs = multierror.Append(
 				errs,
-				fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0200]", key, values[i]),
+				atValue(i, fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0200]", key, values[i])),
 			)
 		}
 	}
 
+	if others := noneConflicts(values); len(others) > 0 {
+		ancestorListItem.None = false
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0112, key, strings.Join(others, "`, `")))
+	}
+
 	return errs
 }
 
+/*
+handleValueless handles directives that do not take a value, such as
+`upgrade-insecure-requests`. Browsers ignore any values, so they are reported
+rather than silently dropped.
+
+----
+
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
+  - values ([]string): A slice of strings, each representing a value for the
+    directive. Expected to be empty.
+
+  - key (string): The name of the directive.
+*/
+func handleValueless(cfg *config, values []string, key string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	for i := range values {
+		cfg.traceToken(key, values[i], ClassInvalid)
+	}
+
+	return fmt.Errorf(errCSP0909, key, strings.Join(values, "`, `"))
+}
+
 /*
 handlePluginTypes handles the "media type expression" type for the
 `plugin-types` directive. Given a common CSP directive:
@@ -527,6 +1674,9 @@ expressions. If they are, they will be added to the MediaTypeListItem struct.
 
 ----
 
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
   - values ([]string): A slice of strings, each representing a value for the
     directive. (value*, above)
 
@@ -536,17 +1686,21 @@ expressions. If they are, they will be added to the MediaTypeListItem struct.
     struct that will be populated with the media type expressions. This acts as
     a "collector".
 */
-func handlePluginTypes(values []string, key string, mediaTypeItem *MediaTypeListItem) error {
+func handlePluginTypes(cfg *config, values []string, key string, mediaTypeItem *MediaTypeListItem) error {
 	var errs *multierror.Error
 
 	for i := range values {
 		switch {
 		case isMediaType(values[i]):
+			cfg.traceToken(key, values[i], ClassMediaType)
+
 			mediaTypeItem.MediaTypes = append(mediaTypeItem.MediaTypes, values[i])
 		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
 			errs = multierror.Append(
 				errs,
-				fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0300]", key, values[i]),
+				atValue(i, fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0300]", key, values[i])),
 			)
 		}
 	}
@@ -561,10 +1715,14 @@ directive. Given a common CSP directive:
 	directive value1 value2 value3 value4
 
 …this function will parse the values and determine if they are valid URL
-references. If they are, they will be added to the URLRef struct.
+references. If they are, they will be added to the URLRef struct. Relative
+references are resolved against the currentURL, when it is known.
 
 ----
 
+  - cfg (*config): The options for this call to Parse. Provides the currentURL,
+    and is used to report tracing events.
+
   - values ([]string): A slice of strings, each representing a value for the
     directive. (value*, above)
 
@@ -573,49 +1731,74 @@ references. If they are, they will be added to the URLRef struct.
   - urlReference (*URLRef): A pointer to the URLRef struct that will be
     populated with the URL references. This acts as a "collector".
 */
-func handleReportingURLs(values []string, key string, urlReference *URLRef) error {
+func handleReportingURLs(cfg *config, values []string, key string, urlReference *URLRef) error {
 	var errs *multierror.Error
 
 	for i := range values {
-		switch {
+		switch resolved, relative := resolveReportingURL(cfg.currentURL, values[i]); {
 		case isValidReportingURL(values[i]):
+			cfg.traceToken(key, values[i], ClassURL)
+
 			urlReference.URLs = append(urlReference.URLs, values[i])
-		default:
-			url, err := url.Parse(values[i])
-			if err != nil {
-				errs = multierror.Append(
-					errs,
-					fmt.Errorf("[ERROR] directive `%s`: could not parse as a URL: `%s` [CSP-0401]", key, values[i]),
-				)
+			urlReference.Resolved = append(urlReference.Resolved, values[i])
 
-				break
+			if !isPotentiallyTrustworthyURL(values[i]) {
+				errs = multierror.Append(errs, atValue(i, insecureReportingURL(cfg, errCSP0404, key, values[i])))
 			}
+		case relative:
+			cfg.traceToken(key, values[i], ClassURL)
 
-			if url.Scheme() == "" {
-				errs = multierror.Append(
-					errs,
-					fmt.Errorf(
-						"[ERROR] directive `%s`: URL `%s` is missing a SCHEME, which is required [CSP-0402]",
-						key,
-						values[i],
-					),
-				)
+			urlReference.URLs = append(urlReference.URLs, values[i])
+			urlReference.Resolved = append(urlReference.Resolved, resolved)
+
+			if !isPotentiallyTrustworthyURL(resolved) {
+				errs = multierror.Append(errs, atValue(i, insecureReportingURL(cfg, errCSP0404, key, values[i])))
+			}
+		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			parsed, err := url.Parse(values[i])
+			if err != nil {
+				// A relative reference only parses against a base URL, so it is
+				// parsed against a placeholder to tell it apart from an invalid URL.
+				parsed, err = url.ParseRef(relativeReferenceBase, values[i])
+				if err != nil {
+					errs = multierror.Append(
+						errs,
+						atValue(i, fmt.Errorf("[ERROR] directive `%s`: could not parse as a URL: `%s` [CSP-0401]", key,
+							values[i])),
+					)
+
+					break
+				}
+
+				if cfg.currentURL == "" {
+					errs = multierror.Append(
+						errs,
+						atValue(i, fmt.Errorf(
+							"[ERROR] directive `%s`: URL `%s` is missing a SCHEME, which is required unless a "+
+								"currentURL is given to resolve it against [CSP-0402]",
+							key,
+							values[i],
+						)),
+					)
+				}
 			}
 
-			if url.Fragment() != "" {
+			if parsed.Fragment() != "" {
 				errs = multierror.Append(
 					errs,
-					fmt.Errorf(
+					atValue(i, fmt.Errorf(
 						"[ERROR] directive `%s`: URL `%s` includes a FRAGMENT, which is disallowed [CSP-0403]",
 						key,
 						values[i],
-					),
+					)),
 				)
 			}
 
 			errs = multierror.Append(
 				errs,
-				fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0400]", key, values[i]),
+				atValue(i, fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0400]", key, values[i])),
 			)
 		}
 	}
@@ -623,9 +1806,55 @@ func handleReportingURLs(values []string, key string, urlReference *URLRef) erro
 	return errs
 }
 
-func handleReportTo(value, key, reportingEndpointsHeader string, reportingRef *ReportingRef) error {
+/*
+handleReportTo handles the single endpoint name of the `report-to` directive, and
+looks it up in the Reporting-Endpoints header. When there is more than one
+value, the first is still recorded, as browsers use it, and the rest are kept
+in ReportingRef.Ignored.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - values ([]string): The values of the directive.
+
+  - key (string): The name of the directive.
+
+  - reportingEndpointsHeader (string): The value of the Reporting-Endpoints
+    header.
+
+  - reportingRef (*ReportingRef): A pointer to the ReportingRef struct that will
+    be populated with the endpoint. This acts as a "collector".
+*/
+func handleReportTo(
+	cfg *config,
+	values []string,
+	key, reportingEndpointsHeader string,
+	reportingRef *ReportingRef,
+) error {
 	var errs *multierror.Error
 
+	switch {
+	case len(values) == 0:
+		return fmt.Errorf(errCSP0503, key)
+	case len(values) > 1:
+		for _, value := range values[1:] {
+			cfg.traceToken(key, value, ClassInvalid)
+		}
+
+		reportingRef.Ignored = values[1:]
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0504, key, values[0], strings.Join(values[1:], " ")))
+	}
+
+	value := values[0]
+	reportingRef.Endpoint = value
+
+	if cfg.withoutReporting {
+		cfg.traceToken(key, value, ClassReportingEndpoint)
+
+		return errs.ErrorOrNil()
+	}
+
 	endpointMap, err := ParseReportingEndpoint(reportingEndpointsHeader)
 	if err != nil {
 		if merr, ok := err.(*multierror.Error); ok {
@@ -636,13 +1865,20 @@ func handleReportTo(value, key, reportingEndpointsHeader string, reportingRef *R
 	}
 
 	if url, ok := endpointMap[value]; ok {
-		reportingRef.Tokens = map[string]string{
-			value: url,
+		cfg.traceToken(key, value, ClassReportingEndpoint)
+
+		reportingRef.URL = url
+
+		if !isPotentiallyTrustworthyURL(url) {
+			errs = multierror.Append(errs, atValue(0, insecureReportingURL(cfg, errCSP0518, key, value, url)))
 		}
 	} else {
+		cfg.traceToken(key, value, ClassInvalid)
+
 		errs = multierror.Append(
 			errs,
-			fmt.Errorf("[ERROR] directive `%s` refers to undefined reporting endpoint `%s` [CSP-0502]", key, value),
+			atValue(0, fmt.Errorf("[ERROR] directive `%s` refers to undefined reporting endpoint `%s` [CSP-0502]", key,
+				value)),
 		)
 	}
 
@@ -660,6 +1896,9 @@ expressions. If they are, they will be added to the SandboxToken struct.
 
 ----
 
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
   - values ([]string): A slice of strings, each representing a value for the
     directive. (value*, above)
 
@@ -668,17 +1907,31 @@ expressions. If they are, they will be added to the SandboxToken struct.
   - sandboxToken (*SandboxToken): A pointer to the SandboxToken struct that will
     be populated with the sandbox expressions. This acts as a "collector".
 */
-func handleSandbox(values []string, key string, sandboxToken *SandboxToken) error {
+func handleSandbox(cfg *config, values []string, key string, sandboxToken *SandboxToken) error {
 	var errs *multierror.Error
 
 	for i := range values {
 		switch {
 		case isSandboxSource(values[i]):
+			cfg.traceToken(key, values[i], ClassSandboxToken)
+
+			if info, _ := lookupSandboxToken(values[i]); info.Support != "" {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0703, key, values[i], info.Support)))
+			}
+
 			sandboxToken.Allow = append(sandboxToken.Allow, values[i])
 		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			if suggestion, ok := suggestSandboxToken(values[i]); ok {
+				errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0702, key, values[i], suggestion)))
+
+				break
+			}
+
 			errs = multierror.Append(
 				errs,
-				fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0700]", key, values[i]),
+				atValue(i, fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0700]", key, values[i])),
 			)
 		}
 	}
@@ -686,6 +1939,142 @@ func handleSandbox(values []string, key string, sandboxToken *SandboxToken) erro
 	return errs
 }
 
+/*
+handleReferrer handles the "referrer token" type for the obsolete `referrer`
+directive. Given a referrer CSP directive:
+
+	referrer origin
+
+…this function will parse the value and determine the `Referrer-Policy` header
+value that replaces it. If there is one, both will be added to the ReferrerToken
+struct.
+
+NOTE: This function works differently from most of the other `handle*` functions
+in that it only accepts a single value.
+
+----
+
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
+  - value (string): A string representing a value for the `referrer` directive.
+
+  - key (string): The name of the directive. (directive, above)
+
+  - referrerToken (*ReferrerToken): A pointer to the ReferrerToken struct that
+    will be populated with the referrer value. This acts as a "collector".
+*/
+func handleReferrer(cfg *config, value, key string, referrerToken *ReferrerToken) error {
+	var errs *multierror.Error
+
+	policy, ok := referrerPolicyFor(value)
+
+	switch {
+	case ok:
+		cfg.traceToken(key, value, ClassReferrerToken)
+
+		referrerToken.Value = value
+		referrerToken.ReferrerPolicy = policy
+
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0808, key, policy))
+	default:
+		cfg.traceToken(key, value, ClassInvalid)
+
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0810, key, value))
+	}
+
+	return errs
+}
+
+/*
+handleRequireSRIFor handles the "resource type" type for the obsolete
+`require-sri-for` directive. Given a common CSP directive:
+
+	directive value1 value2
+
+…this function will parse the values and determine if they are valid resource
+types (`script` or `style`). If they are, they will be added to the
+SRIResourceTypes struct.
+
+----
+
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
+  - values ([]string): A slice of strings, each representing a value for the
+    directive. (value*, above)
+
+  - key (string): The name of the directive. (directive, above)
+
+  - sriTypes (*SRIResourceTypes): A pointer to the SRIResourceTypes struct that
+    will be populated with the resource types. This acts as a "collector".
+*/
+func handleRequireSRIFor(cfg *config, values []string, key string, sriTypes *SRIResourceTypes) error {
+	var errs *multierror.Error
+
+	for i := range values {
+		switch {
+		case isSRIResourceType(values[i]):
+			cfg.traceToken(key, values[i], ClassSRIResourceType)
+
+			sriTypes.Types = append(sriTypes.Types, values[i])
+		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP0807, key, values[i])))
+		}
+	}
+
+	return errs
+}
+
+/*
+handleTrustedTypesSinkGroups handles the "sink group" type for the
+`require-trusted-types-for` directive. Given a common CSP directive:
+
+	directive value1 value2 value3 value4
+
+…this function will parse the values and determine if they are valid Trusted
+Types sink groups. If they are, they will be added to the TrustedTypesSinkGroups
+struct. Today, the only sink group is `'script'`.
+
+----
+
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events.
+
+  - values ([]string): A slice of strings, each representing a value for the
+    directive. (value*, above)
+
+  - key (string): The name of the directive. (directive, above)
+
+  - sinkGroups (*TrustedTypesSinkGroups): A pointer to the
+    TrustedTypesSinkGroups struct that will be populated with the sink groups.
+    This acts as a "collector".
+*/
+func handleTrustedTypesSinkGroups(cfg *config, values []string, key string, sinkGroups *TrustedTypesSinkGroups) error {
+	var errs *multierror.Error
+
+	if len(values) == 0 {
+		return fmt.Errorf(errCSP1101, key)
+	}
+
+	for i := range values {
+		switch {
+		case isTrustedTypesSinkGroup(values[i]):
+			cfg.traceToken(key, values[i], ClassSinkGroup)
+
+			sinkGroups.SinkGroups = append(sinkGroups.SinkGroups, values[i])
+		default:
+			cfg.traceToken(key, values[i], ClassInvalid)
+
+			errs = multierror.Append(errs, atValue(i, fmt.Errorf(errCSP1100, key, values[i])))
+		}
+	}
+
+	return errs
+}
+
 /*
 handleWebRTC handles the "webrtc value" type for the `webrtc` directive. Given a
 webrtc CSP directive:
@@ -700,6 +2089,9 @@ in that it only accepts a single value.
 
 ----
 
+  - cfg (*config): The options for this call to Parse. Used to report
+    tracing events, and to enforce lowercase values in strict mode.
+
   - value (string): A string representing a value for the `webrtc` directive.
 
   - key (string): The name of the directive. (directive, above)
@@ -707,13 +2099,21 @@ in that it only accepts a single value.
   - webrtcToken (*WebRTCToken): A pointer to the WebRTCToken struct that will be
     populated with the webrtc value. This acts as a "collector".
 */
-func handleWebRTC(value, key string, webrtcToken *WebRTCToken) error {
+func handleWebRTC(cfg *config, value, key string, webrtcToken *WebRTCToken) error {
 	var errs *multierror.Error
 
 	switch {
 	case isWebRTCSource(value):
+		cfg.traceToken(key, value, ClassWebRTCValue)
+
+		if cfg.mode == ModeStrict && value != strings.ToLower(value) {
+			errs = multierror.Append(errs, fmt.Errorf(errCSP0602, key, value, strings.ToLower(value)))
+		}
+
 		webrtcToken.Value = value
 	default:
+		cfg.traceToken(key, value, ClassInvalid)
+
 		errs = multierror.Append(
 			errs,
 			fmt.Errorf("[ERROR] directive `%s` has an invalid value `%s` [CSP-0600]", key, value),
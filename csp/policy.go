@@ -21,16 +21,22 @@ type (
 	// https://www.w3.org/TR/CSP2/#source-list-syntax
 	Policy struct {
 		Info                 map[string]Info          `json:"info,omitempty"`
+		Disposition          Disposition              `json:"disposition,omitempty"`
+		Delivery             Delivery                 `json:"delivery,omitempty"`
 		WebRTC               WebRTCToken              `json:"webrtc,omitempty"`
 		ChildSource          []SourceListItem         `json:"child-src,omitempty"`
 		ConnectSource        []SourceListItem         `json:"connect-src,omitempty"`
 		DefaultSource        []SourceListItem         `json:"default-src,omitempty"`
+		FencedFrameSource    []SourceListItem         `json:"fenced-frame-src,omitempty"`
 		FontSource           []SourceListItem         `json:"font-src,omitempty"`
 		FormAction           []SourceListItem         `json:"form-action,omitempty"`
 		FrameSource          []SourceListItem         `json:"frame-src,omitempty"`
 		ImageSource          []SourceListItem         `json:"img-src,omitempty"`
 		ManifestSource       []SourceListItem         `json:"manifest-src,omitempty"`
 		MediaSource          []SourceListItem         `json:"media-src,omitempty"`
+		NavigateTo           []SourceListItem         `json:"navigate-to,omitempty"`
+		PrefetchSource       []SourceListItem         `json:"prefetch-src,omitempty"`
+		Referrer             []ReferrerToken          `json:"referrer,omitempty"`
 		ObjectSource         []SourceListItem         `json:"object-src,omitempty"`
 		ScriptSource         []SourceListItem         `json:"script-src,omitempty"`
 		ScriptSourceAttr     []SourceListItem         `json:"script-src-attr,omitempty"`
@@ -44,9 +50,74 @@ type (
 		ReportTo             []ReportingRef           `json:"report-to,omitempty"`
 		ReportURI            []URLRef                 `json:"report-uri,omitempty"`
 		Sandbox              []SandboxToken           `json:"sandbox,omitempty"`
+		RequireTrustedTypes  []TrustedTypesSinkGroups `json:"require-trusted-types-for,omitempty"`
+		RequireSRIFor        []SRIResourceTypes       `json:"require-sri-for,omitempty"`
 		BaseURI              []SourceListItem         `json:"base-uri,omitempty"`
 		BlockAllMixedContent bool                     `json:"block-all-mixed-content,omitempty"`
 		UpgradeInsecureReq   bool                     `json:"upgrade-insecure-requests,omitempty"`
+
+		// Unknown holds the directives that the parser does not recognize (e.g.,
+		// vendor-specific or future directives), exactly as they were written.
+		Unknown []RawDirective `json:"unknown,omitempty"`
+
+		// Origin is the origin of the currentURL passed to Parse, which `'self'`
+		// matches against. It is nil when the currentURL is empty, invalid, or
+		// has an opaque origin (e.g., `data:`).
+		Origin *Origin `json:"origin,omitempty"`
+
+		// Raw is the serialized policy, exactly as it was passed to Parse.
+		Raw string `json:"raw,omitempty"`
+
+		// Directives lists every directive in the order it appeared, including
+		// duplicates and unknown directives, so that the original policy can be
+		// reproduced.
+		Directives []RawDirective `json:"directives,omitempty"`
+
+		// Comments holds the comments of a policy file that do not belong to a
+		// directive (see ReadPolicyFile).
+		Comments []string `json:"comments,omitempty"`
+	}
+
+	// RawDirective is a directive name and its values, without any validation.
+	RawDirective struct {
+		Name   string   `json:"name"`
+		Values []string `json:"values,omitempty"`
+
+		// Raw is the text of the directive between semicolons, exactly as it was
+		// written (including surrounding whitespace). It is only set in
+		// Policy.Directives.
+		Raw string `json:"raw,omitempty"`
+
+		// NameSpan is the location of the directive name, and ValueSpans has the
+		// location of each value.
+		NameSpan   Span   `json:"nameSpan"`
+		ValueSpans []Span `json:"valueSpans,omitempty"`
+
+		// Tokens has the name, followed by each value, with the spelling that the
+		// parser normalized them to. It is only set in Policy.Directives, and only
+		// with WithRawTokens.
+		Tokens []RawToken `json:"tokens,omitempty"`
+
+		// Comments holds the comments that annotate the directive in a policy
+		// file (see ReadPolicyFile). It is only set in Policy.Directives.
+		Comments []string `json:"comments,omitempty"`
+	}
+
+	// RawToken is a directive name or value, exactly as it was written. When the
+	// parser normalized its spelling (e.g., lowercased `'SELF'`), Normalized is
+	// true and Canonical has the normalized spelling.
+	RawToken struct {
+		Text       string `json:"text"`
+		Canonical  string `json:"canonical,omitempty"`
+		Normalized bool   `json:"normalized,omitempty"`
+	}
+
+	// Span is a range of bytes, [Start, End), within the header value that was
+	// passed to Parse. When a header value holds several comma-separated
+	// policies, offsets are still relative to the whole header value.
+	Span struct {
+		Start int `json:"start"`
+		End   int `json:"end"`
 	}
 
 	Info struct {
@@ -55,31 +126,85 @@ type (
 		Notes       []string `json:"notes,omitempty"`
 	}
 
+	// source-list = *WSP [ source-expression *( required-ascii-whitespace source-expression ) ] *WSP
+	//             / *WSP "'none'" *WSP
 	SourceListItem struct {
 		SourceExprs []SourceExpr `json:"sourceList,omitempty"`
+
+		// None is true when the directive's only value is `'none'`, which
+		// replaces the list and matches nothing. `'none'` is never a
+		// SourceExpr, and is ignored when combined with other values.
+		None bool `json:"none,omitempty"`
+
+		// Empty is true when the directive has no values (e.g., `script-src;`),
+		// which matches nothing, like `'none'`.
+		Empty bool `json:"empty,omitempty"`
 	}
 
-	// source-expression = scheme-source / host-source / keyword-source / nonce-source / hash-source / 'none'
+	// source-expression = scheme-source / host-source / keyword-source / nonce-source / hash-source
 	SourceExpr struct {
-		SchemeSource  string `json:"schemeSource,omitempty"`
-		HostSource    string `json:"hostSource,omitempty"`
-		KeywordSource string `json:"keywordSource,omitempty"`
-		NonceSource   string `json:"nonceSource,omitempty"`
-		HashSource    string `json:"hashSource,omitempty"`
-		None          bool   `json:"none,omitempty"`
+		SchemeSource  string     `json:"schemeSource,omitempty"`
+		SchemeRisk    SchemeRisk `json:"schemeRisk,omitempty"`
+		HostSource    string     `json:"hostSource,omitempty"`
+		KeywordSource string     `json:"keywordSource,omitempty"`
+		NonceSource   string     `json:"nonceSource,omitempty"`
+		HashSource    string     `json:"hashSource,omitempty"`
+
+		// UnicodeHostSource is the host-source as written in the policy, when its
+		// host-part is internationalized. HostSource then has the ASCII (punycode)
+		// form.
+		UnicodeHostSource string `json:"unicodeHostSource,omitempty"`
+
+		// Port is the port-part of a host-source, or 0 if it has none. AnyPort is
+		// true for the `*` port-part.
+		Port    int  `json:"port,omitempty"`
+		AnyPort bool `json:"anyPort,omitempty"`
+
+		// TrailingDot is true when the host-part was written as an absolute DNS
+		// name, with a trailing `.` (e.g., `example.com.`). HostSource then has
+		// the dot removed.
+		TrailingDot bool `json:"trailingDot,omitempty"`
 	}
 
 	// https://www.w3.org/TR/CSP2/#directive-frame-ancestors
 	AncestorSourceListItem struct {
 		AncestorExprs []AncestorExpr `json:"ancestorList,omitempty"`
+
+		// None is true when the directive's only value is `'none'`, which
+		// replaces the list and matches nothing. `'none'` is never an
+		// AncestorExpr, and is ignored when combined with other values.
+		None bool `json:"none,omitempty"`
+
+		// Empty is true when the directive has no values (e.g.,
+		// `frame-ancestors;`), which matches nothing, like `'none'`.
+		Empty bool `json:"empty,omitempty"`
 	}
 
 	// ancestor-source-list = [ ancestor-source *( 1*WSP ancestor-source ) ] / "'none'"
-	// ancestor-source      = scheme-source / host-source
+	// ancestor-source      = scheme-source / host-source / "'self'"
 	AncestorExpr struct {
 		SchemeSource string `json:"schemeSource,omitempty"`
 		HostSource   string `json:"hostSource,omitempty"`
-		None         bool   `json:"none,omitempty"`
+
+		// KeywordSource is `'self'`, the only keyword allowed in an
+		// ancestor-source-list, in lowercase (e.g., `'SELF'` is stored as
+		// `'self'`).
+		KeywordSource string `json:"keywordSource,omitempty"`
+
+		// UnicodeHostSource is the host-source as written in the policy, when its
+		// host-part is internationalized. HostSource then has the ASCII (punycode)
+		// form.
+		UnicodeHostSource string `json:"unicodeHostSource,omitempty"`
+
+		// Port is the port-part of a host-source, or 0 if it has none. AnyPort is
+		// true for the `*` port-part.
+		Port    int  `json:"port,omitempty"`
+		AnyPort bool `json:"anyPort,omitempty"`
+
+		// TrailingDot is true when the host-part was written as an absolute DNS
+		// name, with a trailing `.` (e.g., `example.com.`). HostSource then has
+		// the dot removed.
+		TrailingDot bool `json:"trailingDot,omitempty"`
 	}
 
 	// media-type-list   = media-type *( 1*WSP media-type )
@@ -101,10 +226,54 @@ type (
 	// https://url.spec.whatwg.org/commit-snapshots/eee49fdf4f99d59f717cbeb0bce29fda930196d4/
 	URLRef struct {
 		URLs []string `json:"urls,omitempty"`
+
+		// Resolved has the absolute URL of each entry in URLs, in the same order.
+		// Relative references are resolved against the currentURL passed to
+		// Parse; absolute URLs are copied unchanged.
+		Resolved []string `json:"resolved,omitempty"`
 	}
 
+	// directive-name  = "report-to"
+	// directive-value = token
+	// https://www.w3.org/TR/2024/WD-CSP3-20240613/#directive-report-to
 	ReportingRef struct {
-		Tokens map[string]string `json:"tokens,omitempty"`
+		// Endpoint is the name of the reporting endpoint, as written in the
+		// policy.
+		Endpoint string `json:"endpoint,omitempty"`
+
+		// URL is the URL of the endpoint in the Reporting-Endpoints header. It is
+		// empty when the endpoint is undefined, or was not looked up.
+		URL string `json:"url,omitempty"`
+
+		// Ignored holds the values after the first, which browsers ignore.
+		Ignored []string `json:"ignored,omitempty"`
+	}
+
+	// directive-name  = "referrer"
+	// directive-value = referrer-token
+	// https://www.w3.org/TR/2014/WD-CSP11-20140211/#referrer
+	ReferrerToken struct {
+		Value string `json:"value,omitempty"`
+
+		// ReferrerPolicy is the value of the `Referrer-Policy` header that
+		// replaces this directive.
+		ReferrerPolicy string `json:"referrerPolicy,omitempty"`
+	}
+
+	// directive-name = "require-sri-for"
+	// directive-value = resource-type *( RWS resource-type )
+	// resource-type   = "script" / "style"
+	// https://w3c.github.io/webappsec-subresource-integrity/#opt-in-require-sri-for
+	SRIResourceTypes struct {
+		Types []string `json:"types,omitempty"`
+	}
+
+	// directive-name           = "require-trusted-types-for"
+	// directive-value          = trusted-types-sink-group *( RWS trusted-types-sink-group )
+	// trusted-types-sink-group = "'script'"
+	// https://w3c.github.io/trusted-types/dist/spec/#require-trusted-types-for-csp-directive
+	TrustedTypesSinkGroups struct {
+		SinkGroups []string `json:"sinkGroups,omitempty"`
 	}
 
 	// directive-name  = "webrtc"
@@ -0,0 +1,248 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*
+PolicyFile is a policy read from a text file, with the comments that annotate
+it. The file format is:
+
+	# Policy for www.example.com.
+	# Reviewed by the security team.
+
+	# Our own scripts, and the CDN that serves the bundles.
+	script-src 'self'
+	  https://cdn.example.com;  # Moved from the old CDN in 2024.
+	img-src *;
+
+The rules of the format are:
+
+  - A directive may be split across several lines. Lines are joined with a
+    space, so the policy is parsed as though it were written on one line.
+  - A comment starts with `#` at the start of a line, or after whitespace, and
+    runs to the end of the line. A `#` inside a value (e.g., in a URL) does not
+    start a comment.
+  - Comments on their own lines annotate the next directive, or the directive
+    that they are in the middle of. A comment at the end of a line annotates the
+    directive on that line.
+  - Comments on their own lines that are followed by a blank line before any
+    directive, or by no directive at all, annotate the policy as a whole.
+
+A file holds one policy, so commas are not expected.
+*/
+type PolicyFile struct {
+	// Policy is the serialized policy, without the comments.
+	Policy string `json:"policy"`
+
+	// Comments holds the comments that annotate the policy as a whole.
+	Comments []string `json:"comments,omitempty"`
+
+	// DirectiveComments holds the comments of each directive, in the order the
+	// directives appear in Policy. Directives without comments have a nil entry.
+	DirectiveComments [][]string `json:"directiveComments,omitempty"`
+}
+
+// rePolicyFileComment matches a `#` comment that starts a line or follows
+// whitespace, through the end of the line. The text of the comment is the
+// second group.
+var rePolicyFileComment = regexp.MustCompile(`(^|\s)#\s?(.*)$`)
+
+/*
+ReadPolicyFile reads the contents of a policy file, in the format described by
+PolicyFile.
+
+----
+
+  - contents (string): The contents of the policy file.
+*/
+func ReadPolicyFile(contents string) *PolicyFile {
+	var (
+		file    = &PolicyFile{}
+		kept    []string
+		pending []string
+
+		// open is true while a directive has started, but has not been ended by
+		// a `;`.
+		open bool
+	)
+
+	for _, line := range strings.Split(strings.ReplaceAll(contents, "\r\n", "\n"), "\n") {
+		code, comment, hasComment := line, "", false
+
+		if loc := rePolicyFileComment.FindStringSubmatchIndex(line); loc != nil {
+			code, comment, hasComment = line[:loc[0]], strings.TrimSpace(line[loc[4]:loc[5]]), true
+		}
+
+		code = strings.TrimSpace(code)
+
+		switch {
+		case code == "" && !hasComment:
+			// A blank line ends the comments of the policy as a whole.
+			if len(file.DirectiveComments) == 0 && !open {
+				file.Comments = append(file.Comments, pending...)
+				pending = nil
+			}
+
+			continue
+		case code == "":
+			pending = append(pending, comment)
+
+			continue
+		}
+
+		kept = append(kept, code)
+
+		for i, segment := range strings.Split(code, ";") {
+			if i > 0 {
+				open = false
+			}
+
+			// The comments above a line that continues a directive annotate
+			// that directive.
+			if i == 0 && open && strings.TrimSpace(segment) != "" {
+				last := len(file.DirectiveComments) - 1
+				file.DirectiveComments[last] = append(file.DirectiveComments[last], pending...)
+				pending = nil
+			}
+
+			if strings.TrimSpace(segment) != "" && !open {
+				file.DirectiveComments = append(file.DirectiveComments, pending)
+				pending = nil
+				open = true
+			}
+		}
+
+		if hasComment && len(file.DirectiveComments) > 0 {
+			last := len(file.DirectiveComments) - 1
+			file.DirectiveComments[last] = append(file.DirectiveComments[last], comment)
+		} else if hasComment {
+			pending = append(pending, comment)
+		}
+	}
+
+	file.Comments = append(file.Comments, pending...)
+	file.Policy = strings.Join(kept, " ")
+
+	return file
+}
+
+/*
+ParsePolicyFile reads a policy file (see PolicyFile), parses it with Parse, and
+attaches its comments to the parsed policy and its directives.
+
+----
+
+  - currentURL (string): The URL of the protected document. See Parse.
+
+  - reportingEndpointsHeader (string): The value of the Reporting-Endpoints
+    header. See Parse.
+
+  - contents (string): The contents of the policy file.
+
+  - opts (...Option): The options for Parse.
+*/
+func ParsePolicyFile(currentURL, reportingEndpointsHeader, contents string, opts ...Option) ([]*Policy, error) {
+	file := ReadPolicyFile(contents)
+	policies, err := Parse(currentURL, reportingEndpointsHeader, []string{file.Policy}, opts...)
+
+	if len(policies) > 0 {
+		file.Attach(policies[0])
+	}
+
+	return policies, err
+}
+
+/*
+Attach copies the comments of the file onto a policy that was parsed from
+PolicyFile.Policy: the comments of the policy as a whole onto Policy.Comments,
+and the comments of each directive onto the matching entry of
+Policy.Directives.
+
+----
+
+  - policy (*Policy): The policy parsed from PolicyFile.Policy.
+*/
+func (f *PolicyFile) Attach(policy *Policy) {
+	policy.Comments = f.Comments
+
+	for i := range policy.Directives {
+		if i < len(f.DirectiveComments) {
+			policy.Directives[i].Comments = f.DirectiveComments[i]
+		}
+	}
+}
+
+/*
+FormatPolicyFile writes a policy in the format described by PolicyFile, with one
+directive per line, so that the comments that were attached to it (e.g., by
+ParsePolicyFile) are kept next to the directives they annotate.
+
+----
+
+  - policy (*Policy): The parsed policy.
+*/
+func FormatPolicyFile(policy *Policy) string {
+	var b strings.Builder
+
+	for _, comment := range policy.Comments {
+		b.WriteString(formatComment(comment))
+	}
+
+	if len(policy.Comments) > 0 {
+		b.WriteString("\n")
+	}
+
+	for i := range policy.Directives {
+		directive := &policy.Directives[i]
+
+		for _, comment := range directive.Comments {
+			b.WriteString(formatComment(comment))
+		}
+
+		b.WriteString(strings.Join(append([]string{directive.Name}, directive.Values...), " "))
+		b.WriteString(";\n")
+	}
+
+	return b.String()
+}
+
+// formatComment writes a comment on its own line.
+func formatComment(comment string) string {
+	if comment == "" {
+		return "#\n"
+	}
+
+	return "# " + comment + "\n"
+}
+
+// HasComments reports whether the policy, or any of its directives, has
+// comments from a policy file.
+func (p *Policy) HasComments() bool {
+	if len(p.Comments) > 0 {
+		return true
+	}
+
+	for i := range p.Directives {
+		if len(p.Directives[i].Comments) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
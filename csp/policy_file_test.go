@@ -0,0 +1,92 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicyFile = `# Policy for www.example.com.
+# Reviewed by the security team.
+
+# Our own scripts, and the CDN.
+script-src 'self'
+  # The CDN serves the bundles.
+  https://cdn.example.com;  # Moved in 2024.
+img-src * data:; object-src 'none'  # No plugins.
+# Trailing note.
+`
+
+func TestReadPolicyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	file := ReadPolicyFile(testPolicyFile)
+
+	assert.Equal("script-src 'self' https://cdn.example.com; img-src * data:; object-src 'none'", file.Policy)
+	assert.Equal([]string{"Policy for www.example.com.", "Reviewed by the security team.", "Trailing note."},
+		file.Comments)
+	assert.Equal([][]string{
+		{"Our own scripts, and the CDN.", "The CDN serves the bundles.", "Moved in 2024."},
+		nil,
+		{"No plugins."},
+	}, file.DirectiveComments)
+}
+
+func TestReadPolicyFileFragments(t *testing.T) {
+	assert := assert.New(t)
+
+	// A `#` inside a value is not a comment.
+	file := ReadPolicyFile("report-uri https://example.com/csp#main\n")
+	assert.Equal("report-uri https://example.com/csp#main", file.Policy)
+	assert.Empty(file.Comments)
+	assert.Equal([][]string{nil}, file.DirectiveComments)
+}
+
+func TestParsePolicyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := ParsePolicyFile("https://example.com", "", testPolicyFile)
+	assert.Len(policies, 1)
+
+	policy := policies[0]
+	assert.True(policy.HasComments())
+	assert.Len(policy.Comments, 3)
+	assert.Equal("script-src", policy.Directives[0].Name)
+	assert.Len(policy.Directives[0].Comments, 3)
+	assert.Empty(policy.Directives[1].Comments)
+	assert.Equal([]string{"No plugins."}, policy.Directives[2].Comments)
+
+	formatted := FormatPolicyFile(policy)
+	assert.Equal(`# Policy for www.example.com.
+# Reviewed by the security team.
+# Trailing note.
+
+# Our own scripts, and the CDN.
+# The CDN serves the bundles.
+# Moved in 2024.
+script-src 'self' https://cdn.example.com;
+img-src * data:;
+# No plugins.
+object-src 'none';
+`, formatted)
+
+	// Formatting is stable: reading the output back gives the same comments.
+	again := ReadPolicyFile(formatted)
+	assert.Equal(policy.Comments, again.Comments)
+	assert.Equal(policy.Directives[0].Comments, again.DirectiveComments[0])
+	assert.Equal(policy.Directives[2].Comments, again.DirectiveComments[2])
+}
@@ -0,0 +1,94 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PresetInfo describes a named preset policy.
+type PresetInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Policy      string `json:"policy"`
+}
+
+// presets are the named starting points returned by Preset, in the order they
+// are listed.
+var presets = []PresetInfo{
+	{
+		Name:        "strict",
+		Description: "Only same-origin resources of each type; no plugins, framing, or <base> changes.",
+		Policy: "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self'; font-src 'self'; " +
+			"connect-src 'self'; manifest-src 'self'; base-uri 'none'; form-action 'self'; " +
+			"frame-ancestors 'none'; upgrade-insecure-requests",
+	},
+	{
+		Name:        "basic-self",
+		Description: "Allows everything from the same origin, including framing, and blocks plugins.",
+		Policy:      "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'self'",
+	},
+	{
+		Name:        "api-only",
+		Description: "For responses that are never rendered (e.g., JSON APIs); blocks everything.",
+		Policy:      "default-src 'none'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'; sandbox",
+	},
+	{
+		Name:        "static-site",
+		Description: "For static sites without inline scripts or styles, served over HTTPS.",
+		Policy: "default-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; " +
+			"frame-ancestors 'none'; upgrade-insecure-requests",
+	},
+}
+
+// Presets returns the named preset policies, in a stable order.
+func Presets() []PresetInfo {
+	list := make([]PresetInfo, len(presets))
+	copy(list, presets)
+
+	return list
+}
+
+/*
+Preset returns a freshly-parsed copy of a named preset policy, as a starting
+point that callers can customize with SetDirective, AddSources, and
+RemoveDirective, and serialize with String. The name is case-insensitive.
+
+----
+
+  - name (string): The name of the preset (e.g., `strict`). See Presets for the
+    full list.
+*/
+func Preset(name string) (*Policy, error) {
+	names := make([]string, 0, len(presets))
+
+	for i := range presets {
+		if strings.EqualFold(presets[i].Name, name) {
+			// The presets are meant to be free of warnings and errors, so any
+			// diagnostic that remains means that a preset is broken.
+			policies, err := Parse("", "", []string{presets[i].Policy}, MinSeverity(SeverityWarning))
+			if err != nil {
+				return nil, err
+			}
+
+			return policies[0], nil
+		}
+
+		names = append(names, presets[i].Name)
+	}
+
+	return nil, fmt.Errorf(errCSP0014, name, strings.Join(names, ", "))
+}
@@ -0,0 +1,46 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresets(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, preset := range Presets() {
+		// Every preset must be free of warnings and errors.
+		_, err := Parse("https://example.com", "", []string{preset.Policy}, MinSeverity(SeverityWarning))
+		assert.NoError(err, preset.Name)
+
+		policy, err := Preset(preset.Name)
+		assert.NoError(err)
+		assert.Equal(preset.Policy, policy.Raw)
+	}
+
+	policy, err := Preset("STRICT")
+	assert.NoError(err)
+	assert.Equal([]AncestorSourceListItem{{None: true}}, policy.FrameAncestors)
+
+	// Each call returns a new Policy, so that callers can change it.
+	other, _ := Preset("strict")
+	assert.NotSame(policy, other)
+
+	_, err = Preset("bogus")
+	assert.ErrorContains(err, "unknown preset `bogus`; expected one of: strict, basic-self, api-only, static-site")
+}
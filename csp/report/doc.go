@@ -0,0 +1,22 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package report summarizes many earlier scans (e.g., one per property) into a
+fleet-level report: the grade distribution, the most common findings, and the
+hosts that are most frequently allowed.
+
+It is covered by the same compatibility guarantees as the csp package.
+*/
+package report
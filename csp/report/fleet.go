@@ -0,0 +1,246 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+type (
+	// Fleet summarizes many earlier scans (e.g., one per property).
+	Fleet struct {
+		Scans    int       `json:"scans"`
+		Policies int       `json:"policies"`
+		Grades   Grades    `json:"grades"`
+		Findings []Finding `json:"findings"`
+		Hosts    []Host    `json:"hosts"`
+	}
+
+	// Grades counts the scans by their grade, which is the severity of the worst
+	// diagnostic in the scan.
+	Grades struct {
+		Clean int `json:"clean"`
+		Info  int `json:"info"`
+		Warn  int `json:"warn"`
+		Error int `json:"error"`
+	}
+
+	// Finding is a diagnostic code, and how often it was found.
+	Finding struct {
+		Code        string `json:"code"`
+		Severity    string `json:"severity"`
+		Scans       int    `json:"scans"`
+		Occurrences int    `json:"occurrences"`
+		Example     string `json:"example"`
+	}
+
+	// Host is a host that policies allow, and how many scans allow it.
+	Host struct {
+		Host  string `json:"host"`
+		Scans int    `json:"scans"`
+	}
+)
+
+// reDiagnosticCode matches the code at the end of a diagnostic.
+var reDiagnosticCode = regexp.MustCompile(`\[(CSP-[0-9]+)\]$`)
+
+// NewFleet returns an empty Fleet, ready for scans to be added.
+func NewFleet() *Fleet {
+	return &Fleet{
+		Findings: []Finding{},
+		Hosts:    []Host{},
+	}
+}
+
+/*
+AddScan adds the grade, findings, and hosts of one earlier scan to the report.
+Findings are recomputed from each policy's raw text, so scans taken with older
+versions benefit from newer checks. Scans do not record the URL or the
+Reporting-Endpoints header, so `'self'` and `report-to` are not validated.
+
+----
+
+  - policies ([]csp.Policy): The policies of the scan, as printed by the JSON
+    output of csp-parser.
+*/
+func (f *Fleet) AddScan(policies []csp.Policy) {
+	f.Scans++
+	f.Policies += len(policies)
+
+	// The severity of the worst diagnostic, or -1 for a clean scan.
+	worst := csp.Severity(-1)
+	codes := map[string]bool{}
+	hosts := map[string]bool{}
+
+	for i := range policies {
+		opts := []csp.Option{csp.WithoutSelfValidation(), csp.WithoutReportingValidation()}
+
+		if policies[i].Disposition != "" {
+			opts = append(opts, csp.WithDisposition(policies[i].Disposition))
+		}
+
+		if policies[i].Delivery != "" {
+			opts = append(opts, csp.WithDelivery(policies[i].Delivery))
+		}
+
+		parsed, err := csp.Parse("", "", []string{policies[i].Raw}, opts...)
+
+		var errs []error
+		if merr, ok := err.(*multierror.Error); ok {
+			errs = merr.Errors
+		}
+
+		for _, e := range errs {
+			m := reDiagnosticCode.FindStringSubmatch(e.Error())
+			if m == nil {
+				continue
+			}
+
+			severity := csp.SeverityOf(e)
+			worst = max(worst, severity)
+
+			f.addFinding(m[1], severity, e.Error(), !codes[m[1]])
+			codes[m[1]] = true
+		}
+
+		for _, policy := range parsed {
+			for _, host := range policy.HostSources() {
+				if !hosts[host] {
+					f.addHost(host)
+				}
+
+				hosts[host] = true
+			}
+		}
+	}
+
+	switch worst {
+	case -1:
+		f.Grades.Clean++
+	case csp.SeverityInfo:
+		f.Grades.Info++
+	case csp.SeverityWarning:
+		f.Grades.Warn++
+	default:
+		f.Grades.Error++
+	}
+}
+
+// addFinding counts one occurrence of a diagnostic code. newScan is true for the
+// first occurrence in a scan.
+func (f *Fleet) addFinding(code string, severity csp.Severity, msg string, newScan bool) {
+	i := slices.IndexFunc(f.Findings, func(finding Finding) bool { return finding.Code == code })
+	if i < 0 {
+		f.Findings = append(f.Findings, Finding{
+			Code:     code,
+			Severity: severity.String(),
+			Example:  msg,
+		})
+		i = len(f.Findings) - 1
+	}
+
+	f.Findings[i].Occurrences++
+
+	if newScan {
+		f.Findings[i].Scans++
+	}
+}
+
+// addHost counts one more scan that allows the host.
+func (f *Fleet) addHost(host string) {
+	i := slices.IndexFunc(f.Hosts, func(h Host) bool { return h.Host == host })
+	if i < 0 {
+		f.Hosts = append(f.Hosts, Host{Host: host})
+		i = len(f.Hosts) - 1
+	}
+
+	f.Hosts[i].Scans++
+}
+
+/*
+Sort puts the most common findings and hosts first, and keeps only the top of
+each.
+
+----
+
+  - top (int): The number of findings and hosts to keep. 0 keeps all of them.
+*/
+func (f *Fleet) Sort(top int) {
+	slices.SortStableFunc(f.Findings, func(a, b Finding) int {
+		if a.Scans != b.Scans {
+			return b.Scans - a.Scans
+		}
+
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	slices.SortStableFunc(f.Hosts, func(a, b Host) int {
+		if a.Scans != b.Scans {
+			return b.Scans - a.Scans
+		}
+
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	if top > 0 && len(f.Findings) > top {
+		f.Findings = f.Findings[:top]
+	}
+
+	if top > 0 && len(f.Hosts) > top {
+		f.Hosts = f.Hosts[:top]
+	}
+}
+
+/*
+WriteText writes the report as text.
+
+----
+
+  - w (io.Writer): The writer to write the report to.
+*/
+func (f *Fleet) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d scans, %d policies\n\n", f.Scans, f.Policies)
+
+	fmt.Fprintln(&b, "Grades")
+	fmt.Fprintf(&b, "  error: %d\n  warn:  %d\n  info:  %d\n  clean: %d\n\n",
+		f.Grades.Error, f.Grades.Warn, f.Grades.Info, f.Grades.Clean)
+
+	fmt.Fprintln(&b, "Most common findings")
+
+	for _, finding := range f.Findings {
+		fmt.Fprintf(&b, "  %s (%s): %d scans, %d occurrences\n    e.g., %s\n", finding.Code, finding.Severity,
+			finding.Scans, finding.Occurrences, finding.Example)
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Most frequently allowed hosts")
+
+	for _, h := range f.Hosts {
+		fmt.Fprintf(&b, "  %s: %d scans\n", h.Host, h.Scans)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
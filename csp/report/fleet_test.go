@@ -0,0 +1,46 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleet(t *testing.T) {
+	assert := assert.New(t)
+
+	fleet := NewFleet()
+	fleet.AddScan([]csp.Policy{{Raw: "default-src 'self'; script-src https://cdn.example.com 'unsafe-inline'"}})
+	fleet.AddScan([]csp.Policy{{Raw: "script-src https://cdn.example.com https://a.example.com"}})
+	fleet.AddScan([]csp.Policy{})
+	fleet.Sort(1)
+
+	assert.Equal(3, fleet.Scans)
+	assert.Equal(2, fleet.Policies)
+	assert.Equal(1, fleet.Grades.Clean)
+	assert.Equal(2, fleet.Grades.Info+fleet.Grades.Warn+fleet.Grades.Error)
+	assert.Len(fleet.Findings, 1)
+	assert.Equal([]Host{{Host: "cdn.example.com", Scans: 2}}, fleet.Hosts)
+
+	var b strings.Builder
+
+	assert.NoError(fleet.WriteText(&b))
+	assert.Contains(b.String(), "3 scans, 2 policies")
+	assert.Contains(b.String(), "cdn.example.com: 2 scans")
+}
@@ -15,11 +15,14 @@
 package csp
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"regexp"
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/nlnwa/whatwg-url/url"
 )
 
 // ParseReportingEndpoint checks the syntax of the `Reporting-Endpoints` header.
@@ -112,3 +115,51 @@ func isValidToken(s string) bool {
 
 	return reToken.MatchString(s)
 }
+
+/*
+isPotentiallyTrustworthyURL implements "Is url potentially trustworthy?" from
+Secure Contexts, § 3.1, for the URLs that reports are sent to. Browsers only
+send reports to potentially trustworthy URLs.
+
+https://www.w3.org/TR/secure-contexts/#is-url-trustworthy
+
+----
+
+  - s (string): The absolute URL to check.
+*/
+func isPotentiallyTrustworthyURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme()) {
+	case "https", "wss", "file":
+		return true
+	}
+
+	host := strings.ToLower(strings.Trim(u.Hostname(), "[]"))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+/*
+insecureReportingURL returns the diagnostic for a reporting URL that is not
+potentially trustworthy, at the configured severity.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - msg (string): The diagnostic message, either errCSP0404 or errCSP0518.
+
+  - args (...any): The arguments for the message.
+*/
+func insecureReportingURL(cfg *config, msg string, args ...any) error {
+	return errors.New(withSeverity(fmt.Sprintf(msg, args...), cfg.insecureReportingSeverity))
+}
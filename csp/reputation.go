@@ -0,0 +1,238 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+type (
+	// ReputationProvider is implemented by anything that can answer questions
+	// about the reputation of a host (e.g., a threat-intel feed, a blocklist, or
+	// a domain registration database).
+	ReputationProvider interface {
+		// Lookup returns the reputation of the host. The host is always a bare,
+		// lowercase hostname without a scheme, port, path, or wildcard prefix.
+		Lookup(host string) (Reputation, error)
+	}
+
+	// ContextReputationProvider is a ReputationProvider that supports
+	// cancellation (e.g., because it makes network requests). When a provider
+	// implements this interface, CheckReputationContext calls LookupContext
+	// instead of Lookup.
+	ContextReputationProvider interface {
+		ReputationProvider
+		LookupContext(ctx context.Context, host string) (Reputation, error)
+	}
+
+	// Reputation is the answer from a ReputationProvider for a single host.
+	Reputation struct {
+		// Blocklisted is true if the host appears on a blocklist.
+		Blocklisted bool `json:"blocklisted,omitempty"`
+
+		// RecentlyRegistered is true if the domain was registered recently enough
+		// to be suspicious.
+		RecentlyRegistered bool `json:"recentlyRegistered,omitempty"`
+
+		// Source is a human-readable name for where the answer came from.
+		Source string `json:"source,omitempty"`
+	}
+
+	// LocalReputationList is a ReputationProvider backed by static lists of
+	// domains. A domain in either list also matches all of its subdomains.
+	LocalReputationList struct {
+		Blocklist          []string
+		RecentlyRegistered []string
+	}
+)
+
+// NewLocalReputationList returns a ReputationProvider backed by static lists of
+// blocklisted and recently-registered domains.
+func NewLocalReputationList(blocklist, recentlyRegistered []string) *LocalReputationList {
+	return &LocalReputationList{
+		Blocklist:          blocklist,
+		RecentlyRegistered: recentlyRegistered,
+	}
+}
+
+// Lookup implements ReputationProvider.
+func (l *LocalReputationList) Lookup(host string) (Reputation, error) {
+	return Reputation{
+		Blocklisted:        domainListContains(l.Blocklist, host),
+		RecentlyRegistered: domainListContains(l.RecentlyRegistered, host),
+		Source:             "local list",
+	}, nil
+}
+
+/*
+CheckReputation queries the provider for every host that the policy allows, and
+returns an error for each host that is blocklisted or recently registered.
+
+----
+
+  - policy (*Policy): The parsed policy to evaluate.
+
+  - provider (ReputationProvider): The source of reputation data.
+*/
+func CheckReputation(policy *Policy, provider ReputationProvider) error {
+	return CheckReputationContext(context.Background(), policy, provider)
+}
+
+/*
+CheckReputationContext is like CheckReputation, but stops early when the
+context is canceled or its deadline is exceeded. The context is also passed to
+providers that implement ContextReputationProvider.
+
+----
+
+  - ctx (context.Context): Controls cancellation and deadlines.
+
+  - policy (*Policy): The parsed policy to evaluate.
+
+  - provider (ReputationProvider): The source of reputation data.
+*/
+func CheckReputationContext(ctx context.Context, policy *Policy, provider ReputationProvider) error {
+	var (
+		errs   *multierror.Error
+		cached = map[string]Reputation{}
+	)
+
+	for _, directive := range sourceListDirectives {
+		list, ok := policy.sourceList(directive)
+		if !ok {
+			continue
+		}
+
+		for i := range list.SourceExprs {
+			if list.SourceExprs[i].HostSource == "" {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return multierror.Append(errs, err).ErrorOrNil()
+			}
+
+			errs = multierror.Append(errs, checkHostReputation(
+				ctx,
+				directive,
+				list.SourceExprs[i].HostSource,
+				provider,
+				cached,
+			))
+		}
+	}
+
+	for i := range policy.FrameAncestors {
+		for j := range policy.FrameAncestors[i].AncestorExprs {
+			if policy.FrameAncestors[i].AncestorExprs[j].HostSource == "" {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return multierror.Append(errs, err).ErrorOrNil()
+			}
+
+			errs = multierror.Append(errs, checkHostReputation(
+				ctx,
+				"frame-ancestors",
+				policy.FrameAncestors[i].AncestorExprs[j].HostSource,
+				provider,
+				cached,
+			))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+/*
+checkHostReputation looks up a single host-source, reusing cached answers for
+hosts that have already been looked up.
+
+----
+
+  - ctx (context.Context): Controls cancellation and deadlines.
+
+  - directive (string): The name of the directive the host-source came from.
+
+  - hostSource (string): The host-source expression.
+
+  - provider (ReputationProvider): The source of reputation data.
+
+  - cached (map[string]Reputation): Answers from earlier lookups.
+*/
+func checkHostReputation(
+	ctx context.Context,
+	directive, hostSource string,
+	provider ReputationProvider,
+	cached map[string]Reputation,
+) error {
+	var errs *multierror.Error
+
+	_, host, _, _ := hostsource.Split(hostSource)
+	host = strings.TrimPrefix(strings.ToLower(host), "*.")
+
+	if host == "*" || host == "" {
+		return nil
+	}
+
+	reputation, ok := cached[host]
+	if !ok {
+		var err error
+
+		if p, ok := provider.(ContextReputationProvider); ok {
+			reputation, err = p.LookupContext(ctx, host)
+		} else {
+			reputation, err = provider.Lookup(host)
+		}
+
+		if err != nil {
+			return fmt.Errorf(errCSP1003, host, err)
+		}
+
+		cached[host] = reputation
+	}
+
+	if reputation.Blocklisted {
+		errs = multierror.Append(errs, fmt.Errorf(errCSP1001, directive, hostSource, reputation.Source))
+	}
+
+	if reputation.RecentlyRegistered {
+		errs = multierror.Append(errs, fmt.Errorf(errCSP1002, directive, hostSource, reputation.Source))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// domainListContains reports whether the host is, or is a subdomain of, any of
+// the domains in the list.
+func domainListContains(list []string, host string) bool {
+	host = strings.ToLower(host)
+
+	for i := range list {
+		domain := strings.ToLower(list[i])
+
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
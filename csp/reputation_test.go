@@ -0,0 +1,90 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestCheckReputation(t *testing.T) {
+	provider := NewLocalReputationList([]string{"evil.example"}, []string{"new.example"})
+
+	for name, tc := range map[string]struct {
+		CSP         string
+		ErrorSubstr []string
+	}{
+		"clean": {
+			CSP: "script-src 'self' https://cdn.example.com",
+		},
+		"blocklisted subdomain": {
+			CSP:         "script-src https://cdn.evil.example",
+			ErrorSubstr: []string{"which is on a blocklist (local list) [CSP-1001]"},
+		},
+		"blocklisted wildcard": {
+			CSP:         "img-src *.evil.example",
+			ErrorSubstr: []string{"directive `img-src` allows `*.evil.example`"},
+		},
+		"recently registered ancestor": {
+			CSP:         "frame-ancestors https://new.example",
+			ErrorSubstr: []string{"which was recently registered (local list) [CSP-1002]"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, _ := Parse("", "", []string{tc.CSP})
+			err := CheckReputation(policies[0], provider)
+
+			if len(tc.ErrorSubstr) == 0 {
+				assert.NoError(err)
+
+				return
+			}
+
+			merr, ok := err.(*multierror.Error)
+			assert.True(ok)
+
+			for _, substr := range tc.ErrorSubstr {
+				found := false
+
+				for _, e := range merr.Errors {
+					if strings.Contains(e.Error(), substr) {
+						found = true
+					}
+				}
+
+				assert.Truef(found, "Expected an error containing `%s`.", substr)
+			}
+		})
+	}
+}
+
+func TestCheckReputationContextCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	policies, _ := Parse("", "", []string{"script-src https://cdn.evil.example"})
+	err := CheckReputationContext(ctx, policies[0], NewLocalReputationList([]string{"evil.example"}, nil))
+
+	assert.ErrorIs(err, context.Canceled)
+}
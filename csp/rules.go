@@ -0,0 +1,190 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type (
+	// Rules configures evaluation rules, by the name of a rule group (see
+	// RuleNames) or by the code of a single diagnostic (e.g., `CSP-0106`).
+	//
+	// For example, to accept wildcards in `img-src` while still reporting them
+	// in every other directive:
+	//
+	//	{"wildcard": {"exclude-directives": ["img-src"]}}
+	Rules map[string]RuleConfig
+
+	// RuleFile is the contents of a rules file, which is shared by the CLI and
+	// the language server.
+	RuleFile struct {
+		// Rules configures evaluation rules.
+		Rules Rules `json:"rules,omitempty"`
+
+		// Schemes configures the scheme-source risk check. See WithSchemeRules.
+		Schemes []SchemeRule `json:"schemes,omitempty"`
+	}
+
+	// RuleConfig configures a single evaluation rule.
+	RuleConfig struct {
+		// ExcludeDirectives lists the directives in which the rule's diagnostics
+		// are not reported.
+		ExcludeDirectives []string `json:"exclude-directives,omitempty"`
+	}
+)
+
+// ruleGroups maps the name of each rule group to the codes of its diagnostics.
+var ruleGroups = map[string][]string{
+	"wildcard": {"CSP-0106", "CSP-0119"},
+}
+
+var (
+	// reRuleCode matches the code at the end of a diagnostic message.
+	reRuleCode = regexp.MustCompile(`\[(CSP-[0-9]+)\]$`)
+
+	// reRuleDirective matches the directive that a diagnostic message is about.
+	reRuleDirective = regexp.MustCompile("^\\[[A-Z]+\\] directive `([^`]+)`")
+)
+
+// RuleNames returns the names of the rule groups that Rules accepts, in
+// addition to the codes of single diagnostics.
+func RuleNames() []string {
+	names := make([]string, 0, len(ruleGroups))
+
+	for name := range ruleGroups {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// WithRules configures evaluation rules. See Rules for details. Rules with a
+// name that is neither a rule group nor a diagnostic code are ignored; use
+// Rules.Validate to report them.
+func WithRules(rules Rules) Option {
+	return func(c *config) {
+		c.rules = rules
+	}
+}
+
+/*
+Validate reports the rules whose name is neither a rule group nor the code of a
+diagnostic.
+
+----
+
+  - rules (Rules): The rules to validate.
+*/
+func (r Rules) Validate() error {
+	var errs []error
+
+	names := make([]string, 0, len(r))
+
+	for name := range r {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		if _, ok := ruleGroups[strings.ToLower(name)]; ok || slices.ContainsFunc(errorCatalog, func(msg string) bool {
+			return strings.HasSuffix(msg, "["+strings.ToUpper(name)+"]")
+		}) {
+			continue
+		}
+
+		errs = append(errs, errors.New("unknown rule `"+name+"`; expected one of: "+
+			strings.Join(RuleNames(), ", ")+", or a diagnostic code (e.g., CSP-0106)"))
+	}
+
+	return errors.Join(errs...)
+}
+
+/*
+ReadRules reads a rules file: a JSON document with the evaluation rules under a
+`rules` key, and the scheme-source risk rules under a `schemes` key.
+
+	{
+	  "rules": {"wildcard": {"exclude-directives": ["img-src"]}},
+	  "schemes": [{"scheme": "x-internal:", "allow": true}]
+	}
+
+----
+
+  - r (io.Reader): The contents of the rules file.
+*/
+func ReadRules(r io.Reader) (*RuleFile, error) {
+	var file RuleFile
+
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("could not read rules: %w", err)
+	}
+
+	if err := file.Rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// Options returns the options that apply the rules file to Parse. A nil
+// RuleFile returns no options.
+func (f *RuleFile) Options() []Option {
+	if f == nil {
+		return nil
+	}
+
+	return []Option{WithRules(f.Rules), WithSchemeRules(f.Schemes)}
+}
+
+// ruleExcludes reports whether the rules exclude a diagnostic, because it is
+// about a directive that its rule excludes.
+func (c *config) ruleExcludes(err error) bool {
+	if len(c.rules) == 0 {
+		return false
+	}
+
+	msg := err.Error()
+
+	code := reRuleCode.FindStringSubmatch(msg)
+	directive := reRuleDirective.FindStringSubmatch(msg)
+
+	if code == nil || directive == nil {
+		return false
+	}
+
+	for name, rule := range c.rules {
+		if !strings.EqualFold(name, code[1]) && !slices.Contains(ruleGroups[strings.ToLower(name)], code[1]) {
+			continue
+		}
+
+		if slices.ContainsFunc(rule.ExcludeDirectives, func(d string) bool {
+			return strings.EqualFold(d, directive[1])
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
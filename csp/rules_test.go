@@ -0,0 +1,91 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	const policy = "img-src * https://*:*; script-src *; report-to main"
+
+	for name, tc := range map[string]struct {
+		Rules    Rules
+		Reported []string
+		Excluded []string
+	}{
+		"no rules": {
+			Reported: []string{"`img-src`: host-source `*`", "`img-src`: host-source `https://*:*` allows any port",
+				"`script-src`: host-source `*`"},
+		},
+		"group excludes a directive": {
+			Rules:    Rules{"wildcard": {ExcludeDirectives: []string{"img-src"}}},
+			Reported: []string{"`script-src`: host-source `*`"},
+			Excluded: []string{"`img-src`"},
+		},
+		"code excludes a directive": {
+			Rules:    Rules{"csp-0119": {ExcludeDirectives: []string{"IMG-SRC"}}},
+			Reported: []string{"`img-src`: host-source `https://*:*` allows any port", "`script-src`: host-source `*`"},
+			Excluded: []string{"`img-src`: host-source `*`"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse("https://example.com", "", []string{policy}, WithoutReportingValidation(),
+				WithRules(tc.Rules))
+
+			for _, substr := range tc.Reported {
+				assert.ErrorContains(err, substr)
+			}
+
+			for _, substr := range tc.Excluded {
+				assert.NotContains(fmt.Sprint(err), substr)
+			}
+		})
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	assert := assert.New(t)
+
+	file, err := ReadRules(strings.NewReader(`{"rules": {"wildcard": {"exclude-directives": ["img-src"]}}, ` +
+		`"schemes": [{"scheme": "x-internal:", "allow": true}, {"scheme": "data:", "severity": "ERROR"}]}`))
+	assert.NoError(err)
+	assert.Equal(&RuleFile{
+		Rules: Rules{"wildcard": {ExcludeDirectives: []string{"img-src"}}},
+		Schemes: []SchemeRule{
+			{Scheme: "x-internal:", Allow: true},
+			{Scheme: "data:", Severity: SeverityError},
+		},
+	}, file)
+	assert.Len(file.Options(), 2)
+	assert.Nil((*RuleFile)(nil).Options())
+
+	_, err = ReadRules(strings.NewReader(`{"rules": {"wildcards": {}, "CSP-0106": {}, "CSP-9999": {}}}`))
+	assert.ErrorContains(err, "unknown rule `CSP-9999`")
+	assert.ErrorContains(err, "unknown rule `wildcards`; expected one of: wildcard, or a diagnostic code")
+	assert.NotContains(err.Error(), "`CSP-0106`")
+
+	_, err = ReadRules(strings.NewReader(`{"rules": [`))
+	assert.ErrorContains(err, "could not read rules")
+
+	_, err = ReadRules(strings.NewReader(`{"schemes": [{"scheme": "data:", "severity": "fatal"}]}`))
+	assert.ErrorContains(err, "invalid severity `fatal`")
+}
@@ -0,0 +1,99 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import "strings"
+
+// sandboxTokenInfo describes a sandbox token that the parser recognizes.
+type sandboxTokenInfo struct {
+	Name string
+
+	// Support is empty for the tokens in the HTML standard. Otherwise, it
+	// describes where the token is defined and which browsers support it.
+	Support string
+}
+
+// sandboxRegistry is the list of sandbox tokens that the parser recognizes: the
+// tokens in the HTML standard, followed by tokens that only some browsers
+// support.
+//
+// https://html.spec.whatwg.org/multipage/iframe-embed-object.html#attr-iframe-sandbox
+var sandboxRegistry = []sandboxTokenInfo{
+	{Name: "allow-downloads"},
+	{Name: "allow-forms"},
+	{Name: "allow-modals"},
+	{Name: "allow-orientation-lock"},
+	{Name: "allow-pointer-lock"},
+	{Name: "allow-popups"},
+	{Name: "allow-popups-to-escape-sandbox"},
+	{Name: "allow-presentation"},
+	{Name: "allow-same-origin"},
+	{Name: "allow-scripts"},
+	{Name: "allow-top-navigation"},
+	{Name: "allow-top-navigation-by-user-activation"},
+	{Name: "allow-top-navigation-to-custom-protocols"},
+	{
+		Name:    "allow-downloads-without-user-activation",
+		Support: "was never standardized, and was only supported by Chromium behind a flag",
+	},
+	{
+		Name:    "allow-storage-access-by-user-activation",
+		Support: "is defined by the Storage Access API rather than the HTML standard, and is not supported by every browser",
+	},
+}
+
+// maxSandboxTypoDistance is the largest edit distance between an unknown sandbox
+// token and a known one for which the parser suggests the known token.
+const maxSandboxTypoDistance = 3
+
+var (
+	// sandboxTokens is the name of every token in sandboxRegistry.
+	sandboxTokens = sandboxTokenNames(false)
+
+	// standardSandboxTokens is the name of every token in the HTML standard.
+	standardSandboxTokens = sandboxTokenNames(true)
+)
+
+// sandboxTokenNames returns the names of the tokens in sandboxRegistry,
+// optionally only those in the HTML standard.
+func sandboxTokenNames(standardOnly bool) []string {
+	names := make([]string, 0, len(sandboxRegistry))
+
+	for i := range sandboxRegistry {
+		if !standardOnly || sandboxRegistry[i].Support == "" {
+			names = append(names, sandboxRegistry[i].Name)
+		}
+	}
+
+	return names
+}
+
+/*
+lookupSandboxToken returns the registry entry for a sandbox token. Tokens are
+case-insensitive.
+
+----
+
+  - s (string): The value that will be evaluated.
+*/
+func lookupSandboxToken(s string) (sandboxTokenInfo, bool) {
+	for i := range sandboxRegistry {
+		if strings.EqualFold(s, sandboxRegistry[i].Name) {
+			return sandboxRegistry[i], true
+		}
+	}
+
+	return sandboxTokenInfo{}, false
+}
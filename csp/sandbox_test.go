@@ -0,0 +1,100 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSandboxTokens(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Policy      string
+		ErrorSubstr string
+	}{
+		"standard": {
+			Policy: "sandbox allow-scripts allow-top-navigation-to-custom-protocols",
+		},
+		"case-insensitive": {
+			Policy: "sandbox Allow-Scripts",
+		},
+		"non-standard": {
+			Policy:      "sandbox allow-storage-access-by-user-activation",
+			ErrorSubstr: "[INFO] directive `sandbox`: sandbox token `allow-storage-access-by-user-activation` is defined",
+		},
+		"typo": {
+			Policy:      "sandbox allow-downloads-without-user-activaton",
+			ErrorSubstr: "did you mean `allow-downloads-without-user-activation`? [CSP-0702]",
+		},
+		"unknown": {
+			Policy:      "sandbox allow-malware",
+			ErrorSubstr: "has an invalid value `allow-malware` [CSP-0700]",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse("https://example.com", "", []string{tc.Policy + "; report-to main"},
+				WithoutReportingValidation())
+
+			if tc.ErrorSubstr == "" {
+				assert.NoError(err)
+
+				return
+			}
+
+			assert.ErrorContains(err, tc.ErrorSubstr)
+		})
+	}
+}
+
+func TestParseSandboxEscape(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Policy      string
+		Disposition Disposition
+		Escape      bool
+	}{
+		"scripts only":     {Policy: "sandbox allow-scripts"},
+		"same-origin only": {Policy: "sandbox allow-same-origin"},
+		"both":             {Policy: "sandbox allow-scripts allow-forms allow-same-origin", Escape: true},
+		"both, any case":   {Policy: "sandbox Allow-Same-Origin ALLOW-SCRIPTS", Escape: true},
+		"report-only": {
+			Policy:      "sandbox allow-scripts allow-same-origin",
+			Disposition: DispositionReport,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			opts := []Option{WithoutReportingValidation()}
+			if tc.Disposition != "" {
+				opts = append(opts, WithDisposition(tc.Disposition))
+			}
+
+			_, err := Parse("https://example.com", "", []string{tc.Policy + "; report-to main"}, opts...)
+
+			if tc.Escape {
+				assert.ErrorContains(err, "[WARN] directive `sandbox` allows both `allow-scripts` and "+
+					"`allow-same-origin`")
+
+				return
+			}
+
+			assert.NotContains(fmt.Sprint(err), "[CSP-0704]")
+		})
+	}
+}
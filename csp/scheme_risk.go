@@ -0,0 +1,164 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type (
+	// SchemeRisk is the risk tier of a scheme-source.
+	SchemeRisk string
+
+	// SchemeRule sets how the scheme-source risk check (CSP-0121) treats a scheme
+	// in some directives.
+	SchemeRule struct {
+		// Scheme is the scheme, with or without its trailing colon (e.g., `data:`).
+		Scheme string `json:"scheme"`
+
+		// Directives lists the directives that the rule applies to. An empty list
+		// applies the rule to every directive.
+		Directives []string `json:"directives,omitempty"`
+
+		// Severity is the severity of the diagnostic.
+		Severity Severity `json:"severity"`
+
+		// Allow accepts the scheme in these directives, without a diagnostic
+		// (e.g., for an internal application scheme).
+		Allow bool `json:"allow,omitempty"`
+	}
+)
+
+// scriptContexts are the directives that govern scripts or plugins, directly or
+// as a fallback, where a scheme that lets an attacker supply content leads to
+// script injection.
+var scriptContexts = []string{"default-src", "script-src", "script-src-elem", "script-src-attr", "object-src"}
+
+// DefaultSchemeRules is the scheme-source risk table that is used when
+// WithSchemeRules is not passed, and after the rules that it passes.
+var DefaultSchemeRules = []SchemeRule{
+	{Scheme: "data:", Directives: scriptContexts, Severity: SeverityWarning},
+	{Scheme: "blob:", Directives: scriptContexts, Severity: SeverityWarning},
+	{Scheme: "filesystem:", Severity: SeverityWarning},
+	{Scheme: "ftp:", Severity: SeverityWarning},
+}
+
+const (
+	// SchemeRiskOK is used for schemes that only allow encrypted network
+	// connections (`https:`, `wss:`).
+	SchemeRiskOK SchemeRisk = "ok"
+
+	// SchemeRiskInsecure is used for schemes that allow unencrypted network
+	// connections (`http:`, `ws:`, `ftp:`).
+	SchemeRiskInsecure SchemeRisk = "insecure"
+
+	// SchemeRiskDangerous is used for schemes whose content can be crafted by an
+	// attacker without a network request (`data:`, `blob:`, `filesystem:`,
+	// `javascript:`).
+	SchemeRiskDangerous SchemeRisk = "dangerous"
+
+	// SchemeRiskInformational is used for every other scheme (e.g., custom
+	// application schemes like `x-man-page:`).
+	SchemeRiskInformational SchemeRisk = "informational"
+)
+
+/*
+ClassifyScheme returns the risk tier of a scheme. The scheme may be passed with
+or without its trailing colon.
+
+----
+
+  - scheme (string): The scheme to classify (e.g., `data:`).
+*/
+func ClassifyScheme(scheme string) SchemeRisk {
+	switch strings.ToLower(strings.TrimSuffix(scheme, ":")) {
+	case "https", "wss":
+		return SchemeRiskOK
+	case "http", "ws", "ftp":
+		return SchemeRiskInsecure
+	case "data", "blob", "filesystem", "javascript":
+		return SchemeRiskDangerous
+	}
+
+	return SchemeRiskInformational
+}
+
+// WithSchemeRules adds rules to the scheme-source risk check, which take
+// precedence over DefaultSchemeRules. The first rule that matches a scheme and a
+// directive is used, so a rule can raise or lower the severity of a default one,
+// or allow a scheme (e.g., an internal application scheme) outright.
+func WithSchemeRules(rules []SchemeRule) Option {
+	return func(c *config) {
+		c.schemeRules = rules
+	}
+}
+
+// isJavaScriptScheme reports whether a scheme-source or host-source allows
+// `javascript:` URLs. Those are reported as CSP-0122 in every directive, rather
+// than through the scheme rules.
+func isJavaScriptScheme(value string) bool {
+	scheme, _, _ := strings.Cut(value, ":")
+
+	return strings.EqualFold(scheme, "javascript") && strings.Contains(value, ":")
+}
+
+/*
+schemeRiskError returns the diagnostic for a scheme-source, according to the
+first scheme rule that matches it, or nil if no rule matches or the rule allows
+the scheme.
+
+----
+
+  - key (string): The name of the directive.
+
+  - value (string): The scheme-source, as it was written.
+*/
+func (c *config) schemeRiskError(key, value string) error {
+	scheme := strings.ToLower(strings.TrimSuffix(value, ":"))
+
+	for _, rule := range slices.Concat(c.schemeRules, DefaultSchemeRules) {
+		if strings.ToLower(strings.TrimSuffix(rule.Scheme, ":")) != scheme {
+			continue
+		}
+
+		if len(rule.Directives) > 0 && !slices.ContainsFunc(rule.Directives, func(d string) bool {
+			return strings.EqualFold(d, key)
+		}) {
+			continue
+		}
+
+		if rule.Allow {
+			return nil
+		}
+
+		var reason string
+
+		switch ClassifyScheme(scheme) {
+		case SchemeRiskDangerous:
+			reason = "lets an attacker supply the content without a network request"
+		case SchemeRiskInsecure:
+			reason = "loads the content over an unencrypted connection"
+		default:
+			reason = "is flagged by the scheme-source rules"
+		}
+
+		return errors.New(withSeverity(fmt.Sprintf(errCSP0121, key, value, reason), rule.Severity))
+	}
+
+	return nil
+}
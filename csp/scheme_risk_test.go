@@ -0,0 +1,126 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestClassifyScheme(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Input    string
+		Expected SchemeRisk
+	}{
+		"https:":      {Input: "https:", Expected: SchemeRiskOK},
+		"WSS:":        {Input: "WSS:", Expected: SchemeRiskOK},
+		"http:":       {Input: "http:", Expected: SchemeRiskInsecure},
+		"ws":          {Input: "ws", Expected: SchemeRiskInsecure},
+		"ftp:":        {Input: "ftp:", Expected: SchemeRiskInsecure},
+		"data:":       {Input: "data:", Expected: SchemeRiskDangerous},
+		"blob:":       {Input: "blob:", Expected: SchemeRiskDangerous},
+		"filesystem:": {Input: "filesystem:", Expected: SchemeRiskDangerous},
+		"javascript:": {Input: "javascript:", Expected: SchemeRiskDangerous},
+		"x-man-page:": {Input: "x-man-page:", Expected: SchemeRiskInformational},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			actual := ClassifyScheme(tc.Input)
+
+			assert.Equalf(tc.Expected, actual, "Expected `%v`, but got `%v`.", tc.Expected, actual)
+		})
+	}
+}
+
+func TestParseSchemeRisk(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, _ := Parse("", "", []string{"img-src https: data:"})
+
+	assert.Equal(SchemeRiskOK, policies[0].ImageSource[0].SourceExprs[0].SchemeRisk)
+	assert.Equal(SchemeRiskDangerous, policies[0].ImageSource[0].SourceExprs[1].SchemeRisk)
+}
+
+func TestParseSchemeRules(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CSP         string
+		Rules       []SchemeRule
+		ErrorSubstr string
+	}{
+		"data: in img-src": {CSP: "img-src data:"},
+		"data: in script-src": {
+			CSP: "script-src data:",
+			ErrorSubstr: "[WARN] directive `script-src`: scheme-source `data:` lets an attacker supply the content " +
+				"without a network request [CSP-0121]",
+		},
+		"data: in default-src": {
+			CSP:         "default-src 'self' data:",
+			ErrorSubstr: "directive `default-src`: scheme-source `data:`",
+		},
+		"blob: in worker-src": {CSP: "worker-src blob:"},
+		"ftp: anywhere": {
+			CSP:         "img-src FTP:",
+			ErrorSubstr: "[WARN] directive `img-src`: scheme-source `FTP:` loads the content over an unencrypted",
+		},
+		"raised severity": {
+			CSP:         "script-src data:",
+			Rules:       []SchemeRule{{Scheme: "data", Severity: SeverityError}},
+			ErrorSubstr: "[ERROR] directive `script-src`: scheme-source `data:`",
+		},
+		"allowed in one directive": {
+			CSP:   "object-src blob:",
+			Rules: []SchemeRule{{Scheme: "blob:", Directives: []string{"object-src"}, Allow: true}},
+		},
+		"internal scheme": {
+			CSP:         "img-src x-internal:",
+			Rules:       []SchemeRule{{Scheme: "x-internal:", Severity: SeverityInfo}},
+			ErrorSubstr: "[INFO] directive `img-src`: scheme-source `x-internal:` is flagged by the scheme-source rules",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse("https://example.com", "", []string{tc.CSP + "; report-to main"},
+				WithoutReportingValidation(), WithSchemeRules(tc.Rules))
+
+			if tc.ErrorSubstr == "" {
+				assert.NotContains(fmt.Sprint(err), "[CSP-0121]")
+
+				return
+			}
+
+			assert.ErrorContains(err, tc.ErrorSubstr)
+		})
+	}
+}
+
+func TestIsJavaScriptScheme(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isJavaScriptScheme("javascript:"))
+	assert.True(isJavaScriptScheme("JavaScript:"))
+	assert.True(isJavaScriptScheme("javascript://example.com"))
+	assert.False(isJavaScriptScheme("javascript.example.com"))
+	assert.False(isJavaScriptScheme("https:"))
+
+	// CSP-0122 replaces the scheme rules for `javascript:`.
+	_, err := Parse("https://example.com", "", []string{"img-src javascript:; report-to main"},
+		WithoutReportingValidation())
+	assert.ErrorContains(err, "[CSP-0122]")
+	assert.NotContains(err.Error(), "[CSP-0121]")
+}
@@ -0,0 +1,96 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"slices"
+	"strings"
+)
+
+/*
+commaMistake reports whether a `,` between two parts of a header value looks
+like a separator mistake, rather than the boundary between two policies: either
+the comma touches a token on both sides (e.g., `'self',style-src`), or the text
+after it does not start with a directive name (e.g., `a.example, b.example`). It
+also returns the tokens around the comma, for the diagnostic.
+
+----
+
+  - before (string): The header value up to the comma.
+
+  - after (string): The header value after the comma, up to the next comma.
+*/
+func commaMistake(before, after string) (string, bool) {
+	prev := strings.Fields(before)
+	next := strings.Fields(after)
+
+	if len(prev) == 0 || len(next) == 0 {
+		return "", false
+	}
+
+	last := prev[len(prev)-1]
+	first := strings.Split(next[0], ";")[0]
+	context := last + "," + first
+
+	if strings.TrimRightFunc(before, isSpaceRune) != before || strings.TrimLeftFunc(after, isSpaceRune) != after {
+		if first != "" && slices.Contains(knownDirectives, strings.ToLower(first)) {
+			return "", false
+		}
+
+		context = last + ", " + first
+	}
+
+	return context, true
+}
+
+/*
+misplacedValue reports whether an unknown directive name looks like a value of
+the directive before it, cut off by a `;` that was meant to be a space (e.g.,
+`script-src https://a.example; 'unsafe-inline'`, or
+`sandbox allow-scripts;allow-forms`).
+
+----
+
+  - previous (string): The lowercase name of the directive before it, or an
+    empty string if it is the first directive.
+
+  - key (string): The name of the unknown directive.
+*/
+func misplacedValue(previous, key string) bool {
+	switch {
+	case previous == "sandbox":
+		return isSandboxSource(key)
+	case slices.Contains(sourceListDirectives, previous) || previous == "frame-ancestors":
+		return isKeywordSource(key) || isNonceSource(key) || isHashSource(key) || isSchemeSource(key) ||
+			(isHostSource(key) && strings.ContainsAny(key, ".:*"))
+	}
+
+	return false
+}
+
+// isSpaceRune reports whether the rune is ASCII whitespace.
+func isSpaceRune(r rune) bool {
+	return r < 0x80 && isASCIIWhitespace(byte(r))
+}
+
+// previousDirective returns the name of the directive before the one being
+// parsed, or an empty string if it is the first directive.
+func previousDirective(order []string) string {
+	if len(order) < 2 {
+		return ""
+	}
+
+	return order[len(order)-2]
+}
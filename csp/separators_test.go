@@ -0,0 +1,101 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommaMistake(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Before   string
+		After    string
+		Expected string
+	}{
+		"no whitespace around the comma": {
+			Before:   "script-src 'self'",
+			After:    "style-src 'self'",
+			Expected: "'self',style-src",
+		},
+		"comma between sources": {
+			Before:   "script-src https://a.example.com",
+			After:    " https://b.example.com",
+			Expected: "https://a.example.com, https://b.example.com",
+		},
+		"separate policies": {
+			Before: "default-src 'self'",
+			After:  " script-src 'none'",
+		},
+		"directive without values": {
+			Before:   "script-src 'self'",
+			After:    " upgrade-insecure-requests;",
+			Expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			context, ok := commaMistake(tc.Before, tc.After)
+
+			assert.Equal(tc.Expected, context)
+			assert.Equal(tc.Expected != "", ok)
+		})
+	}
+}
+
+func TestParseSeparatorMistakes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CSP         string
+		ErrorSubstr string
+		Policies    int
+	}{
+		"comma instead of semicolon": {
+			CSP:         "script-src 'self',style-src 'self'",
+			ErrorSubstr: "`'self',style-src` has a `,`, which starts a new policy",
+			Policies:    2,
+		},
+		"comma between sources": {
+			CSP:         "script-src https://a.example.com, https://b.example.com",
+			ErrorSubstr: "`https://a.example.com, https://b.example.com` has a `,`",
+			Policies:    2,
+		},
+		"semicolon between sources": {
+			CSP:         "script-src https://a.example.com; 'unsafe-inline'",
+			ErrorSubstr: "unknown directive `'unsafe-inline'`, which looks like a value of `script-src`",
+			Policies:    1,
+		},
+		"semicolon between sandbox tokens": {
+			CSP:         "sandbox allow-scripts;allow-forms",
+			ErrorSubstr: "unknown directive `allow-forms`, which looks like a value of `sandbox`",
+			Policies:    1,
+		},
+		"unrelated unknown directive": {
+			CSP:         "script-src 'self'; bogus",
+			ErrorSubstr: "unknown directive `bogus` [CSP-0901]",
+			Policies:    1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, err := Parse("https://example.com", "", []string{tc.CSP}, WithoutReportingValidation())
+
+			assert.Len(policies, tc.Policies)
+			assert.ErrorContains(err, tc.ErrorSubstr)
+		})
+	}
+}
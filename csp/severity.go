@@ -0,0 +1,145 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Severity is the severity of a diagnostic, as shown by the `[INFO]`, `[WARN]`,
+// or `[ERROR]` prefix of its message.
+type Severity int
+
+const (
+	// SeverityInfo is used for informational notes.
+	SeverityInfo Severity = iota
+
+	// SeverityWarning is used for problems that do not break the policy.
+	SeverityWarning
+
+	// SeverityError is used for problems that break the policy, or that make part
+	// of it ineffective.
+	SeverityError
+)
+
+// String returns the severity as it appears in diagnostic messages.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarning:
+		return "WARN"
+	}
+
+	return "ERROR"
+}
+
+// MarshalText encodes the severity as `info`, `warn`, or `error`.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+// UnmarshalText decodes `info`, `warn` (or `warning`), or `error`, in any case.
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "info":
+		*s = SeverityInfo
+	case "warn", "warning":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		return fmt.Errorf("invalid severity `%s`; expected one of: info, warn, error", text)
+	}
+
+	return nil
+}
+
+/*
+SeverityOf returns the severity of a diagnostic returned by this package. Errors
+without a severity prefix (e.g., context cancellation) are treated as errors.
+
+----
+
+  - err (error): A single diagnostic (not a multierror).
+*/
+func SeverityOf(err error) Severity {
+	msg := err.Error()
+
+	switch {
+	case strings.HasPrefix(msg, "[INFO]"):
+		return SeverityInfo
+	case strings.HasPrefix(msg, "[WARN]"):
+		return SeverityWarning
+	}
+
+	return SeverityError
+}
+
+// MinSeverity controls which diagnostics Parse returns. Diagnostics below the
+// given severity are dropped (e.g., MinSeverity(SeverityWarning) suppresses the
+// CSP-0001 and CSP-0002 notes, along with every other note; to opt out of only
+// those, use WithoutSelfValidation and WithoutReportingValidation).
+func MinSeverity(s Severity) Option {
+	return func(c *config) {
+		c.minSeverity = s
+	}
+}
+
+/*
+withSeverity replaces the severity prefix of a diagnostic message, for the
+diagnostics whose severity can be configured.
+
+----
+
+  - msg (string): The diagnostic message, with its default severity prefix.
+
+  - s (Severity): The severity to use instead.
+*/
+func withSeverity(msg string, s Severity) string {
+	_, rest, _ := strings.Cut(msg, "]")
+
+	return "[" + s.String() + "]" + rest
+}
+
+// filterSeverity drops the errors that are below the minimum severity, or that
+// the rules exclude.
+func (c *config) filterSeverity(errs []error) []error {
+	filtered := make([]error, 0, len(errs))
+
+	for i := range errs {
+		if SeverityOf(errs[i]) >= c.minSeverity && !c.ruleExcludes(errs[i]) {
+			filtered = append(filtered, errs[i])
+		}
+	}
+
+	return filtered
+}
+
+// result wraps the errors that meet the minimum severity in a multierror, or
+// returns nil if there are none.
+func (c *config) result(errs *multierror.Error) error {
+	filtered := c.filterSeverity(errorsOf(errs))
+	c.endStats(len(filtered))
+
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	return &multierror.Error{Errors: filtered}
+}
@@ -0,0 +1,513 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/net/html"
+)
+
+type (
+	// SiteAudit lists everything in a static site that the policy would block.
+	SiteAudit struct {
+		Files   int               `json:"files"`
+		Blocked []BlockedResource `json:"blocked,omitempty"`
+
+		// StaticNonces lists the elements whose `nonce` attribute matches a
+		// nonce-source in the policy. A nonce in a static file is served with
+		// every response, so it no longer protects anything.
+		StaticNonces []StaticNonce `json:"staticNonces,omitempty"`
+	}
+
+	// StaticNonce is a nonce from the policy that appears in a static file.
+	StaticNonce struct {
+		File      string `json:"file"`
+		Directive string `json:"directive"`
+		Nonce     string `json:"nonce"`
+	}
+
+	// BlockedResource is a resource or piece of inline content in a static site
+	// that the policy would block.
+	BlockedResource struct {
+		// File is the path of the HTML or CSS file, relative to the root of the
+		// site.
+		File string `json:"file"`
+
+		// Directive is the directive (after fallback) that blocks the resource.
+		Directive string `json:"directive"`
+
+		// URL is the absolute URL of the resource. It is empty for inline content.
+		URL string `json:"url,omitempty"`
+
+		// Inline describes inline content: an element (e.g., `<script>`), or the
+		// name of an attribute (e.g., `onclick`). It is empty for resources loaded
+		// by URL.
+		Inline string `json:"inline,omitempty"`
+
+		// Hash is the `'sha256-...'` hash-source that would allow inline content.
+		Hash string `json:"hash,omitempty"`
+	}
+
+	// siteReference is a URL that a file refers to, and the directive that
+	// governs loading it. The `nonce` and `integrity` attributes are only set
+	// for `<script>` and stylesheet `<link>` elements.
+	siteReference struct {
+		directive string
+		url       string
+		nonce     string
+		integrity string
+	}
+
+	// inlineAttribute is an event handler or `style` attribute.
+	inlineAttribute struct {
+		directive string
+		name      string
+		content   string
+	}
+)
+
+var (
+	// reCSSURL matches `url(...)` references in CSS.
+	reCSSURL = regexp.MustCompile(`url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+
+	// reCSSImport matches `@import "..."` rules in CSS (`@import url(...)` is
+	// matched by reCSSURL).
+	reCSSImport = regexp.MustCompile(`@import\s+['"]([^'"]+)['"]`)
+)
+
+/*
+AuditFS walks a built static site and reports every resource and piece of
+inline content that the policy would block, before the site is deployed. HTML
+files (`.html`, `.htm`) are checked for elements that load resources, inline
+`<script>` and `<style>` elements, event handler attributes, and `style`
+attributes. CSS files (`.css`) and inline `<style>` elements are checked for
+`url(...)` references and `@import` rules. Scripts and stylesheets are allowed
+by a matching `nonce` attribute, and scripts by a matching `integrity`
+attribute, as well as by URL. HTML files that contain a nonce from
+the policy are reported too, since a nonce in a static file is reused.
+
+The returned error contains a diagnostic for each blocked resource. Errors
+reading the file system are returned with a nil SiteAudit.
+
+----
+
+  - policy (*Policy): The parsed policy to evaluate.
+
+  - fsys (fs.FS): The root of the built site (e.g., `os.DirFS("./public")`).
+
+  - siteURL (string): The absolute URL that the root of the site will be served
+    from (e.g., `https://example.com/`). It is used to resolve relative URLs,
+    and as the origin for `'self'`.
+*/
+func AuditFS(policy *Policy, fsys fs.FS, siteURL string) (*SiteAudit, error) {
+	var errs *multierror.Error
+
+	base, err := url.Parse(siteURL)
+	if err != nil || !base.IsAbs() {
+		return nil, fmt.Errorf(errCSP0004, siteURL)
+	}
+
+	audit := &SiteAudit{}
+
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		ext := strings.ToLower(path.Ext(name))
+		if ext != ".html" && ext != ".htm" && ext != ".css" {
+			return nil
+		}
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		audit.Files++
+		fileURL := base.ResolveReference(&url.URL{Path: name}).String()
+
+		var (
+			blocked []BlockedResource
+			nonces  []StaticNonce
+		)
+
+		if ext == ".css" {
+			blocked = auditReferences(policy, siteURL, fileURL, cssReferences(string(b)))
+		} else {
+			blocked, nonces, err = auditHTMLFile(policy, siteURL, fileURL, b)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := range nonces {
+			nonces[i].File = name
+			errs = multierror.Append(errs, fmt.Errorf(errCSP1205, name, nonces[i].Nonce, nonces[i].Directive))
+		}
+
+		audit.StaticNonces = append(audit.StaticNonces, nonces...)
+
+		for i := range blocked {
+			blocked[i].File = name
+
+			if blocked[i].Inline != "" {
+				errs = multierror.Append(errs, fmt.Errorf(
+					errCSP1204, name, blocked[i].Inline, blocked[i].Directive, blocked[i].Hash,
+				))
+			} else {
+				errs = multierror.Append(errs, fmt.Errorf(errCSP1203, name, blocked[i].URL, blocked[i].Directive))
+			}
+		}
+
+		audit.Blocked = append(audit.Blocked, blocked...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return audit, errs.ErrorOrNil()
+}
+
+// auditHTMLFile returns the resources and inline content of an HTML file that
+// the policy would block, and the nonces from the policy that the file contains.
+func auditHTMLFile(policy *Policy, siteURL, fileURL string, b []byte) ([]BlockedResource, []StaticNonce, error) {
+	doc, err := html.Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	references, attributes := collectSiteReferences(doc, nil, nil)
+	elements := collectAuditElements(doc, nil)
+
+	for i := range elements {
+		if elements[i].inline && elements[i].directive == "style-src-elem" {
+			references = append(references, cssReferences(elements[i].content)...)
+		}
+	}
+
+	blocked := auditReferences(policy, siteURL, fileURL, references)
+
+	for i := range elements {
+		if !elements[i].inline {
+			continue
+		}
+
+		effective, list, ok := policy.effectiveSourceList(elements[i].directive)
+		if !ok || allowsUnsafeInline(list) {
+			continue
+		}
+
+		allowed := false
+
+		for j := range list.SourceExprs {
+			allowed = allowed || auditSourceMatches(&list.SourceExprs[j], &elements[i])
+		}
+
+		if !allowed {
+			blocked = append(blocked, BlockedResource{
+				Directive: effective,
+				Inline:    "<" + strings.TrimSuffix(elements[i].directive, "-src-elem") + ">",
+				Hash:      hashSource("sha256", elements[i].content),
+			})
+		}
+	}
+
+	for i := range attributes {
+		effective, list, ok := policy.effectiveSourceList(attributes[i].directive)
+		if !ok || allowsUnsafeInline(list) || allowsHashedAttribute(list, attributes[i].content) {
+			continue
+		}
+
+		blocked = append(blocked, BlockedResource{
+			Directive: effective,
+			Inline:    attributes[i].name,
+			Hash:      hashSource("sha256", attributes[i].content),
+		})
+	}
+
+	return blocked, staticNonces(policy, elements), nil
+}
+
+// staticNonces returns the nonces from the policy that elements of the file
+// use, once each.
+func staticNonces(policy *Policy, elements []auditElement) []StaticNonce {
+	nonces := []StaticNonce{}
+
+	for _, use := range policyNonces(policy) {
+		if slices.ContainsFunc(nonces, func(n StaticNonce) bool { return n.Nonce == use.nonce }) {
+			continue
+		}
+
+		if slices.ContainsFunc(elements, func(e auditElement) bool { return e.nonce == use.nonce }) {
+			nonces = append(nonces, StaticNonce{Directive: use.directive, Nonce: use.nonce})
+		}
+	}
+
+	return nonces
+}
+
+// auditReferences returns the references that the policy would block, resolved
+// against the URL of the file that contains them. Each blocked URL is reported
+// once per directive.
+func auditReferences(policy *Policy, siteURL, fileURL string, references []siteReference) []BlockedResource {
+	blocked := []BlockedResource{}
+	seen := map[siteReference]bool{}
+	base, _ := url.Parse(fileURL)
+
+	for i := range references {
+		ref, err := url.Parse(strings.TrimSpace(references[i].url))
+		if err != nil || (ref.Scheme == "" && ref.Host == "" && ref.Path == "") {
+			// Unparseable, or a fragment-only reference within the same file.
+			continue
+		}
+
+		target := base.ResolveReference(ref).String()
+		key := siteReference{directive: references[i].directive, url: target}
+
+		if seen[key] {
+			continue
+		}
+
+		if _, list, ok := policy.effectiveSourceList(key.directive); ok && allowsElementMetadata(list, &references[i]) {
+			continue
+		}
+
+		explanation, err := policy.Explain(siteURL, references[i].directive, target)
+		if err != nil || explanation.Allowed {
+			continue
+		}
+
+		seen[key] = true
+
+		blocked = append(blocked, BlockedResource{
+			Directive: explanation.EffectiveDirective,
+			URL:       target,
+		})
+	}
+
+	return blocked
+}
+
+// collectSiteReferences walks the document and returns the URLs that it loads,
+// and its event handler and `style` attributes, in document order.
+func collectSiteReferences(
+	n *html.Node,
+	references []siteReference,
+	attributes []inlineAttribute,
+) ([]siteReference, []inlineAttribute) {
+	if n.Type == html.ElementNode {
+		add := func(directive, key string) {
+			if hasAttr(n, key) {
+				references = append(references, siteReference{directive: directive, url: attr(n, key)})
+			}
+		}
+
+		addElement := func(directive, key string) {
+			if hasAttr(n, key) {
+				references = append(references, siteReference{
+					directive: directive,
+					url:       attr(n, key),
+					nonce:     attr(n, "nonce"),
+					integrity: attr(n, "integrity"),
+				})
+			}
+		}
+
+		switch n.Data {
+		case "script":
+			if isScriptBlock(n) {
+				addElement("script-src-elem", "src")
+			}
+		case "link":
+			switch rel := strings.ToLower(attr(n, "rel")); {
+			case strings.Contains(rel, "stylesheet"):
+				addElement("style-src-elem", "href")
+			case strings.Contains(rel, "icon"):
+				add("img-src", "href")
+			case rel == "manifest":
+				add("manifest-src", "href")
+			}
+		case "img":
+			add("img-src", "src")
+		case "audio", "video", "track":
+			add("media-src", "src")
+		case "source":
+			if n.Parent != nil && (n.Parent.Data == "audio" || n.Parent.Data == "video") {
+				add("media-src", "src")
+			} else {
+				add("img-src", "src")
+			}
+		case "iframe":
+			add("frame-src", "src")
+		case "object":
+			add("object-src", "data")
+		case "embed":
+			add("object-src", "src")
+		case "form":
+			add("form-action", "action")
+		case "base":
+			add("base-uri", "href")
+		}
+
+		for i := range n.Attr {
+			key := strings.ToLower(n.Attr[i].Key)
+
+			switch {
+			case strings.HasPrefix(key, "on"):
+				attributes = append(attributes, inlineAttribute{"script-src-attr", key, n.Attr[i].Val})
+			case key == "style":
+				attributes = append(attributes, inlineAttribute{"style-src-attr", key, n.Attr[i].Val})
+				references = append(references, cssReferences(n.Attr[i].Val)...)
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		references, attributes = collectSiteReferences(c, references, attributes)
+	}
+
+	return references, attributes
+}
+
+// cssReferences returns the URLs that a stylesheet loads. Font files are
+// governed by `font-src`, imported stylesheets by `style-src-elem`, and
+// everything else by `img-src`.
+func cssReferences(css string) []siteReference {
+	references := []siteReference{}
+	imported := map[string]bool{}
+
+	for _, m := range reCSSImport.FindAllStringSubmatch(css, -1) {
+		imported[m[1]] = true
+		references = append(references, siteReference{directive: "style-src-elem", url: m[1]})
+	}
+
+	for _, m := range reCSSURL.FindAllStringSubmatchIndex(css, -1) {
+		ref := css[m[2]:m[3]]
+		directive := "img-src"
+
+		switch {
+		case strings.HasSuffix(strings.TrimRight(css[:m[0]], " \t\r\n"), "@import"):
+			directive = "style-src-elem"
+		case isFontFile(ref):
+			directive = "font-src"
+		}
+
+		if !imported[ref] {
+			references = append(references, siteReference{directive: directive, url: ref})
+		}
+	}
+
+	return references
+}
+
+// isFontFile reports whether the URL points to a web font, based on its file
+// extension.
+func isFontFile(ref string) bool {
+	if u, err := url.Parse(ref); err == nil {
+		ref = u.Path
+	}
+
+	switch strings.ToLower(path.Ext(ref)) {
+	case ".woff", ".woff2", ".ttf", ".otf", ".eot":
+		return true
+	}
+
+	return false
+}
+
+// allowsHashedAttribute reports whether the list allows an event handler or
+// `style` attribute by its hash, which requires `'unsafe-hashes'`.
+func allowsHashedAttribute(list *SourceListItem, content string) bool {
+	if !containsKeyword(list, "'unsafe-hashes'") {
+		return false
+	}
+
+	for i := range list.SourceExprs {
+		if list.SourceExprs[i].HashSource == "" {
+			continue
+		}
+
+		element := auditElement{inline: true, content: content}
+
+		if auditSourceMatches(&list.SourceExprs[i], &element) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+allowsElementMetadata reports whether the list allows an external resource by
+the `nonce` or `integrity` attribute of the element that loads it, which
+`Explain` does not consider. A nonce-source must match the nonce. For scripts,
+every hash in the `integrity` attribute must match a hash-source.
+
+https://w3c.github.io/webappsec-csp/#match-element-to-source-list
+
+----
+
+  - list (*SourceListItem): The effective source list for the element.
+
+  - ref (*siteReference): The resource and the attributes of its element.
+*/
+func allowsElementMetadata(list *SourceListItem, ref *siteReference) bool {
+	element := auditElement{nonce: ref.nonce}
+
+	for i := range list.SourceExprs {
+		if list.SourceExprs[i].NonceSource != "" && auditSourceMatches(&list.SourceExprs[i], &element) {
+			return true
+		}
+	}
+
+	if ref.directive != "script-src-elem" {
+		return false
+	}
+
+	matched := 0
+
+	for _, token := range strings.Fields(ref.integrity) {
+		token, _, _ = strings.Cut(token, "?")
+		algorithm, digest, _ := strings.Cut(token, "-")
+		algorithm = strings.ToLower(algorithm)
+
+		if algorithm != "sha256" && algorithm != "sha384" && algorithm != "sha512" {
+			// Unknown algorithms are ignored, as in Subresource Integrity.
+			continue
+		}
+
+		if !slices.ContainsFunc(list.SourceExprs, func(expr SourceExpr) bool {
+			return expr.HashSource != "" && strings.EqualFold(expr.HashSource, "'"+algorithm+"-"+digest+"'")
+		}) {
+			return false
+		}
+
+		matched++
+	}
+
+	return matched > 0
+}
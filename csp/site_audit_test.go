@@ -0,0 +1,150 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditFS(t *testing.T) {
+	assert := assert.New(t)
+
+	site := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<!DOCTYPE html>
+<html><head>
+<link rel="stylesheet" href="/css/site.css">
+<script src="https://cdn.example.net/app.js"></script>
+<script>console.log("hi")</script>
+</head><body>
+<img src="logo.png"><img src="https://images.example.net/a.png">
+<a href="#top" onclick="go()">Top</a>
+</body></html>`)},
+		"css/site.css": &fstest.MapFile{Data: []byte(`@import "https://fonts.example.net/font.css";
+@font-face { src: url("../fonts/a.woff2"); }
+body { background: url(https://images.example.net/bg.png); }`)},
+		"fonts/a.woff2": &fstest.MapFile{Data: []byte{}},
+		"robots.txt":    &fstest.MapFile{Data: []byte("User-agent: *")},
+	}
+
+	policies, _ := Parse("", "", []string{"default-src 'self'; img-src 'self' https://images.example.net"})
+
+	audit, err := AuditFS(policies[0], site, "https://example.com/")
+	assert.Error(err)
+	assert.Equal(2, audit.Files)
+
+	assert.Equal([]BlockedResource{
+		{File: "css/site.css", Directive: "default-src", URL: "https://fonts.example.net/font.css"},
+		{File: "index.html", Directive: "default-src", URL: "https://cdn.example.net/app.js"},
+		{
+			File:      "index.html",
+			Directive: "default-src",
+			Inline:    "<script>",
+			Hash:      "'sha256-TMFma7PHrBUjZEUKY/MwBLuX3/HrQe2+A1FmjMS7ppA='",
+		},
+		{
+			File:      "index.html",
+			Directive: "default-src",
+			Inline:    "onclick",
+			Hash:      "'sha256-5KYv+PUboo5h+0+YAtGRPbwv5d/QxzHslP4YGnUaxRw='",
+		},
+	}, audit.Blocked)
+
+	assert.ErrorContains(err, "`index.html`: `https://cdn.example.net/app.js` would be blocked by `default-src` [CSP-1203]")
+
+	_, err = AuditFS(policies[0], site, "/relative")
+	assert.ErrorContains(err, "[CSP-0004]")
+}
+
+func TestAuditFSStaticNonces(t *testing.T) {
+	assert := assert.New(t)
+
+	site := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<script nonce="r4nd0m">go()</script>
+<style nonce="r4nd0m">p {}</style>`)},
+		"about.html": &fstest.MapFile{Data: []byte(`<script nonce="other">go()</script>`)},
+	}
+
+	policies, _ := Parse("", "", []string{"script-src 'nonce-r4nd0m'; style-src 'nonce-r4nd0m'"})
+
+	audit, err := AuditFS(policies[0], site, "https://example.com/")
+	assert.Equal([]StaticNonce{
+		{File: "index.html", Directive: "script-src", Nonce: "r4nd0m"},
+	}, audit.StaticNonces)
+
+	assert.ErrorContains(err, "[WARN] `index.html`: nonce `r4nd0m` from `script-src` appears in a static file")
+	assert.NotContains(err.Error(), "`about.html`: nonce")
+}
+
+func TestAuditFSSelf(t *testing.T) {
+	assert := assert.New(t)
+
+	site := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<script src="/app.js"></script>
+<script src="https://example.com/vendor.js"></script>`)},
+	}
+
+	policies, _ := Parse("", "", []string{"script-src 'self'"})
+
+	audit, err := AuditFS(policies[0], site, "https://example.com/")
+	assert.NoError(err)
+	assert.Empty(audit.Blocked)
+
+	audit, err = AuditFS(policies[0], site, "https://www.example.org/")
+	assert.Error(err)
+	assert.Equal([]BlockedResource{
+		{File: "index.html", Directive: "script-src", URL: "https://example.com/vendor.js"},
+	}, audit.Blocked)
+}
+
+func TestAuditFSElementMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	site := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<script src="/app.js" nonce="abc"></script>
+<script src="https://cdn.example.net/lib.js" integrity="sha384-AAAA sha256-BBBB"></script>
+<script src="https://cdn.example.net/other.js" integrity="sha256-BBBB sha512-CCCC"></script>
+<script src="https://cdn.example.net/plain.js"></script>
+<link rel="stylesheet" href="https://cdn.example.net/site.css" nonce="abc">`)},
+	}
+
+	policies, _ := Parse("", "", []string{
+		"script-src 'nonce-abc' 'strict-dynamic' 'sha384-AAAA' 'sha256-BBBB'; style-src 'nonce-abc'",
+	})
+
+	audit, err := AuditFS(policies[0], site, "https://example.com/")
+	assert.Error(err)
+	assert.Equal([]BlockedResource{
+		{File: "index.html", Directive: "script-src", URL: "https://cdn.example.net/other.js"},
+		{File: "index.html", Directive: "script-src", URL: "https://cdn.example.net/plain.js"},
+	}, audit.Blocked)
+}
+
+func TestAuditFSDataBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	site := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<script type="application/ld+json">{"@type": "Organization"}</script>
+<script type="text/x-template" src="https://cdn.example.net/template.html"></script>`)},
+	}
+
+	policies, _ := Parse("", "", []string{"script-src 'self'"})
+
+	audit, err := AuditFS(policies[0], site, "https://example.com/")
+	assert.NoError(err)
+	assert.Empty(audit.Blocked)
+}
@@ -0,0 +1,344 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+/*
+Union returns a source list that allows everything allowed by either source
+list. Expressions that are subsumed by another expression in the result (e.g.,
+`cdn.example.com` when `*.example.com` is also present) are dropped.
+
+An empty result matches nothing. It has None set when either list was `'none'`,
+or Empty set when both lists had no values.
+
+----
+
+  - other (SourceListItem): The source list to combine with this one.
+*/
+func (s SourceListItem) Union(other SourceListItem) SourceListItem {
+	combined := append(s.expressions(), other.expressions()...)
+	result := SourceListItem{}
+
+	for i := range combined {
+		subsumed := false
+
+		for j := range combined {
+			if i == j || !combined[j].Subsumes(combined[i]) {
+				continue
+			}
+
+			// When two expressions are equivalent, keep the first one.
+			if !combined[i].Subsumes(combined[j]) || j < i {
+				subsumed = true
+
+				break
+			}
+		}
+
+		if !subsumed {
+			result.SourceExprs = append(result.SourceExprs, combined[i])
+		}
+	}
+
+	if len(result.SourceExprs) == 0 {
+		result.None = s.None || other.None
+		result.Empty = !result.None && s.Empty && other.Empty
+	}
+
+	return result
+}
+
+/*
+Intersection returns a source list that allows only what is allowed by both
+source lists. The result is computed at the level of source expressions: an
+expression is kept when it is subsumed by an expression in the other list.
+Expressions that only partially overlap are not included.
+
+An empty result matches nothing. It has None set when either list was `'none'`,
+or Empty set when either list had no values.
+
+----
+
+  - other (SourceListItem): The source list to intersect with this one.
+*/
+func (s SourceListItem) Intersection(other SourceListItem) SourceListItem {
+	var candidates []SourceExpr
+
+	left := s.expressions()
+	right := other.expressions()
+
+	for i := range left {
+		if subsumedByAny(left[i], right) {
+			candidates = append(candidates, left[i])
+		}
+	}
+
+	for i := range right {
+		if subsumedByAny(right[i], left) {
+			candidates = append(candidates, right[i])
+		}
+	}
+
+	// Remove redundant expressions from the combined candidates.
+	result := SourceListItem{SourceExprs: candidates}.Union(SourceListItem{})
+
+	if len(result.SourceExprs) == 0 {
+		result.None = s.None || other.None
+		result.Empty = !result.None && (s.Empty || other.Empty)
+	}
+
+	return result
+}
+
+/*
+Subtract returns the expressions in this source list that are not subsumed by
+any expression in the other source list. This answers questions like "what did
+adding this CDN actually expand?"
+
+An empty result means that this source list allows nothing beyond the other.
+
+----
+
+  - other (SourceListItem): The source list to subtract from this one.
+*/
+func (s SourceListItem) Subtract(other SourceListItem) SourceListItem {
+	result := SourceListItem{}
+	right := other.expressions()
+
+	for _, expr := range s.expressions() {
+		if !subsumedByAny(expr, right) {
+			result.SourceExprs = append(result.SourceExprs, expr)
+		}
+	}
+
+	return result
+}
+
+/*
+HasKeyword reports whether the source list contains the keyword-source,
+ignoring case.
+
+----
+
+  - keyword (string): The keyword-source, including the single quotes (e.g.,
+    `'self'`).
+*/
+func (s SourceListItem) HasKeyword(keyword string) bool {
+	return containsKeyword(&s, keyword)
+}
+
+// containsKeyword reports whether the source list contains the keyword-source.
+func containsKeyword(list *SourceListItem, keyword string) bool {
+	for i := range list.SourceExprs {
+		if strings.EqualFold(list.SourceExprs[i].KeywordSource, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Subsumes reports whether this source expression allows everything that the
+other source expression allows. Scheme-sources, wildcard hosts, wildcard ports,
+and path prefixes are all taken into account. Keyword-sources, nonce-sources,
+and hash-sources only subsume themselves.
+
+----
+
+  - other (SourceExpr): The source expression to compare against.
+*/
+func (e SourceExpr) Subsumes(other SourceExpr) bool {
+	switch {
+	case e.KeywordSource != "" || e.NonceSource != "" || e.HashSource != "":
+		return strings.EqualFold(e.KeywordSource, other.KeywordSource) &&
+			e.NonceSource == other.NonceSource &&
+			e.HashSource == other.HashSource
+	case e.HostSource == "*":
+		switch {
+		case other.SchemeSource != "":
+			return hostsource.IsHTTPScheme(strings.TrimSuffix(other.SchemeSource, ":"))
+		case other.HostSource != "":
+			scheme := hostSourceScheme(other.HostSource)
+
+			return scheme == "" || hostsource.IsHTTPScheme(scheme)
+		}
+
+		return false
+	case e.SchemeSource != "":
+		scheme := strings.TrimSuffix(e.SchemeSource, ":")
+
+		if other.SchemeSource != "" {
+			return hostsource.SchemeMatches(scheme, strings.TrimSuffix(other.SchemeSource, ":"))
+		}
+
+		if other.HostSource == "" || other.HostSource == "*" {
+			return false
+		}
+
+		otherScheme := hostSourceScheme(other.HostSource)
+
+		// A host-source without a scheme inherits the scheme of the protected
+		// document, which is HTTP or HTTPS.
+		if otherScheme == "" {
+			return strings.EqualFold(scheme, "http")
+		}
+
+		return hostsource.SchemeMatches(scheme, otherScheme)
+	case e.HostSource != "":
+		return other.HostSource != "" && other.HostSource != "*" && hostSourceSubsumes(e.HostSource, other.HostSource)
+	}
+
+	return false
+}
+
+/*
+hostSourceSubsumes reports whether host-source a allows everything that
+host-source b allows.
+
+----
+
+  - a (string): The host-source that may subsume the other.
+
+  - b (string): The host-source that may be subsumed.
+*/
+func hostSourceSubsumes(a, b string) bool {
+	aScheme, aHost, aPort, aPath := hostsource.Split(a)
+	bScheme, bHost, bPort, bPath := hostsource.Split(b)
+
+	switch {
+	case aScheme == "" && bScheme == "":
+	case aScheme == "":
+		// A scheme-less expression allows HTTPS regardless of the scheme of the
+		// protected document.
+		if !strings.EqualFold(bScheme, "https") {
+			return false
+		}
+	case bScheme == "":
+		if !strings.EqualFold(aScheme, "http") {
+			return false
+		}
+	default:
+		if !hostsource.SchemeMatches(aScheme, bScheme) {
+			return false
+		}
+	}
+
+	if !hostsource.HostMatches(aHost, bHost) {
+		return false
+	}
+
+	if !portSubsumes(aPort, bPort, bScheme) {
+		return false
+	}
+
+	switch {
+	case aPath == "" || aPath == "/":
+		return true
+	case strings.HasSuffix(aPath, "/"):
+		return strings.HasPrefix(bPath, aPath)
+	}
+
+	return aPath == bPath
+}
+
+/*
+portSubsumes reports whether port-part a allows every port that port-part b
+allows. An empty port-part means the default port of the scheme of the URL being
+loaded, so `https://a.example` and `https://a.example:443` allow the same ports,
+but `http://a.example` (which also matches HTTPS on port 443) and
+`http://a.example:80` do not.
+
+----
+
+  - a (string): The port-part that may subsume the other.
+
+  - b (string): The port-part that may be subsumed.
+
+  - scheme (string): The scheme-part of the host-source that b belongs to.
+*/
+func portSubsumes(a, b, scheme string) bool {
+	switch {
+	case a == "*" || a == b:
+		return true
+	case b == "*" || (a != "" && b != ""):
+		return false
+	}
+
+	// A host-source without a scheme inherits the scheme of the protected
+	// document, which is HTTP or HTTPS.
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	// Every scheme that b matches must have the explicit port as its default.
+	explicit := a + b
+
+	for _, candidate := range []string{"http", "https", "ws", "wss", strings.ToLower(scheme)} {
+		if hostsource.SchemeMatches(scheme, candidate) &&
+			strconv.Itoa(hostsource.DefaultPort(candidate)) != explicit {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the source expression as it would appear in a policy.
+func (e SourceExpr) String() string {
+	switch {
+	case e.SchemeSource != "":
+		return e.SchemeSource
+	case e.HostSource != "":
+		return e.HostSource
+	case e.KeywordSource != "":
+		return e.KeywordSource
+	case e.NonceSource != "":
+		return e.NonceSource
+	}
+
+	return e.HashSource
+}
+
+// hostSourceScheme returns the scheme-part of a host-source, without the
+// trailing `://`.
+func hostSourceScheme(s string) string {
+	scheme, _, _, _ := hostsource.Split(s)
+
+	return scheme
+}
+
+// subsumedByAny reports whether any of the expressions subsumes expr.
+func subsumedByAny(expr SourceExpr, exprs []SourceExpr) bool {
+	for i := range exprs {
+		if exprs[i].Subsumes(expr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expressions returns a copy of the source expressions in the list.
+func (s SourceListItem) expressions() []SourceExpr {
+	return slices.Clone(s.SourceExprs)
+}
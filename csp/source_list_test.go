@@ -0,0 +1,163 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestSourceExprSubsumes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		A        SourceExpr
+		B        SourceExpr
+		Expected bool
+	}{
+		"identical host": {
+			A:        SourceExpr{HostSource: "cdn.example.com"},
+			B:        SourceExpr{HostSource: "cdn.example.com"},
+			Expected: true,
+		},
+		"wildcard host": {
+			A:        SourceExpr{HostSource: "*.example.com"},
+			B:        SourceExpr{HostSource: "https://cdn.example.com"},
+			Expected: true,
+		},
+		"wildcard host does not cover apex": {
+			A:        SourceExpr{HostSource: "*.example.com"},
+			B:        SourceExpr{HostSource: "example.com"},
+			Expected: false,
+		},
+		"https: covers https host": {
+			A:        SourceExpr{SchemeSource: "https:"},
+			B:        SourceExpr{HostSource: "https://cdn.example.com"},
+			Expected: true,
+		},
+		"https: does not cover scheme-less host": {
+			A:        SourceExpr{SchemeSource: "https:"},
+			B:        SourceExpr{HostSource: "cdn.example.com"},
+			Expected: false,
+		},
+		"http: covers https:": {
+			A:        SourceExpr{SchemeSource: "http:"},
+			B:        SourceExpr{SchemeSource: "https:"},
+			Expected: true,
+		},
+		"* covers host": {
+			A:        SourceExpr{HostSource: "*"},
+			B:        SourceExpr{HostSource: "cdn.example.com"},
+			Expected: true,
+		},
+		"* does not cover data:": {
+			A:        SourceExpr{HostSource: "*"},
+			B:        SourceExpr{SchemeSource: "data:"},
+			Expected: false,
+		},
+		"path prefix": {
+			A:        SourceExpr{HostSource: "cdn.example.com/js/"},
+			B:        SourceExpr{HostSource: "cdn.example.com/js/app.js"},
+			Expected: true,
+		},
+		"narrower path": {
+			A:        SourceExpr{HostSource: "cdn.example.com/js/app.js"},
+			B:        SourceExpr{HostSource: "cdn.example.com"},
+			Expected: false,
+		},
+		"wildcard port": {
+			A:        SourceExpr{HostSource: "cdn.example.com:*"},
+			B:        SourceExpr{HostSource: "cdn.example.com:8443"},
+			Expected: true,
+		},
+		"default port": {
+			A:        SourceExpr{HostSource: "https://cdn.example.com"},
+			B:        SourceExpr{HostSource: "https://cdn.example.com:443"},
+			Expected: true,
+		},
+		"explicit default port": {
+			A:        SourceExpr{HostSource: "wss://cdn.example.com:443"},
+			B:        SourceExpr{HostSource: "wss://cdn.example.com"},
+			Expected: true,
+		},
+		"default port of an upgraded scheme": {
+			A:        SourceExpr{HostSource: "http://cdn.example.com"},
+			B:        SourceExpr{HostSource: "http://cdn.example.com:80"},
+			Expected: false,
+		},
+		"other port": {
+			A:        SourceExpr{HostSource: "https://cdn.example.com"},
+			B:        SourceExpr{HostSource: "https://cdn.example.com:8443"},
+			Expected: false,
+		},
+		"keyword": {
+			A:        SourceExpr{KeywordSource: "'self'"},
+			B:        SourceExpr{KeywordSource: "'SELF'"},
+			Expected: true,
+		},
+		"keyword does not cover host": {
+			A:        SourceExpr{KeywordSource: "'self'"},
+			B:        SourceExpr{HostSource: "cdn.example.com"},
+			Expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			actual := tc.A.Subsumes(tc.B)
+
+			assert.Equalf(tc.Expected, actual, "Expected `%v`, but got `%v`.", tc.Expected, actual)
+		})
+	}
+}
+
+func TestSourceListAlgebra(t *testing.T) {
+	assert := assert.New(t)
+
+	before := SourceListItem{SourceExprs: []SourceExpr{
+		{KeywordSource: "'self'"},
+		{HostSource: "cdn.example.com"},
+	}}
+	after := SourceListItem{SourceExprs: []SourceExpr{
+		{KeywordSource: "'self'"},
+		{HostSource: "*.example.com"},
+		{HostSource: "cdn.example.net"},
+	}}
+
+	assert.Equal(
+		[]SourceExpr{{KeywordSource: "'self'"}, {HostSource: "*.example.com"}, {HostSource: "cdn.example.net"}},
+		before.Union(after).SourceExprs,
+	)
+	assert.Equal(
+		[]SourceExpr{{KeywordSource: "'self'"}, {HostSource: "cdn.example.com"}},
+		before.Intersection(after).SourceExprs,
+	)
+	assert.Equal(
+		[]SourceExpr{{HostSource: "*.example.com"}, {HostSource: "cdn.example.net"}},
+		after.Subtract(before).SourceExprs,
+	)
+	assert.Empty(before.Subtract(after).SourceExprs)
+
+	// A result that matches nothing keeps the reason why.
+	none := SourceListItem{None: true}
+	empty := SourceListItem{Empty: true}
+
+	assert.Equal(SourceListItem{None: true}, none.Union(none))
+	assert.Equal(SourceListItem{None: true}, none.Union(empty))
+	assert.Equal(SourceListItem{Empty: true}, empty.Union(empty))
+	assert.Equal(before.SourceExprs, none.Union(before).SourceExprs)
+	assert.False(none.Union(before).None)
+	assert.Equal(SourceListItem{None: true}, before.Intersection(none))
+	assert.Equal(SourceListItem{Empty: true}, empty.Intersection(before))
+}
@@ -0,0 +1,185 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type (
+	// Stats describes the work done by a call to Parse. It helps to find slow or
+	// pathological inputs.
+	Stats struct {
+		// Duration is the wall-clock time spent in Parse.
+		Duration time.Duration `json:"duration"`
+
+		// Directives is the number of directives, across all policies.
+		Directives int `json:"directives"`
+
+		// Tokens is the number of directive values, across all policies.
+		Tokens int `json:"tokens"`
+
+		// Diagnostics is the number of diagnostics returned.
+		Diagnostics int `json:"diagnostics"`
+
+		// Policies has the statistics for each policy.
+		Policies []PolicyStats `json:"policies,omitempty"`
+	}
+
+	// PolicyStats describes the work done to parse a single policy.
+	PolicyStats struct {
+		Index      int           `json:"index"`
+		Length     int           `json:"length"`
+		Directives int           `json:"directives"`
+		Tokens     int           `json:"tokens"`
+		Duration   time.Duration `json:"duration"`
+	}
+)
+
+// WithStats collects statistics about a call to Parse into s. The contents of s
+// are replaced.
+func WithStats(s *Stats) Option {
+	return func(c *config) {
+		c.stats = s
+	}
+}
+
+// startStats resets the statistics at the beginning of a call to Parse.
+func (c *config) startStats() {
+	c.started = time.Now()
+
+	if c.stats != nil {
+		*c.stats = Stats{}
+	}
+}
+
+// startPolicyStats begins the statistics for the current policy.
+func (c *config) startPolicyStats(length int) {
+	if c.stats == nil {
+		return
+	}
+
+	c.stats.Policies = append(c.stats.Policies, PolicyStats{
+		Index:  c.policyIndex,
+		Length: length,
+	})
+}
+
+// countDirective adds a directive and its values to the statistics for the
+// current policy.
+func (c *config) countDirective(values []string) {
+	if c.stats == nil || len(c.stats.Policies) == 0 {
+		return
+	}
+
+	p := &c.stats.Policies[len(c.stats.Policies)-1]
+	p.Directives++
+	p.Tokens += len(values)
+
+	c.stats.Directives++
+	c.stats.Tokens += len(values)
+}
+
+// endPolicyStats records how long the current policy took to parse.
+func (c *config) endPolicyStats(started time.Time) {
+	if c.stats == nil || len(c.stats.Policies) == 0 {
+		return
+	}
+
+	c.stats.Policies[len(c.stats.Policies)-1].Duration = time.Since(started)
+}
+
+// endStats completes the statistics at the end of a call to Parse.
+func (c *config) endStats(diagnostics int) {
+	if c.stats == nil {
+		return
+	}
+
+	c.stats.Duration = time.Since(c.started)
+	c.stats.Diagnostics = diagnostics
+}
+
+/*
+WritePrometheus writes the statistics in the Prometheus text exposition format,
+for a scraper or the node_exporter textfile collector. Each metric is a gauge
+describing the last call to Parse. The `csp_parser_policy_*` metrics have a
+`policy` label with the index of the policy.
+
+https://prometheus.io/docs/instrumenting/exposition_formats/
+
+----
+
+  - w (io.Writer): The writer to write the metrics to.
+*/
+func (s *Stats) WritePrometheus(w io.Writer) error {
+	totals := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"csp_parser_duration_seconds", "Wall-clock time spent parsing.", s.Duration.Seconds()},
+		{"csp_parser_policies", "Number of policies.", float64(len(s.Policies))},
+		{"csp_parser_directives", "Number of directives, across all policies.", float64(s.Directives)},
+		{"csp_parser_tokens", "Number of directive values, across all policies.", float64(s.Tokens)},
+		{"csp_parser_diagnostics", "Number of diagnostics returned.", float64(s.Diagnostics)},
+	}
+
+	for _, m := range totals {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n",
+			m.name, m.help, m.name, m.name, formatMetric(m.value))
+		if err != nil {
+			return err
+		}
+	}
+
+	perPolicy := []struct {
+		name  string
+		help  string
+		value func(p *PolicyStats) float64
+	}{
+		{"csp_parser_policy_duration_seconds", "Wall-clock time spent parsing the policy.",
+			func(p *PolicyStats) float64 { return p.Duration.Seconds() }},
+		{"csp_parser_policy_length_bytes", "Length of the serialized policy.",
+			func(p *PolicyStats) float64 { return float64(p.Length) }},
+		{"csp_parser_policy_directives", "Number of directives in the policy.",
+			func(p *PolicyStats) float64 { return float64(p.Directives) }},
+		{"csp_parser_policy_tokens", "Number of directive values in the policy.",
+			func(p *PolicyStats) float64 { return float64(p.Tokens) }},
+	}
+
+	for _, m := range perPolicy {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name); err != nil {
+			return err
+		}
+
+		for i := range s.Policies {
+			_, err := fmt.Fprintf(w, "%s{policy=\"%d\"} %s\n", m.name, s.Policies[i].Index,
+				formatMetric(m.value(&s.Policies[i])))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatMetric formats a sample value as Prometheus expects it.
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
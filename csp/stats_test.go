@@ -0,0 +1,74 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsWritePrometheus(t *testing.T) {
+	assert := assert.New(t)
+
+	stats := Stats{
+		Duration:    1500 * time.Microsecond,
+		Directives:  3,
+		Tokens:      4,
+		Diagnostics: 2,
+		Policies: []PolicyStats{
+			{Index: 0, Length: 40, Directives: 2, Tokens: 3, Duration: time.Millisecond},
+			{Index: 1, Length: 17, Directives: 1, Tokens: 1, Duration: 250 * time.Microsecond},
+		},
+	}
+
+	var b strings.Builder
+
+	assert.NoError(stats.WritePrometheus(&b))
+	assert.Equal(`# HELP csp_parser_duration_seconds Wall-clock time spent parsing.
+# TYPE csp_parser_duration_seconds gauge
+csp_parser_duration_seconds 0.0015
+# HELP csp_parser_policies Number of policies.
+# TYPE csp_parser_policies gauge
+csp_parser_policies 2
+# HELP csp_parser_directives Number of directives, across all policies.
+# TYPE csp_parser_directives gauge
+csp_parser_directives 3
+# HELP csp_parser_tokens Number of directive values, across all policies.
+# TYPE csp_parser_tokens gauge
+csp_parser_tokens 4
+# HELP csp_parser_diagnostics Number of diagnostics returned.
+# TYPE csp_parser_diagnostics gauge
+csp_parser_diagnostics 2
+# HELP csp_parser_policy_duration_seconds Wall-clock time spent parsing the policy.
+# TYPE csp_parser_policy_duration_seconds gauge
+csp_parser_policy_duration_seconds{policy="0"} 0.001
+csp_parser_policy_duration_seconds{policy="1"} 0.00025
+# HELP csp_parser_policy_length_bytes Length of the serialized policy.
+# TYPE csp_parser_policy_length_bytes gauge
+csp_parser_policy_length_bytes{policy="0"} 40
+csp_parser_policy_length_bytes{policy="1"} 17
+# HELP csp_parser_policy_directives Number of directives in the policy.
+# TYPE csp_parser_policy_directives gauge
+csp_parser_policy_directives{policy="0"} 2
+csp_parser_policy_directives{policy="1"} 1
+# HELP csp_parser_policy_tokens Number of directive values in the policy.
+# TYPE csp_parser_policy_tokens gauge
+csp_parser_policy_tokens{policy="0"} 3
+csp_parser_policy_tokens{policy="1"} 1
+`, b.String())
+}
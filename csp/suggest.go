@@ -0,0 +1,145 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import "strings"
+
+const (
+	// maxDirectiveTypoDistance is the largest edit distance between an unknown
+	// directive name and a known one that is still suggested.
+	maxDirectiveTypoDistance = 2
+
+	// maxKeywordTypoDistance is the largest edit distance between a quoted value
+	// and a keyword-source that is still suggested.
+	maxKeywordTypoDistance = 2
+)
+
+// keywordQuotes are the characters that are mistaken for the single quotes
+// around a keyword-source, including the curly quotes that word processors
+// substitute.
+const keywordQuotes = "'\"`\u2018\u2019\u201c\u201d"
+
+// ancestorKeywords are the keyword-sources that are valid in `frame-ancestors`.
+var ancestorKeywords = []string{`'self'`, `'none'`}
+
+// suggestDirective returns the known directive that an unknown directive name
+// most likely meant (e.g., `script-src` for `scritp-src`).
+func suggestDirective(name string) (string, bool) {
+	return closest(name, knownDirectives, maxDirectiveTypoDistance)
+}
+
+// suggestSandboxToken returns the sandbox token that an unknown one most likely
+// meant (e.g., `allow-scripts` for `allow-script`).
+func suggestSandboxToken(token string) (string, bool) {
+	return closest(token, sandboxTokens, maxSandboxTypoDistance)
+}
+
+// keywordCandidates returns the keyword-sources that may be suggested in a
+// source list: `'none'`, the known keywords, and the registered ones.
+func keywordCandidates() []string {
+	candidates := append([]string{`'none'`}, keywordSources...)
+
+	registeredKeywordsMu.RLock()
+	defer registeredKeywordsMu.RUnlock()
+
+	for keyword := range registeredKeywords {
+		candidates = append(candidates, keyword)
+	}
+
+	return candidates
+}
+
+/*
+suggestKeyword returns the keyword-source that a value most likely meant: the
+same name without quotes, or with the wrong quotes (e.g., `self`, `"self"`), or
+a quoted keyword with a typo (e.g., `'unsafe-inlin'`). Unquoted values are only
+matched exactly, since anything else is a plausible host name.
+
+----
+
+  - value (string): The value that was not recognized as a keyword-source.
+
+  - candidates ([]string): The keyword-sources that are valid here, including
+    their single quotes.
+*/
+func suggestKeyword(value string, candidates []string) (string, bool) {
+	name := strings.Trim(value, keywordQuotes)
+
+	for _, candidate := range candidates {
+		if strings.EqualFold(name, strings.Trim(candidate, "'")) {
+			return candidate, true
+		}
+	}
+
+	if name == value {
+		return "", false
+	}
+
+	return closest("'"+name+"'", candidates, maxKeywordTypoDistance)
+}
+
+/*
+closest returns the candidate with the smallest edit distance to s, compared
+case-insensitively, for "did you mean" suggestions. ok is false when no
+candidate is within maxDistance edits.
+
+----
+
+  - s (string): The value that was not recognized.
+
+  - candidates ([]string): The recognized values.
+
+  - maxDistance (int): The largest edit distance that is still a plausible typo.
+*/
+func closest(s string, candidates []string, maxDistance int) (suggestion string, ok bool) {
+	best := maxDistance + 1
+	s = strings.ToLower(s)
+
+	for _, candidate := range candidates {
+		if d := editDistance(s, strings.ToLower(candidate)); d < best {
+			best, suggestion = d, candidate
+		}
+	}
+
+	return suggestion, best <= maxDistance
+}
+
+// editDistance returns the Levenshtein distance between a and b: the number of
+// single-byte insertions, deletions, and substitutions that turn a into b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
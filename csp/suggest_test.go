@@ -0,0 +1,131 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	for name, tc := range map[string]struct {
+		A, B     string
+		Distance int
+	}{
+		"equal":        {A: "allow-forms", B: "allow-forms"},
+		"empty":        {A: "", B: "abc", Distance: 3},
+		"substitution": {A: "allow-forma", B: "allow-forms", Distance: 1},
+		"insertion":    {A: "allow-form", B: "allow-forms", Distance: 1},
+		"deletion":     {A: "allow-formss", B: "allow-forms", Distance: 1},
+		"transposed":   {A: "allow-scirpts", B: "allow-scripts", Distance: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Distance, editDistance(tc.A, tc.B))
+			assert.Equal(t, tc.Distance, editDistance(tc.B, tc.A))
+		})
+	}
+}
+
+func TestClosest(t *testing.T) {
+	assert := assert.New(t)
+
+	suggestion, ok := closest("ALLOW-POPUP", sandboxTokens, maxSandboxTypoDistance)
+	assert.True(ok)
+	assert.Equal("allow-popups", suggestion)
+
+	_, ok = closest("allow-malware", sandboxTokens, maxSandboxTypoDistance)
+	assert.False(ok)
+}
+
+func TestSuggestKeyword(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Value      string
+		Suggestion string
+	}{
+		"unquoted":         {Value: "self", Suggestion: "'self'"},
+		"double quotes":    {Value: `"unsafe-inline"`, Suggestion: "'unsafe-inline'"},
+		"curly quotes":     {Value: "\u2018self\u2019", Suggestion: "'self'"},
+		"typo":             {Value: "'unsafe-inlin'", Suggestion: "'unsafe-inline'"},
+		"case":             {Value: "NONE", Suggestion: "'none'"},
+		"unquoted typo":    {Value: "slef"},
+		"host name":        {Value: "example.com"},
+		"unrelated quoted": {Value: "'something-else'"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			suggestion, ok := suggestKeyword(tc.Value, keywordCandidates())
+
+			assert.Equal(tc.Suggestion, suggestion)
+			assert.Equal(tc.Suggestion != "", ok)
+		})
+	}
+}
+
+func TestSuggestDirective(t *testing.T) {
+	assert := assert.New(t)
+
+	suggestion, ok := suggestDirective("scritp-src")
+	assert.True(ok)
+	assert.Equal("script-src", suggestion)
+
+	suggestion, ok = suggestDirective("IMG-SRC-")
+	assert.True(ok)
+	assert.Equal("img-src", suggestion)
+
+	_, ok = suggestDirective("bogus")
+	assert.False(ok)
+}
+
+func TestParseSuggestions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CSP         string
+		ErrorSubstr string
+	}{
+		"directive typo": {
+			CSP:         "scritp-src 'self'",
+			ErrorSubstr: "unknown directive `scritp-src`; did you mean `script-src`? [CSP-0916]",
+		},
+		"keyword typo": {
+			CSP:         "script-src 'unsafe-inlin'",
+			ErrorSubstr: "has an invalid value `'unsafe-inlin'`; did you mean `'unsafe-inline'`? [CSP-0123]",
+		},
+		"keyword in double quotes": {
+			CSP:         `script-src "self"`,
+			ErrorSubstr: "has an invalid value `\"self\"`; did you mean `'self'`? [CSP-0123]",
+		},
+		"unquoted keyword": {
+			CSP:         "script-src self",
+			ErrorSubstr: "has `self`, which browsers read as a host-source",
+		},
+		"frame-ancestors typo": {
+			CSP:         "frame-ancestors 'slef'",
+			ErrorSubstr: "did you mean `'self'`? [CSP-0204]",
+		},
+		"sandbox typo": {
+			CSP:         "sandbox allow-script",
+			ErrorSubstr: "did you mean `allow-scripts`? [CSP-0702]",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse("https://example.com", "", []string{tc.CSP}, WithoutReportingValidation())
+
+			assert.ErrorContains(err, tc.ErrorSubstr)
+		})
+	}
+}
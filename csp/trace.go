@@ -0,0 +1,131 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import "github.com/hashicorp/go-multierror"
+
+// TraceKind identifies the kind of parsing decision that a TraceEvent describes.
+type TraceKind string
+
+const (
+	// TraceDirective is raised when a directive is recognized (or not).
+	TraceDirective TraceKind = "directive"
+
+	// TraceToken is raised when a directive value is classified.
+	TraceToken TraceKind = "token"
+
+	// TraceDiagnostic is raised when an error, warning, or note is produced.
+	TraceDiagnostic TraceKind = "diagnostic"
+)
+
+// Token classifications reported in TraceEvent.Classification.
+const (
+	ClassNone              = "none"
+	ClassSchemeSource      = "scheme-source"
+	ClassHostSource        = "host-source"
+	ClassKeywordSource     = "keyword-source"
+	ClassNonceSource       = "nonce-source"
+	ClassHashSource        = "hash-source"
+	ClassMediaType         = "media-type"
+	ClassURL               = "url"
+	ClassReportingEndpoint = "reporting-endpoint"
+	ClassSandboxToken      = "sandbox-token"
+	ClassWebRTCValue       = "webrtc-value"
+	ClassSinkGroup         = "sink-group"
+	ClassSRIResourceType   = "sri-resource-type"
+	ClassReferrerToken     = "referrer-token"
+	ClassDuplicate         = "duplicate"
+	ClassInvalid           = "invalid"
+)
+
+/*
+TraceEvent describes a single parsing decision. Tool builders (IDE plugins, the
+TUI) can register a callback with WithTrace to build live, incremental views on
+top of the parser.
+
+  - For TraceDirective events, Directive and Values are set, and Recognized
+    reports whether the directive name is known.
+
+  - For TraceToken events, Directive, Token, and Classification are set.
+
+  - For TraceDiagnostic events, Err is set. PolicyIndex is -1 for diagnostics
+    that do not belong to a specific policy.
+*/
+type TraceEvent struct {
+	Kind           TraceKind
+	PolicyIndex    int
+	Directive      string
+	Values         []string
+	Recognized     bool
+	Token          string
+	Classification string
+	Err            error
+}
+
+// traceDirective reports that a directive was encountered.
+func (c *config) traceDirective(name string, values []string, recognized bool) {
+	if c == nil || c.trace == nil {
+		return
+	}
+
+	c.trace(TraceEvent{
+		Kind:        TraceDirective,
+		PolicyIndex: c.policyIndex,
+		Directive:   name,
+		Values:      values,
+		Recognized:  recognized,
+	})
+}
+
+// traceToken reports how a directive value was classified.
+func (c *config) traceToken(directive, token, classification string) {
+	if c == nil || c.trace == nil {
+		return
+	}
+
+	c.trace(TraceEvent{
+		Kind:           TraceToken,
+		PolicyIndex:    c.policyIndex,
+		Directive:      directive,
+		Token:          token,
+		Classification: classification,
+	})
+}
+
+// traceDiagnostics reports each of the errors.
+func (c *config) traceDiagnostics(errs []error) {
+	if c == nil || c.trace == nil {
+		return
+	}
+
+	errs = c.filterSeverity(errs)
+
+	for i := range errs {
+		c.trace(TraceEvent{
+			Kind:        TraceDiagnostic,
+			PolicyIndex: c.policyIndex,
+			Err:         errs[i],
+		})
+	}
+}
+
+// errorsOf returns the errors collected in a multierror, which may be nil.
+func errorsOf(errs *multierror.Error) []error {
+	if errs == nil {
+		return nil
+	}
+
+	return errs.Errors
+}
@@ -0,0 +1,79 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// minTruncatedLength is the length, in bytes, from which a header that ends
+// with a directive name alone looks truncated rather than unfinished. Web
+// servers, CDNs, and proxies commonly limit header values to a few kilobytes.
+const minTruncatedLength = 1024
+
+// valuelessDirectives are the known directives that are complete without a
+// value.
+var valuelessDirectives = []string{
+	"block-all-mixed-content",
+	"sandbox",
+	"upgrade-insecure-requests",
+}
+
+/*
+truncatedTail reports whether a policy appears to have been cut off (e.g., by a
+length limit on the header), and returns its last token. The signs are:
+
+  - The last token opens a quote without closing it (e.g., `'unsafe-inl`).
+
+  - The last directive is an unknown name that begins a known name (e.g.,
+    `frame-anc`).
+
+  - A long header ends with the name of a directive that requires a value.
+
+A policy that ends with `;` or whitespace never looks truncated.
+
+----
+
+  - policy (string): A single serialized policy.
+
+  - end (int): The byte offset of the end of the policy within its header
+    value.
+*/
+func truncatedTail(policy string, end int) (string, bool) {
+	if policy == "" || strings.HasSuffix(policy, ";") || unicode.IsSpace(rune(policy[len(policy)-1])) {
+		return "", false
+	}
+
+	fields := strings.Fields(policy[strings.LastIndex(policy, ";")+1:])
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	token := fields[len(fields)-1]
+	name := strings.ToLower(fields[0])
+
+	switch {
+	case strings.HasPrefix(token, "'") && (len(token) == 1 || !strings.HasSuffix(token, "'")):
+		return token, true
+	case len(fields) > 1:
+		return "", false
+	case !slices.Contains(knownDirectives, name):
+		return token, slices.ContainsFunc(knownDirectives, func(d string) bool { return strings.HasPrefix(d, name) })
+	}
+
+	return token, end >= minTruncatedLength && !slices.Contains(valuelessDirectives, name)
+}
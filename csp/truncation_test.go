@@ -0,0 +1,79 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncatedTail(t *testing.T) {
+	long := "default-src 'self'; script-src " + strings.Repeat("https://cdn.example.com ", 50)
+
+	for name, tc := range map[string]struct {
+		Policy    string
+		Token     string
+		Truncated bool
+	}{
+		"complete":                {Policy: "default-src 'self'; script-src 'self'"},
+		"trailing semicolon":      {Policy: "default-src 'self'; script-src 'unsafe-inl;"},
+		"trailing space":          {Policy: "default-src 'self'; frame-anc "},
+		"unbalanced quote":        {Policy: "default-src 'self'; script-src 'unsafe-inl", Token: "'unsafe-inl", Truncated: true},
+		"lone quote":              {Policy: "default-src 'self' '", Token: "'", Truncated: true},
+		"partial directive name":  {Policy: "default-src 'self'; frame-anc", Token: "frame-anc", Truncated: true},
+		"unknown directive name":  {Policy: "default-src 'self'; bogus"},
+		"short, name only":        {Policy: "default-src 'self'; img-src"},
+		"long, name only":         {Policy: long + "; img-src", Token: "img-src", Truncated: true},
+		"long, valueless name":    {Policy: long + "; upgrade-insecure-requests"},
+		"long, complete":          {Policy: long + "; img-src 'self'"},
+		"partial host is unknown": {Policy: long + "; img-src https://cdn.exa"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			token, truncated := truncatedTail(tc.Policy, len(tc.Policy))
+			assert.Equal(tc.Truncated, truncated)
+
+			if tc.Truncated {
+				assert.Equal(tc.Token, token)
+			}
+		})
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	policies, err := Parse("https://example.com", "", []string{"default-src 'self'; script-src 'self' 'unsafe-inl"})
+	assert.ErrorContains(err, "[ERROR] policy appears to be truncated after `'unsafe-inl`, and the rest of it is "+
+		"missing")
+	assert.NotContains(err.Error(), "[CSP-0100]")
+	assert.Equal([]SourceExpr{{KeywordSource: "'self'"}}, policies[0].ScriptSource[0].SourceExprs)
+
+	var pe *PolicyError
+
+	merr, _ := err.(*multierror.Error)
+	assert.ErrorAs(merr.Errors[1], &pe)
+	assert.Equal("/0/directives/1/values/1", pe.Pointer)
+
+	// Only the last directive is affected.
+	_, err = Parse("https://example.com", "", []string{"script-src 'unsafe-inl; frame-anc"})
+	assert.ErrorContains(err, "truncated after `frame-anc`")
+	assert.ErrorContains(err, "directive `script-src` has an invalid value `'unsafe-inl` [CSP-0100]")
+	assert.NotContains(err.Error(), "[CSP-0901]")
+}
@@ -0,0 +1,153 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/northwood-labs/csp-parser/internal/hostsource"
+)
+
+type (
+	// vendor describes the sources that a third-party service needs. When the
+	// trigger is allowed, every requirement should be allowed as well.
+	vendor struct {
+		name         string
+		trigger      vendorSource
+		requirements []vendorSource
+	}
+
+	// vendorSource is a host-source that a vendor needs in a directive.
+	vendorSource struct {
+		directive string
+		source    string
+	}
+)
+
+// knownVendors is the knowledge base of third-party services, and the sources
+// that they document as required.
+var knownVendors = []vendor{
+	{
+		// https://developers.google.com/tag-platform/security/guides/csp
+		name:    "Google Analytics",
+		trigger: vendorSource{"script-src-elem", "https://www.googletagmanager.com"},
+		requirements: []vendorSource{
+			{"img-src", "https://www.googletagmanager.com"},
+			{"connect-src", "https://www.google-analytics.com"},
+			{"connect-src", "https://region1.google-analytics.com"},
+		},
+	},
+	{
+		// https://docs.stripe.com/security/guide#content-security-policy
+		name:    "Stripe.js",
+		trigger: vendorSource{"script-src-elem", "https://js.stripe.com"},
+		requirements: []vendorSource{
+			{"frame-src", "https://js.stripe.com"},
+			{"frame-src", "https://hooks.stripe.com"},
+			{"connect-src", "https://api.stripe.com"},
+		},
+	},
+	{
+		// https://developers.google.com/fonts/docs/getting_started
+		name:    "Google Fonts",
+		trigger: vendorSource{"style-src-elem", "https://fonts.googleapis.com"},
+		requirements: []vendorSource{
+			{"font-src", "https://fonts.gstatic.com"},
+		},
+	},
+}
+
+/*
+CheckVendors looks for third-party services that are only partially allowed by
+the policy (e.g., `www.googletagmanager.com` is allowed in `script-src`, but
+`www.google-analytics.com` is missing from `connect-src`), and returns an error
+suggesting each missing companion source.
+
+----
+
+  - policy (*Policy): The parsed policy to evaluate.
+*/
+func CheckVendors(policy *Policy) error {
+	var errs *multierror.Error
+
+	for i := range knownVendors {
+		v := &knownVendors[i]
+
+		effective, found := policy.allowsVendorSource(v.trigger, true)
+		if !found {
+			continue
+		}
+
+		for j := range v.requirements {
+			if _, ok := policy.allowsVendorSource(v.requirements[j], false); ok {
+				continue
+			}
+
+			errs = multierror.Append(errs, fmt.Errorf(
+				errCSP1301,
+				v.name,
+				effective,
+				v.trigger.source,
+				v.requirements[j].directive,
+				v.requirements[j].source,
+			))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+/*
+allowsVendorSource reports whether the policy allows the vendor source, and
+returns the name of the directive (after fallback) that made the decision.
+
+----
+
+  - source (vendorSource): The vendor source to look for.
+
+  - explicit (bool): If true, the host must be named in the policy. Wildcards
+    and scheme-sources that happen to allow it do not count. This is used to
+    detect which vendors the policy was written for.
+*/
+func (p *Policy) allowsVendorSource(source vendorSource, explicit bool) (string, bool) {
+	effective, list, ok := p.effectiveSourceList(source.directive)
+	if !ok {
+		return "", !explicit
+	}
+
+	expr := SourceExpr{HostSource: source.source}
+
+	if !explicit {
+		return effective, subsumedByAny(expr, list.expressions())
+	}
+
+	_, host, _, _ := hostsource.Split(source.source)
+
+	for i := range list.SourceExprs {
+		if list.SourceExprs[i].HostSource == "" {
+			continue
+		}
+
+		_, h, _, _ := hostsource.Split(list.SourceExprs[i].HostSource)
+
+		if strings.EqualFold(h, host) && list.SourceExprs[i].Subsumes(expr) {
+			return effective, true
+		}
+	}
+
+	return effective, false
+}
@@ -0,0 +1,69 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestCheckVendors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		CSP         string
+		ErrorSubstr []string
+	}{
+		"no vendors": {
+			CSP: "default-src 'self'",
+		},
+		"wildcard does not count as a vendor": {
+			CSP: "default-src 'self'; script-src https:",
+		},
+		"google analytics, partially configured": {
+			CSP: "default-src 'self'; script-src 'self' https://www.googletagmanager.com",
+			ErrorSubstr: []string{
+				"Google Analytics appears to be partially configured: `script-src` allows " +
+					"`https://www.googletagmanager.com`, but `connect-src` does not allow " +
+					"`https://www.google-analytics.com`",
+				"`img-src` does not allow `https://www.googletagmanager.com`",
+			},
+		},
+		"google analytics, fully configured": {
+			CSP: "default-src 'self'; script-src https://www.googletagmanager.com; " +
+				"img-src https://www.googletagmanager.com; connect-src https://*.google-analytics.com",
+		},
+		"google fonts, unrestricted font-src": {
+			CSP: "style-src https://fonts.googleapis.com",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, _ := Parse("", "", []string{tc.CSP})
+			err := CheckVendors(policies[0])
+
+			if len(tc.ErrorSubstr) == 0 {
+				assert.NoError(err)
+
+				return
+			}
+
+			for i := range tc.ErrorSubstr {
+				assert.ErrorContains(err, tc.ErrorSubstr[i])
+			}
+		})
+	}
+}
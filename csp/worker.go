@@ -0,0 +1,155 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ExecutionContext is the kind of global object that a policy applies to: a
+// document, or one of the kinds of worker. Workers get their own policy from
+// the response to the worker script, and several directives have no effect
+// there.
+//
+// https://www.w3.org/TR/2024/WD-CSP3-20240613/#initialize-global-object-csp
+type ExecutionContext string
+
+const (
+	// ContextDocument is used for policies that apply to a document. It is the
+	// default.
+	ContextDocument ExecutionContext = "document"
+
+	// ContextDedicatedWorker is used for policies that apply to a dedicated
+	// worker (`new Worker()`).
+	ContextDedicatedWorker ExecutionContext = "dedicated-worker"
+
+	// ContextSharedWorker is used for policies that apply to a shared worker
+	// (`new SharedWorker()`).
+	ContextSharedWorker ExecutionContext = "shared-worker"
+
+	// ContextServiceWorker is used for policies that apply to a service worker
+	// (`navigator.serviceWorker.register()`).
+	ContextServiceWorker ExecutionContext = "service-worker"
+)
+
+// ExecutionContexts is the list of execution contexts.
+var ExecutionContexts = []ExecutionContext{
+	ContextDocument,
+	ContextDedicatedWorker,
+	ContextSharedWorker,
+	ContextServiceWorker,
+}
+
+// workerIgnoredDirectives maps the directives that have no effect in any kind of
+// worker to the reason. Workers still load scripts with `importScripts()`
+// (`script-src-elem`), evaluate strings (`script-src`), fetch resources
+// (`connect-src`), and load fonts (`font-src`).
+var workerIgnoredDirectives = map[string]string{
+	"base-uri":         "workers have no `<base>` element",
+	"fenced-frame-src": "workers cannot create fenced frames",
+	"form-action":      "workers have no forms",
+	"frame-ancestors":  "workers cannot be embedded in a frame",
+	"frame-src":        "workers cannot create frames",
+	"img-src":          "workers load images with `fetch()`, which `connect-src` governs",
+	"manifest-src":     "workers have no application manifest",
+	"media-src":        "workers have no media elements",
+	"object-src":       "workers have no plugins",
+	"sandbox":          "browsers only apply `sandbox` to documents",
+	"script-src-attr":  "workers have no event handler attributes",
+	"style-src":        "workers have no stylesheets",
+	"style-src-attr":   "workers have no stylesheets",
+	"style-src-elem":   "workers have no stylesheets",
+	"webrtc":           "WebRTC is not available in workers",
+}
+
+// serviceWorkerIgnoredDirectives maps the directives that only have no effect
+// in a service worker to the reason. Dedicated and shared workers can start
+// nested dedicated workers, which `worker-src` (and its fallback, `child-src`)
+// governs.
+var serviceWorkerIgnoredDirectives = map[string]string{
+	"child-src":  "service workers cannot create frames or workers",
+	"worker-src": "service workers cannot create workers",
+}
+
+/*
+WithExecutionContext sets the kind of global object that the policies passed
+to Parse apply to. The default is ContextDocument. In a worker context, the
+directives that have no effect there are reported.
+
+The script of a worker is not checked against the worker's own policy, but
+against the `worker-src` (or its fallbacks) of the policy that created it, so
+parse that policy as a document policy to check it.
+
+----
+
+  - ec (ExecutionContext): The kind of global object.
+*/
+func WithExecutionContext(ec ExecutionContext) Option {
+	return func(c *config) {
+		c.executionContext = ec
+	}
+}
+
+// isWorker reports whether the execution context is any kind of worker.
+func (ec ExecutionContext) isWorker() bool {
+	return ec == ContextDedicatedWorker || ec == ContextSharedWorker || ec == ContextServiceWorker
+}
+
+// String returns the execution context as it is written in prose (e.g.,
+// `service worker`).
+func (ec ExecutionContext) String() string {
+	return strings.ReplaceAll(string(ec), "-", " ")
+}
+
+/*
+checkWorkerContext reports directives that have no effect because the policy
+applies to a worker. Each directive is reported once, in the order it appears.
+
+----
+
+  - cfg (*config): The options for this call to Parse.
+
+  - policy (*Policy): The parsed policy.
+*/
+func checkWorkerContext(cfg *config, policy *Policy) error {
+	var errs *multierror.Error
+
+	if !cfg.executionContext.isWorker() {
+		return nil
+	}
+
+	reported := map[string]bool{}
+
+	for _, directive := range policy.Directives {
+		name := strings.ToLower(directive.Name)
+
+		reason, ok := workerIgnoredDirectives[name]
+		if !ok && cfg.executionContext == ContextServiceWorker {
+			reason, ok = serviceWorkerIgnoredDirectives[name]
+		}
+
+		if !ok || reported[name] {
+			continue
+		}
+
+		reported[name] = true
+		errs = multierror.Append(errs, fmt.Errorf(errCSP0029, name, cfg.executionContext, reason))
+	}
+
+	return errs.ErrorOrNil()
+}
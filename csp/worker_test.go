@@ -0,0 +1,86 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerContext(t *testing.T) {
+	const policy = "default-src 'self'; img-src 'self'; IMG-SRC https:; worker-src 'self'; child-src 'none'; " +
+		"script-src 'self'; connect-src 'self'; font-src 'self'; sandbox allow-scripts allow-same-origin"
+
+	for name, tc := range map[string]struct {
+		context ExecutionContext
+		ignored []string
+	}{
+		"document": {
+			context: ContextDocument,
+		},
+		"dedicated worker": {
+			context: ContextDedicatedWorker,
+			ignored: []string{"img-src", "sandbox"},
+		},
+		"shared worker": {
+			context: ContextSharedWorker,
+			ignored: []string{"img-src", "sandbox"},
+		},
+		"service worker": {
+			context: ContextServiceWorker,
+			ignored: []string{"img-src", "worker-src", "child-src", "sandbox"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse("https://example.com", "", []string{policy}, WithExecutionContext(tc.context),
+				WithoutReportingValidation())
+
+			var ignored []string
+
+			merr, ok := err.(*multierror.Error)
+			assert.True(ok)
+
+			for _, e := range merr.Errors {
+				if code := reRuleCode.FindStringSubmatch(e.Error()); code != nil && code[1] == "CSP-0029" {
+					directive := reRuleDirective.FindStringSubmatch(e.Error())
+					ignored = append(ignored, directive[1])
+				}
+			}
+
+			// Each directive is reported once, even when it appears more than once.
+			assert.Equal(tc.ignored, ignored)
+
+			// Workers ignore `sandbox`, so it cannot make `'self'` inert or let
+			// scripts escape it.
+			if tc.context.isWorker() {
+				assert.NotContains(fmt.Sprint(err), "[CSP-0006]")
+				assert.NotContains(fmt.Sprint(err), "[CSP-0704]")
+			} else {
+				assert.ErrorContains(err, "[CSP-0704]")
+			}
+		})
+	}
+
+	_, err := Parse("https://example.com", "", []string{"style-src 'self'"},
+		WithExecutionContext(ContextServiceWorker))
+	assert.ErrorContains(t, err,
+		"[WARN] directive `style-src` has no effect in a service worker, because workers have no stylesheets "+
+			"[CSP-0029]")
+}
@@ -0,0 +1,197 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package hostsource splits, joins, and matches the parts of a host-source
+expression (e.g., `https://*.example.com:443/path/`). It is an implementation
+detail of the csp package, and is not covered by its compatibility guarantees.
+*/
+package hostsource
+
+import (
+	"net/url"
+	"strings"
+)
+
+/*
+Split splits a host-source expression into its scheme-part, host-part,
+port-part, and path-part. Parts that are not present are returned as empty
+strings.
+
+----
+
+  - s (string): The host-source expression.
+*/
+func Split(s string) (scheme, host, port, path string) {
+	rest := s
+
+	if idx := strings.Index(rest, "://"); idx >= 0 {
+		scheme = rest[:idx]
+		rest = rest[idx+3:]
+	}
+
+	if idx := strings.Index(rest, "/"); idx >= 0 {
+		path = rest[idx:]
+		rest = rest[:idx]
+	}
+
+	if idx := strings.LastIndex(rest, ":"); idx >= 0 {
+		port = rest[idx+1:]
+		rest = rest[:idx]
+	}
+
+	return scheme, rest, port, path
+}
+
+/*
+Join is the inverse of Split.
+
+----
+
+  - scheme (string): The scheme-part, without the trailing `://`. May be empty.
+
+  - host (string): The host-part.
+
+  - port (string): The port-part, without the leading `:`. May be empty.
+
+  - path (string): The path-part, including the leading `/`. May be empty.
+*/
+func Join(scheme, host, port, path string) string {
+	s := host
+
+	if scheme != "" {
+		s = scheme + "://" + s
+	}
+
+	if port != "" {
+		s += ":" + port
+	}
+
+	return s + path
+}
+
+/*
+SchemeMatches implements "scheme-part matching" from CSP Level 3, § 6.7.2.6.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#match-schemes
+
+----
+
+  - a (string): The scheme from the source expression, without the colon.
+
+  - b (string): The scheme of the URL being loaded.
+*/
+func SchemeMatches(a, b string) bool {
+	a = strings.ToLower(a)
+	b = strings.ToLower(b)
+
+	switch {
+	case a == b:
+		return true
+	case a == "http" && b == "https":
+		return true
+	case a == "ws" && (b == "wss" || b == "http" || b == "https"):
+		return true
+	case a == "wss" && b == "https":
+		return true
+	}
+
+	return false
+}
+
+/*
+HostMatches implements "host-part matching" from CSP Level 3, § 6.7.2.7.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#match-hosts
+
+----
+
+  - pattern (string): The host-part from the source expression.
+
+  - host (string): The host of the URL being loaded.
+*/
+func HostMatches(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+
+	// An absolute DNS name (e.g., `example.com.`) names the same host as the
+	// relative one.
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if pattern == "*" {
+		return true
+	}
+
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+
+	return pattern == host
+}
+
+/*
+PathMatches implements "path-part matching" from CSP Level 3, § 6.7.2.10.
+
+https://www.w3.org/TR/2024/WD-CSP3-20240613/#match-paths
+
+----
+
+  - pattern (string): The path-part from the source expression.
+
+  - path (string): The path of the URL being loaded.
+*/
+func PathMatches(pattern, path string) bool {
+	if pattern == "" || (pattern == "/" && path == "") {
+		return true
+	}
+
+	pattern = decodePath(pattern)
+	path = decodePath(path)
+
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path, pattern)
+	}
+
+	return pattern == path
+}
+
+// decodePath percent-decodes a path, returning the input unchanged if it is not
+// validly encoded.
+func decodePath(s string) string {
+	decoded, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+
+	return decoded
+}
+
+// IsHTTPScheme reports whether the scheme is an HTTP(S) scheme.
+func IsHTTPScheme(scheme string) bool {
+	return strings.EqualFold(scheme, "http") || strings.EqualFold(scheme, "https")
+}
+
+// DefaultPort returns the default port for the scheme, or 0 if the scheme does
+// not have one.
+func DefaultPort(scheme string) int {
+	switch strings.ToLower(scheme) {
+	case "http", "ws":
+		return 80
+	case "https", "wss":
+		return 443
+	case "ftp":
+		return 21
+	}
+
+	return 0
+}
@@ -0,0 +1,56 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// <https://github.com/golang/go/wiki/TableDrivenTests>
+func TestSplit(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Input    string
+		Expected []string
+	}{
+		"host":     {Input: "example.com", Expected: []string{"", "example.com", "", ""}},
+		"scheme":   {Input: "https://example.com", Expected: []string{"https", "example.com", "", ""}},
+		"port":     {Input: "example.com:8080", Expected: []string{"", "example.com", "8080", ""}},
+		"wildcard": {Input: "https://*.example.com:*/a/", Expected: []string{"https", "*.example.com", "*", "/a/"}},
+		"path":     {Input: "example.com/a:b", Expected: []string{"", "example.com", "", "/a:b"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			scheme, host, port, path := Split(tc.Input)
+			assert.Equal(tc.Expected, []string{scheme, host, port, path})
+			assert.Equal(tc.Input, Join(scheme, host, port, path))
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(SchemeMatches("http", "HTTPS"))
+	assert.False(SchemeMatches("https", "http"))
+	assert.True(HostMatches("*.example.com", "a.example.com."))
+	assert.False(HostMatches("*.example.com", "example.com"))
+	assert.True(PathMatches("/a/", "/a/b%20c"))
+	assert.False(PathMatches("/a", "/a/b"))
+	assert.Equal(443, DefaultPort("WSS"))
+	assert.Equal(0, DefaultPort("data"))
+}
@@ -0,0 +1,51 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+// directiveDocs is the hover text for each directive.
+var directiveDocs = map[string]string{
+	"base-uri":                  "Restricts the URLs that can be used in a document's `<base>` element.",
+	"block-all-mixed-content":   "Obsolete. Use `upgrade-insecure-requests` instead.",
+	"child-src":                 "Governs nested browsing contexts and workers. Deprecated in favor of `frame-src` and `worker-src`.",
+	"connect-src":               "Restricts the URLs that can be loaded using script interfaces (e.g., `fetch()`, `WebSocket`).",
+	"default-src":               "The fallback for the other fetch directives.",
+	"fenced-frame-src":          "Restricts the URLs that can be loaded into `<fencedframe>` elements.",
+	"font-src":                  "Restricts the URLs from which fonts can be loaded.",
+	"form-action":               "Restricts the URLs that can be used as the target of form submissions.",
+	"frame-ancestors":           "Restricts the URLs that can embed the resource using `<frame>`, `<iframe>`, `<object>`, or `<embed>`.",
+	"frame-src":                 "Restricts the URLs that can be loaded into nested browsing contexts.",
+	"img-src":                   "Restricts the URLs from which images can be loaded.",
+	"manifest-src":              "Restricts the URLs from which application manifests can be loaded.",
+	"media-src":                 "Restricts the URLs from which `<audio>`, `<video>`, and `<track>` can be loaded.",
+	"navigate-to":               "Experimental and removed from CSP3. Browsers ignore it.",
+	"object-src":                "Restricts the URLs from which plugin content can be loaded. Usually set to `'none'`.",
+	"plugin-types":              "Obsolete. Remove this directive from the policy.",
+	"prefetch-src":              "Removed from CSP3. Browsers ignore it.",
+	"referrer":                  "Obsolete. Use the `Referrer-Policy` header instead.",
+	"report-to":                 "Names the `Reporting-Endpoints` endpoint that violation reports are sent to.",
+	"report-uri":                "The URLs that violation reports are sent to. Deprecated in favor of `report-to`.",
+	"require-sri-for":           "Removed from CSP3. Use `integrity` attributes instead.",
+	"require-trusted-types-for": "Requires Trusted Types for the given sink groups (e.g., `'script'`).",
+	"sandbox":                   "Applies restrictions to the page's actions, like an `<iframe sandbox>`.",
+	"script-src":                "Restricts the sources of JavaScript.",
+	"script-src-attr":           "Restricts the sources of JavaScript in inline event handlers.",
+	"script-src-elem":           "Restricts the sources of JavaScript in `<script>` elements.",
+	"style-src":                 "Restricts the sources of stylesheets.",
+	"style-src-attr":            "Restricts inline styles applied with the `style` attribute.",
+	"style-src-elem":            "Restricts the sources of stylesheets in `<style>` and `<link>` elements.",
+	"upgrade-insecure-requests": "Instructs the browser to upgrade HTTP URLs to HTTPS.",
+	"webrtc":                    "Controls whether WebRTC connections are allowed (`'allow'` or `'block'`).",
+	"worker-src":                "Restricts the URLs that can be loaded as workers.",
+}
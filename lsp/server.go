@@ -0,0 +1,450 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package lsp implements a minimal Language Server Protocol server for policy
+files. It provides diagnostics, hovers for directives, and completions for
+directive names and keyword-sources.
+
+https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/
+*/
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/northwood-labs/csp-parser/csp"
+)
+
+type (
+	// Server is a Language Server Protocol server that communicates over a pair
+	// of streams (normally stdin and stdout).
+	Server struct {
+		in   *bufio.Reader
+		out  io.Writer
+		mu   sync.Mutex
+		docs map[string]string
+
+		// rules configures the evaluation rules, from SetRules or from the
+		// initialization options.
+		rules *csp.RuleFile
+	}
+
+	// message is a JSON-RPC 2.0 request, response, or notification.
+	message struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id,omitempty"`
+		Method  string           `json:"method,omitempty"`
+		Params  json.RawMessage  `json:"params,omitempty"`
+		Result  any              `json:"result,omitempty"`
+	}
+
+	position struct {
+		Line      int `json:"line"`
+		Character int `json:"character"`
+	}
+
+	textRange struct {
+		Start position `json:"start"`
+		End   position `json:"end"`
+	}
+
+	diagnostic struct {
+		Range    textRange `json:"range"`
+		Severity int       `json:"severity"`
+		Code     string    `json:"code,omitempty"`
+		Source   string    `json:"source"`
+		Message  string    `json:"message"`
+	}
+
+	textDocumentParams struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+		Position position `json:"position"`
+
+		InitializationOptions *csp.RuleFile `json:"initializationOptions"`
+	}
+)
+
+// errExit is returned by handle when the client sends the `exit` notification.
+var errExit = errors.New("exit")
+
+// NewServer returns a Server that reads requests from r and writes responses
+// to w.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(r),
+		out:  w,
+		docs: map[string]string{},
+	}
+}
+
+// SetRules configures the evaluation rules used for diagnostics. Clients can
+// also send a rules file (see csp.ReadRules) as the initialization options,
+// which take precedence.
+func (s *Server) SetRules(rules *csp.RuleFile) {
+	s.rules = rules
+}
+
+// Run serves requests until the client sends `exit`, or the input is closed.
+func (s *Server) Run() error {
+	tp := textproto.NewReader(s.in)
+
+	for {
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return fmt.Errorf("invalid Content-Length: %w", err)
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(s.in, body); err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return err
+		}
+
+		if err := s.handle(&msg); err != nil {
+			if errors.Is(err, errExit) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// handle dispatches a single message.
+func (s *Server) handle(msg *message) error {
+	var params textDocumentParams
+
+	if len(msg.Params) > 0 {
+		_ = json.Unmarshal(msg.Params, &params)
+	}
+
+	switch msg.Method {
+	case "initialize":
+		if params.InitializationOptions != nil {
+			s.rules = params.InitializationOptions
+		}
+
+		return s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // Full
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{},
+			},
+			"serverInfo": map[string]any{"name": "csp-parser"},
+		})
+	case "shutdown":
+		return s.reply(msg.ID, nil)
+	case "exit":
+		return errExit
+	case "textDocument/didOpen":
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		if len(params.ContentChanges) == 0 {
+			return nil
+		}
+
+		return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		delete(s.docs, params.TextDocument.URI)
+
+		return s.publish(params.TextDocument.URI, []diagnostic{})
+	case "textDocument/hover":
+		word := wordAt(s.docs[params.TextDocument.URI], params.Position)
+		if doc, ok := directiveDocs[strings.ToLower(word)]; ok {
+			return s.reply(msg.ID, map[string]any{
+				"contents": map[string]any{"kind": "markdown", "value": "**" + word + "**\n\n" + doc},
+			})
+		}
+
+		return s.reply(msg.ID, nil)
+	case "textDocument/completion":
+		return s.reply(msg.ID, completions())
+	}
+
+	// Unknown requests get an empty result; unknown notifications are ignored.
+	if msg.ID != nil {
+		return s.reply(msg.ID, nil)
+	}
+
+	return nil
+}
+
+// update stores the new text of a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	s.docs[uri] = text
+
+	return s.publish(uri, diagnose(text, s.rules))
+}
+
+// publish sends the diagnostics for a document.
+func (s *Server) publish(uri string, diagnostics []diagnostic) error {
+	return s.write(message{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: mustMarshal(map[string]any{
+			"uri":         uri,
+			"diagnostics": diagnostics,
+		}),
+	})
+}
+
+// reply sends the result of a request. A nil result is sent as JSON null.
+func (s *Server) reply(id *json.RawMessage, result any) error {
+	if result == nil {
+		return s.write(map[string]any{"jsonrpc": "2.0", "id": id, "result": nil})
+	}
+
+	return s.write(message{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// write frames and sends a message.
+func (s *Server) write(v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+
+	return err
+}
+
+/*
+diagnose parses the text of a policy file and converts the diagnostics into
+LSP diagnostics. Comments (`# ...`) are blanked out before parsing, so that
+positions in the text are preserved. Each diagnostic points at the directive
+that it is about, or at the start of the document when it is not about a single
+directive.
+
+----
+
+  - text (string): The contents of the policy file.
+
+  - rules (*csp.RuleFile): The evaluation rules, or nil.
+*/
+func diagnose(text string, rules *csp.RuleFile) []diagnostic {
+	diagnostics := []diagnostic{}
+
+	opts := append([]csp.Option{
+		csp.MinSeverity(csp.SeverityWarning),
+		csp.WithoutSelfValidation(),
+		csp.WithoutReportingValidation(),
+	}, rules.Options()...)
+
+	_, err := csp.Parse("", "", []string{stripComments(text)}, opts...)
+	if err == nil {
+		return diagnostics
+	}
+
+	var errs []error
+
+	if merr, ok := err.(*multierror.Error); ok {
+		errs = merr.Errors
+	} else {
+		errs = []error{err}
+	}
+
+	for i := range errs {
+		msg := errs[i].Error()
+		severity := 1
+
+		switch csp.SeverityOf(errs[i]) {
+		case csp.SeverityWarning:
+			severity = 2
+		case csp.SeverityInfo:
+			severity = 3
+		}
+
+		code := ""
+		if start := strings.LastIndex(msg, "[CSP-"); start >= 0 {
+			code = strings.Trim(msg[start:], "[]")
+			msg = strings.TrimSpace(msg[:start])
+		}
+
+		if end := strings.Index(msg, "] "); strings.HasPrefix(msg, "[") && end >= 0 {
+			msg = msg[end+2:]
+		}
+
+		r := rangeOf(text, directiveOf(msg))
+
+		var pe *csp.PolicyError
+		if errors.As(errs[i], &pe) && pe.Span != nil {
+			r = textRange{Start: positionOf(text, pe.Span.Start), End: positionOf(text, pe.Span.End)}
+		}
+
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    r,
+			Severity: severity,
+			Code:     code,
+			Source:   "csp-parser",
+			Message:  msg,
+		})
+	}
+
+	return diagnostics
+}
+
+// stripComments replaces `# ...` comments with spaces, keeping every other
+// byte (including newlines) at the same offset.
+func stripComments(text string) string {
+	b := []byte(text)
+	inComment := false
+
+	for i := range b {
+		switch {
+		case b[i] == '\n':
+			inComment = false
+		case b[i] == '#' && (i == 0 || b[i-1] == ' ' || b[i-1] == '\t' || b[i-1] == '\n'):
+			inComment = true
+		}
+
+		if inComment {
+			b[i] = ' '
+		}
+	}
+
+	return string(b)
+}
+
+// directiveOf returns the directive named in a diagnostic message (e.g., the
+// `script-src` in "directive `script-src` has an invalid value"), if any.
+func directiveOf(msg string) string {
+	_, rest, ok := strings.Cut(msg, "directive `")
+	if !ok {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(rest, "`")
+
+	return name
+}
+
+// rangeOf returns the range of the first occurrence of the word in the text,
+// or an empty range at the start of the text.
+func rangeOf(text, word string) textRange {
+	if word != "" {
+		for line, content := range strings.Split(text, "\n") {
+			if col := strings.Index(content, word); col >= 0 {
+				return textRange{
+					Start: position{Line: line, Character: col},
+					End:   position{Line: line, Character: col + len(word)},
+				}
+			}
+		}
+	}
+
+	return textRange{}
+}
+
+// positionOf converts a byte offset in the text to a position.
+func positionOf(text string, offset int) position {
+	before := text[:min(offset, len(text))]
+	line := strings.Count(before, "\n")
+
+	return position{Line: line, Character: len(before) - strings.LastIndex(before, "\n") - 1}
+}
+
+// wordAt returns the directive name or keyword under the position.
+func wordAt(text string, pos position) string {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return ""
+	}
+
+	line := lines[pos.Line]
+	if pos.Character > len(line) {
+		return ""
+	}
+
+	isWordByte := func(c byte) bool {
+		return c == '-' || c == '\'' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+	}
+
+	start, end := pos.Character, pos.Character
+	for start > 0 && isWordByte(line[start-1]) {
+		start--
+	}
+
+	for end < len(line) && isWordByte(line[end]) {
+		end++
+	}
+
+	return line[start:end]
+}
+
+// completions returns the completion items for directive names and
+// keyword-sources.
+func completions() []map[string]any {
+	caps := csp.Capabilities()
+	items := make([]map[string]any, 0, len(caps.Directives)+len(caps.Keywords))
+
+	for _, d := range caps.Directives {
+		items = append(items, map[string]any{
+			"label":         d,
+			"kind":          14, // Keyword
+			"documentation": directiveDocs[d],
+		})
+	}
+
+	for _, k := range caps.Keywords {
+		items = append(items, map[string]any{
+			"label": k,
+			"kind":  21, // Constant
+		})
+	}
+
+	return items
+}
+
+// mustMarshal marshals a value that is known to be serializable.
+func mustMarshal(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
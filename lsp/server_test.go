@@ -0,0 +1,78 @@
+// Copyright 2024, Northwood Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/northwood-labs/csp-parser/csp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnose(t *testing.T) {
+	assert := assert.New(t)
+
+	diagnostics := diagnose("# A policy file\ndefault-src 'self';\nbogus-src 'self' # not a directive\n", nil)
+	assert.Len(diagnostics, 1)
+
+	assert.Equal("CSP-0901", diagnostics[0].Code)
+	assert.Equal(1, diagnostics[0].Severity)
+	assert.Equal("unknown directive `bogus-src`", diagnostics[0].Message)
+	assert.Equal(textRange{
+		Start: position{Line: 2, Character: 0},
+		End:   position{Line: 2, Character: 16},
+	}, diagnostics[0].Range)
+}
+
+func TestDiagnoseRules(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Len(diagnose("img-src *; script-src *", nil), 2)
+
+	diagnostics := diagnose("img-src *; script-src *", &csp.RuleFile{
+		Rules: csp.Rules{"wildcard": {ExcludeDirectives: []string{"img-src"}}},
+	})
+	assert.Len(diagnostics, 1)
+	assert.Equal("CSP-0119", diagnostics[0].Code)
+	assert.Contains(diagnostics[0].Message, "`script-src`")
+}
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+
+	var in, out bytes.Buffer
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.csp",` +
+			`"text":"script-src 'self'"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.csp"},` +
+			`"position":{"line":0,"character":3}}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	err := NewServer(&in, &out).Run()
+	assert.NoError(err)
+
+	assert.Contains(out.String(), `"hoverProvider":true`)
+	assert.Contains(out.String(), `"method":"textDocument/publishDiagnostics"`)
+	assert.Contains(out.String(), "Restricts the sources of JavaScript.")
+	assert.Equal(3, strings.Count(out.String(), "Content-Length:"))
+}